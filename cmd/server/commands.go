@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
+)
+
+const importBatchSize = 500
+
+type exportRecord struct {
+	InputHash       string      `json:"input_hash"`
+	InputText       string      `json:"input_text"`
+	InputLength     int         `json:"input_length"`
+	ModelName       string      `json:"model_name"`
+	EmbeddingVector []float64   `json:"embedding_vector,omitempty"`
+	EmbeddingMatrix [][]float64 `json:"embedding_matrix,omitempty"`
+}
+
+func runCommand(ctx context.Context, db *database.Database, aiClient *openai.Client, embeddingCache *cache.Cache, tenants []string, args []string, logger *zap.Logger) error {
+	switch args[0] {
+	case "export":
+		flags := flag.NewFlagSet("export", flag.ExitOnError)
+		out := flags.String("out", "cache.jsonl", "Path to write the exported cache to")
+		flags.Parse(args[1:])
+		return exportCache(ctx, db, *out, logger)
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ExitOnError)
+		in := flags.String("in", "cache.jsonl", "Path to read the cache export from")
+		flags.Parse(args[1:])
+		return importCache(ctx, db, *in, logger)
+	case "verify":
+		flags := flag.NewFlagSet("verify", flag.ExitOnError)
+		reembed := flags.Bool("reembed", false, "Re-embed corrupt rows through the provider")
+		flags.Parse(args[1:])
+		return verifyCache(ctx, db, aiClient, embeddingCache, tenants, *reembed, logger)
+	case "index":
+		flags := flag.NewFlagSet("index", flag.ExitOnError)
+		params := database.VectorIndexParams{}
+		flags.StringVar(&params.Method, "method", database.IndexHNSW, "Index method: hnsw or ivfflat")
+		flags.StringVar(&params.Metric, "metric", "cosine", "Distance metric: cosine, l2 or ip")
+		flags.IntVar(&params.M, "m", 16, "HNSW max connections per layer")
+		flags.IntVar(&params.EfConstruction, "ef-construction", 64, "HNSW candidate list size while building")
+		flags.IntVar(&params.Lists, "lists", 100, "IVFFlat number of lists")
+		flags.Parse(args[1:])
+		if err := db.EnsureVectorIndex(ctx, params); err != nil {
+			return fmt.Errorf("failed to ensure vector index: %w", err)
+		}
+		logger.Info("Vector indexes are in place", zap.String("method", params.Method))
+		return nil
+	case "reformat":
+		flags := flag.NewFlagSet("reformat", flag.ExitOnError)
+		format := flags.String("format", "", "Target vector format: json, compressed or binary (default: database.vector_format)")
+		batchSize := flags.Int("batch", importBatchSize, "Rows rewritten per batch")
+		flags.Parse(args[1:])
+		return reformatCache(ctx, db, *format, *batchSize, logger)
+	default:
+		return fmt.Errorf("unknown command %q (expected export, import, verify, index or reformat)", args[0])
+	}
+}
+
+func exportCache(ctx context.Context, db *database.Database, path string, logger *zap.Logger) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	count := 0
+	err = db.ExportEmbeddings(ctx, func(embedding *database.CachedEmbedding) error {
+		count++
+		return encoder.Encode(&exportRecord{
+			InputHash:       embedding.InputHash,
+			InputText:       embedding.InputText,
+			InputLength:     embedding.InputLength,
+			ModelName:       embedding.ModelName,
+			EmbeddingVector: embedding.EmbeddingVector,
+			EmbeddingMatrix: embedding.EmbeddingMatrix,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export cache: %w", err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	logger.Info("Cache export completed",
+		zap.String("path", path),
+		zap.Int("entries", count))
+
+	return nil
+}
+
+func importCache(ctx context.Context, db *database.Database, path string, logger *zap.Logger) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+
+	count := 0
+	batch := make([]*database.CachedEmbedding, 0, importBatchSize)
+	flush := func() error {
+		if err := db.ImportEmbeddings(ctx, batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for decoder.More() {
+		var record exportRecord
+		if err := decoder.Decode(&record); err != nil {
+			return fmt.Errorf("failed to decode import record %d: %w", count+len(batch)+1, err)
+		}
+
+		if record.InputHash == "" || (len(record.EmbeddingVector) == 0 && len(record.EmbeddingMatrix) == 0) {
+			return fmt.Errorf("import record %d is missing input_hash or embedding_vector", count+len(batch)+1)
+		}
+
+		batch = append(batch, &database.CachedEmbedding{
+			InputHash:       record.InputHash,
+			InputText:       record.InputText,
+			InputLength:     record.InputLength,
+			ModelName:       record.ModelName,
+			EmbeddingVector: record.EmbeddingVector,
+			EmbeddingMatrix: record.EmbeddingMatrix,
+		})
+
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	logger.Info("Cache import completed",
+		zap.String("path", path),
+		zap.Int("entries", count))
+
+	return nil
+}
+
+func reformatCache(ctx context.Context, db *database.Database, format string, batchSize int, logger *zap.Logger) error {
+	if batchSize < 1 {
+		return fmt.Errorf("invalid batch size: %d", batchSize)
+	}
+
+	results, err := db.Reformat(ctx, format, batchSize)
+	for _, result := range results {
+		logger.Info("Cache table reformatted",
+			zap.String("table", result.Table),
+			zap.Int("rewritten", result.Rewritten),
+			zap.Int("skipped", result.Skipped),
+			zap.Int("failed", result.Failed))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reformat cache: %w", err)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
@@ -12,6 +13,8 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/asyncjobs"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/audit"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
@@ -19,7 +22,11 @@ import (
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/logger"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/server"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/storeretry"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/tenant"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/tokenizer"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/tracker"
+	appversion "github.com/zanmato/meilisearch-embedder-proxy/internal/version"
 )
 
 var (
@@ -28,20 +35,22 @@ var (
 )
 
 const (
-	AppName    = "Meep"
-	AppVersion = "1.0.0"
-	AppDesc    = "Meilisearch Embedder Proxy"
+	AppName = "Meep"
+	AppDesc = "Meilisearch Embedder Proxy"
 )
 
 func main() {
 	flag.Parse()
 
+	buildInfo := appversion.Get()
+
 	if *version {
-		fmt.Printf("%s v%s - %s\n", AppName, AppVersion, AppDesc)
+		fmt.Printf("%s v%s - %s (commit %s, built %s, %s)\n", AppName, buildInfo.Version, AppDesc,
+			buildInfo.Commit, buildInfo.BuildDate, buildInfo.GoVersion)
 		os.Exit(0)
 	}
 
-	fmt.Printf("Starting %s v%s...\n", AppName, AppVersion)
+	fmt.Printf("Starting %s v%s...\n", AppName, buildInfo.Version)
 
 	cfg, err := config.Load(*configPath)
 	if err != nil {
@@ -58,7 +67,9 @@ func main() {
 
 	zapLogger.Info("Starting service",
 		zap.String("app_name", AppName),
-		zap.String("version", AppVersion),
+		zap.String("version", buildInfo.Version),
+		zap.String("commit", buildInfo.Commit),
+		zap.String("build_date", buildInfo.BuildDate),
 		zap.String("config_file", *configPath))
 
 	zapLogger.Info("Configuration loaded",
@@ -71,7 +82,19 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	db, err := database.New(cfg.DatabaseDSN(), zapLogger)
+	db, err := database.New(cfg.DatabaseDSN(), database.Options{
+		AcquireTimeout: time.Duration(cfg.Database.AcquireTimeoutMs) * time.Millisecond,
+		StoreInputText: cfg.Cache.StoreInputText,
+		ShardCount:     cfg.Database.ShardCount,
+		ConflictPolicy: cfg.Cache.ConflictPolicy,
+		TablePerModel:  cfg.Database.TablePerModel,
+
+		ReadRetries:      cfg.Database.ReadRetries,
+		ReadRetryBackoff: time.Duration(cfg.Database.ReadRetryBackoffMs) * time.Millisecond,
+
+		MaxDimensions: cfg.Database.MaxVectorDims,
+		VectorFormat:  cfg.Database.StoredVectorFormat(),
+	}, zapLogger)
 	if err != nil {
 		zapLogger.Fatal("Failed to connect to database", zap.Error(err))
 	}
@@ -81,35 +104,106 @@ func main() {
 		zapLogger.Fatal("Failed to run database migrations", zap.Error(err))
 	}
 
-	aiClient, err := openai.New(
-		cfg.OpenAI.APIKey,
-		cfg.OpenAI.BaseURL,
-		cfg.OpenAI.Model,
-		cfg.OpenAI.MaxRetries,
-		cfg.OpenAI.TimeoutSec,
-		zapLogger,
-	)
+	aiClient, err := openai.New(&cfg.OpenAI, zapLogger)
 	if err != nil {
 		zapLogger.Fatal("Failed to initialize OpenAI client", zap.Error(err))
 	}
 
+	hasher := hash.New(&cfg.Hash, zapLogger)
+	tokenizer.Preload(aiClient.GetModel())
+
+	if flag.NArg() > 0 {
+		tenantIDs := make([]string, len(cfg.Tenants))
+		for i, t := range cfg.Tenants {
+			tenantIDs[i] = t.ID
+		}
+
+		commandCache := cache.New(db, aiClient, hasher, nil, nil, &cfg.Cache, zapLogger)
+		if err := runCommand(ctx, db, aiClient, commandCache, tenantIDs, flag.Args(), zapLogger); err != nil {
+			zapLogger.Fatal("Command failed", zap.String("command", flag.Arg(0)), zap.Error(err))
+		}
+		return
+	}
+
 	zapLogger.Info("Validating OpenAI model...")
 	if err := aiClient.ValidateModel(ctx); err != nil {
+		if cfg.OpenAI.RequireListedModels && errors.Is(err, openai.ErrModelNotListed) {
+			zapLogger.Fatal("Configured model not available from provider", zap.Error(err))
+		}
 		zapLogger.Error("Model validation failed, but continuing", zap.Error(err))
 	}
 
-	hasher := hash.New(zapLogger)
-	usageTracker := tracker.New(db, zapLogger, cfg.Tracker.BatchSize, time.Duration(cfg.Tracker.FlushIntervalSec)*time.Second)
+	usageTracker := tracker.New(db, zapLogger, cfg.Tracker.BatchSize, time.Duration(cfg.Tracker.FlushIntervalSec)*time.Second,
+		cfg.Tracker.ChannelBuffer, time.Duration(cfg.Tracker.BlockTimeoutMs)*time.Millisecond,
+		tracker.Options{
+			RetryAttempts: cfg.Tracker.RetryAttempts,
+			RetryBackoff:  time.Duration(cfg.Tracker.RetryBackoffMs) * time.Millisecond,
+			OnFailure:     cfg.Tracker.OnFailure,
+			SpillFile:     cfg.Tracker.SpillFile,
+			SpillMaxBytes: cfg.Tracker.SpillMaxBytes,
+		})
 	usageTracker.Start(ctx)
-	defer usageTracker.Stop()
 
-	cache := cache.New(db, aiClient, hasher, usageTracker, zapLogger)
+	var storeRetry *storeretry.Queue
+	if cfg.Cache.StoreRetryQueueSize > 0 && cfg.Cache.StoreRetryAttempts > 0 {
+		storeRetry = storeretry.New(db, zapLogger, cfg.Cache.StoreRetryQueueSize, cfg.Cache.StoreRetryAttempts, time.Duration(cfg.Cache.StoreRetryBackoffMs)*time.Millisecond)
+		storeRetry.Start(ctx)
+	}
 
-	httpServer := server.New(cache, zapLogger)
+	embeddingCache := cache.New(db, aiClient, hasher, usageTracker, storeRetry, &cfg.Cache, zapLogger)
+
+	var scheduler *cache.Scheduler
+	if len(cfg.Warmup.Schedule) > 0 {
+		scheduler, err = cache.NewScheduler(embeddingCache, cfg.Warmup.Schedule, zapLogger)
+		if err != nil {
+			zapLogger.Fatal("Failed to configure warmup schedules", zap.Error(err))
+		}
+		scheduler.Start(ctx)
+	}
+
+	var auditRecorder *audit.Recorder
+	if cfg.Server.Audit {
+		auditRecorder = audit.New(db, zapLogger, cfg.Tracker.BatchSize, time.Duration(cfg.Tracker.FlushIntervalSec)*time.Second)
+		auditRecorder.Start(ctx)
+		defer auditRecorder.Stop()
+	}
+
+	var tenants *tenant.Registry
+	if len(cfg.Tenants) > 0 {
+		tenants = tenant.New(cfg.Tenants, zapLogger)
+	}
+
+	var asyncJobs *asyncjobs.Registry
+	if cfg.Async.Enabled() {
+		asyncJobs = asyncjobs.New(&cfg.Async, zapLogger)
+	}
+
+	httpServer, err := server.New(embeddingCache, auditRecorder, tenants, asyncJobs, &cfg.Server, &cfg.Logging, zapLogger)
+	if err != nil {
+		zapLogger.Fatal("Failed to initialize HTTP server", zap.Error(err))
+	}
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			zapLogger.Info("Received SIGHUP, reloading OpenAI API key")
+
+			apiKey, err := cfg.OpenAI.ResolveAPIKey()
+			if err != nil {
+				zapLogger.Error("Failed to reload OpenAI API key", zap.Error(err))
+				continue
+			}
+
+			if err := aiClient.SetAPIKey(apiKey); err != nil {
+				zapLogger.Error("Failed to apply reloaded OpenAI API key", zap.Error(err))
+			}
+		}
+	}()
+
 	go func() {
 		addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 		if err := httpServer.Start(addr); err != nil && err != http.ErrServerClosed {
@@ -117,9 +211,26 @@ func main() {
 		}
 	}()
 
+	if cfg.Server.AdminPort > 0 {
+		go func() {
+			addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.AdminPort)
+			if err := httpServer.StartAdmin(addr); err != nil && err != http.ErrServerClosed {
+				zapLogger.Fatal("Failed to start admin HTTP server", zap.Error(err))
+			}
+		}()
+	}
+
+	httpServer.SetReady(true)
+
+	listenAddress := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	if cfg.Server.UnixSocket != "" {
+		listenAddress = "unix:" + cfg.Server.UnixSocket
+	}
+
 	zapLogger.Info("Service started successfully",
-		zap.String("address", fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)),
+		zap.String("address", listenAddress),
 		zap.String("health_check", fmt.Sprintf("http://%s:%d/healthz", cfg.Server.Host, cfg.Server.Port)),
+		zap.String("readiness_check", fmt.Sprintf("http://%s:%d/readyz", cfg.Server.Host, cfg.Server.Port)),
 		zap.String("embeddings_endpoint", fmt.Sprintf("http://%s:%d/embed", cfg.Server.Host, cfg.Server.Port)))
 
 	select {
@@ -140,5 +251,14 @@ func main() {
 		zapLogger.Info("HTTP server shutdown completed")
 	}
 
+	// Stop everything that can still record usage before the tracker.
+	if scheduler != nil {
+		scheduler.Stop()
+	}
+	if storeRetry != nil {
+		storeRetry.Stop(shutdownCtx)
+	}
+	usageTracker.Stop(shutdownCtx)
+
 	zapLogger.Info("Service shutdown completed")
 }
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/vectorcheck"
+)
+
+type verifyReport struct {
+	Scanned   int
+	Backfills int
+	Corrupt   int
+	Reembeds  int
+	Failed    int
+}
+
+func verifyCache(ctx context.Context, db *database.Database, aiClient *openai.Client, embeddingCache *cache.Cache, tenants []string, reembed bool, logger *zap.Logger) error {
+	var report verifyReport
+	var corrupt []*database.VerifyRow
+
+	err := db.ScanForVerify(ctx, func(row *database.VerifyRow) error {
+		report.Scanned++
+
+		if problem := vectorProblem(row); problem != "" {
+			report.Corrupt++
+			logger.Warn("Corrupt cache row",
+				zap.String("id", row.ID.String()),
+				zap.String("input_hash", row.InputHash),
+				zap.String("model", row.ModelName),
+				zap.String("problem", problem))
+			corrupt = append(corrupt, row)
+			return nil
+		}
+
+		dimensions := len(row.EmbeddingVector)
+		if len(row.EmbeddingMatrix) > 0 {
+			dimensions = len(row.EmbeddingMatrix[0])
+		}
+		inputLength := row.InputLength
+		if row.InputText != "" {
+			inputLength = len(row.InputText)
+		}
+
+		if row.InputLength == inputLength && row.Dimensions != nil && *row.Dimensions == dimensions && !db.ShardOutdated(row) {
+			return nil
+		}
+
+		if err := db.UpdateEmbeddingMetadata(ctx, row, inputLength, dimensions); err != nil {
+			return err
+		}
+		report.Backfills++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify cache: %w", err)
+	}
+
+	if reembed {
+		for _, row := range corrupt {
+			if err := reembedRow(ctx, db, aiClient, embeddingCache, tenants, row); err != nil {
+				report.Failed++
+				logger.Error("Failed to re-embed corrupt row",
+					zap.String("input_hash", row.InputHash),
+					zap.Error(err))
+				continue
+			}
+			report.Reembeds++
+		}
+	}
+
+	logger.Info("Cache verification completed",
+		zap.Int("scanned", report.Scanned),
+		zap.Int("backfilled", report.Backfills),
+		zap.Int("corrupt", report.Corrupt),
+		zap.Int("reembedded", report.Reembeds),
+		zap.Int("reembed_failed", report.Failed))
+
+	return nil
+}
+
+func vectorProblem(row *database.VerifyRow) string {
+	if row.ParseErr != nil {
+		return row.ParseErr.Error()
+	}
+
+	var err error
+	if len(row.EmbeddingMatrix) > 0 {
+		err = vectorcheck.ValidateMatrix(row.EmbeddingMatrix)
+	} else {
+		err = vectorcheck.Validate(row.EmbeddingVector)
+	}
+	if err != nil {
+		return err.Error()
+	}
+
+	return ""
+}
+
+// reembedRow rewrites a corrupt row with a fresh vector. tenants are the
+// configured tenant IDs, which the row's key may have been made with.
+func reembedRow(ctx context.Context, db *database.Database, aiClient *openai.Client, embeddingCache *cache.Cache, tenants []string, row *database.VerifyRow) error {
+	if row.InputText == "" {
+		return fmt.Errorf("input text not stored")
+	}
+
+	dimensions := 0
+	if row.Dimensions != nil {
+		dimensions = *row.Dimensions
+	}
+
+	// Rows written before inputs were prepared ahead of embedding hold the
+	// raw text; re-embed what the hash represents, with the options that
+	// produced it.
+	input, req, ok := embeddingCache.MatchStoredRow(row.InputText, row.ModelName, row.InputHash, dimensions, append([]string{""}, tenants...))
+	if !ok {
+		return fmt.Errorf("no request variant reproduces the stored hash")
+	}
+	row.InputText = input
+	normalize := *req.Normalize
+	ctx = openai.WithDimensions(ctx, req.Dimensions)
+
+	if aiClient.ModelConfig(row.ModelName).MultiVector {
+		response, err := aiClient.CreateMultiVectorEmbeddings(ctx, []string{row.InputText}, row.ModelName)
+		if err != nil {
+			return err
+		}
+
+		matrix := response.Embeddings[0]
+		if normalize {
+			for _, vector := range matrix {
+				cache.NormalizeVector(vector)
+			}
+		}
+
+		return db.ReplaceEmbeddingMatrix(ctx, row.InputHash, row.InputText, row.ModelName, matrix)
+	}
+
+	response, err := aiClient.CreateEmbeddingWithModel(ctx, row.InputText, row.ModelName)
+	if err != nil {
+		return err
+	}
+
+	if normalize {
+		cache.NormalizeVector(response.Embedding)
+	}
+
+	return db.ReplaceEmbedding(ctx, row.InputHash, row.InputText, row.ModelName, response.Embedding)
+}
@@ -0,0 +1,405 @@
+package asyncjobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// Delivery states of a job's callback.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// ErrTooManyJobs is returned by Start when async.max_running or
+// async.max_running_per_tenant jobs are already running.
+var ErrTooManyJobs = errors.New("too many running async jobs")
+
+// chunkTimeout bounds each MaxBatchSize chunk, like a synchronous request.
+const chunkTimeout = 60 * time.Second
+
+type Job struct {
+	ID               string     `json:"id"`
+	Status           string     `json:"status"`
+	Model            string     `json:"model,omitempty"`
+	Total            int        `json:"total"`
+	Processed        int        `json:"processed"`
+	Delivery         string     `json:"delivery"`
+	DeliveryAttempts int        `json:"delivery_attempts"`
+	Error            string     `json:"error,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+}
+
+// Result is the callback body, and what GET .../result returns. Embeddings
+// are in input order; a failed item is null and listed in Errors. When the
+// job has more than async.max_inline_inputs inputs, Embeddings is left out
+// of the callback and ResultURL points at the full result instead.
+type Result struct {
+	ID         string            `json:"id"`
+	Status     string            `json:"status"`
+	Model      string            `json:"model,omitempty"`
+	Embeddings [][]float64       `json:"embeddings,omitempty"`
+	ResultURL  string            `json:"result_url,omitempty"`
+	Errors     []cache.ItemError `json:"errors,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Usage      struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+	CreatedAt  time.Time `json:"created_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+type job struct {
+	Job
+	tenant      string
+	callbackURL string
+	path        string
+	result      *Result
+	cancel      context.CancelFunc
+}
+
+// Registry embeds async requests in the background and delivers each result
+// to its webhook. Jobs and results live in memory, so they do not survive a
+// restart; finished jobs are dropped ttl after they end.
+type Registry struct {
+	logger *zap.Logger
+	config *config.AsyncConfig
+	client *webhookClient
+	ttl    time.Duration
+	mu     sync.Mutex
+	jobs   map[string]*job
+	wg     sync.WaitGroup
+
+	// stopCtx outlives cancelled jobs, so their callbacks are still sent;
+	// only Stop ends it.
+	stopCtx context.Context
+	stop    context.CancelFunc
+}
+
+func New(cfg *config.AsyncConfig, logger *zap.Logger) *Registry {
+	logger.Info("Async embedding jobs enabled",
+		zap.String("callback_url", cfg.CallbackURL),
+		zap.Strings("allowed_callback_hosts", cfg.AllowedCallbackHosts),
+		zap.Int("max_inputs", cfg.MaxInputs),
+		zap.Int("callback_retries", cfg.CallbackRetries),
+		zap.Int("max_running", cfg.MaxRunning),
+		zap.Int("max_running_per_tenant", cfg.MaxRunningPerTenant))
+
+	stopCtx, stop := context.WithCancel(context.Background())
+	return &Registry{
+		logger:  logger,
+		config:  cfg,
+		client:  newWebhookClient(cfg),
+		ttl:     time.Duration(cfg.JobTTLSec) * time.Second,
+		jobs:    make(map[string]*job),
+		stopCtx: stopCtx,
+		stop:    stop,
+	}
+}
+
+func (r *Registry) MaxInputs() int {
+	return r.config.MaxInputs
+}
+
+// CallbackURL returns the webhook for a request: its own callback_url when
+// the host is allowed, otherwise the configured default.
+func (r *Registry) CallbackURL(requested string) (string, error) {
+	if requested == "" {
+		if r.config.CallbackURL == "" {
+			return "", fmt.Errorf("callback_url is required")
+		}
+		return r.config.CallbackURL, nil
+	}
+
+	u, err := url.Parse(requested)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil {
+		return "", fmt.Errorf("invalid callback_url: %q", requested)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, pattern := range r.config.AllowedCallbackHosts {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "."); ok {
+			if host == suffix || strings.HasSuffix(host, pattern) {
+				return requested, nil
+			}
+		} else if host == pattern {
+			return requested, nil
+		}
+	}
+
+	return "", fmt.Errorf("callback_url host %q is not in async.allowed_callback_hosts", host)
+}
+
+// Start runs req in the background. path is the route the job was
+// submitted on, which result_url is built from; budget is asked before
+// every chunk after the first and fails the job when it returns an error;
+// onChunk sees every embedded chunk, for accounting.
+func (r *Registry) Start(c *cache.Cache, req *cache.AsyncRequest, callbackURL, path string, budget func() error, onChunk func(*cache.EmbeddingResponse)) (Job, error) {
+	r.mu.Lock()
+	r.sweepLocked()
+	if err := r.admitLocked(req.Tenant); err != nil {
+		r.mu.Unlock()
+		return Job{}, err
+	}
+
+	ctx, cancel := context.WithCancel(r.stopCtx)
+
+	j := &job{
+		Job: Job{
+			ID:        uuid.New().String(),
+			Status:    StatusRunning,
+			Model:     req.Model,
+			Total:     len(req.Input),
+			Delivery:  DeliveryPending,
+			CreatedAt: time.Now(),
+		},
+		tenant:      req.Tenant,
+		callbackURL: callbackURL,
+		path:        strings.TrimSuffix(path, "/"),
+		cancel:      cancel,
+	}
+	r.jobs[j.ID] = j
+	snapshot := j.Job
+	r.mu.Unlock()
+
+	r.logger.Info("Started async embedding job",
+		zap.String("job_id", j.ID),
+		zap.Int("input_count", len(req.Input)),
+		zap.String("tenant", req.Tenant))
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+
+		result := r.run(ctx, c, j, req, budget, onChunk)
+
+		r.mu.Lock()
+		j.result = result
+		j.Model = result.Model
+		j.Status = result.Status
+		j.Error = result.Error
+		j.FinishedAt = &result.FinishedAt
+		processed := j.Processed
+		r.mu.Unlock()
+
+		r.logger.Info("Async embedding job finished",
+			zap.String("job_id", j.ID),
+			zap.String("status", result.Status),
+			zap.Int("processed", processed),
+			zap.Int("failed", len(result.Errors)))
+
+		r.deliver(j, result)
+	}()
+
+	return snapshot, nil
+}
+
+// admitLocked refuses a new job for tenant while the running limits are
+// reached.
+func (r *Registry) admitLocked(tenant string) error {
+	running, tenantRunning := 0, 0
+	for _, j := range r.jobs {
+		if j.Status != StatusRunning {
+			continue
+		}
+		running++
+		if j.tenant == tenant {
+			tenantRunning++
+		}
+	}
+
+	if r.config.MaxRunning > 0 && running >= r.config.MaxRunning {
+		return fmt.Errorf("%w: %d of async.max_running %d", ErrTooManyJobs, running, r.config.MaxRunning)
+	}
+	if tenant != "" && r.config.MaxRunningPerTenant > 0 && tenantRunning >= r.config.MaxRunningPerTenant {
+		return fmt.Errorf("%w: %d of async.max_running_per_tenant %d for tenant %s",
+			ErrTooManyJobs, tenantRunning, r.config.MaxRunningPerTenant, tenant)
+	}
+	return nil
+}
+
+func (r *Registry) run(ctx context.Context, c *cache.Cache, j *job, req *cache.AsyncRequest, budget func() error, onChunk func(*cache.EmbeddingResponse)) *Result {
+	result := &Result{
+		ID:         j.ID,
+		Status:     StatusCompleted,
+		Model:      req.Model,
+		Embeddings: make([][]float64, 0, len(req.Input)),
+		CreatedAt:  j.CreatedAt,
+	}
+
+	for i, chunk := range req.Chunks() {
+		offset := len(result.Embeddings)
+
+		if i > 0 && budget != nil {
+			if err := budget(); err != nil {
+				result.Status = StatusFailed
+				result.Error = fmt.Sprintf("inputs starting at index %d: %v", offset, err)
+				break
+			}
+		}
+
+		chunkCtx, cancel := context.WithTimeout(ctx, chunkTimeout)
+		response, err := c.GetEmbedding(chunkCtx, chunk)
+		cancel()
+
+		if ctx.Err() != nil {
+			result.Status = StatusCancelled
+			result.Error = "job cancelled"
+			break
+		}
+		if err != nil {
+			result.Status = StatusFailed
+			result.Error = fmt.Sprintf("inputs starting at index %d: %v", offset, err)
+			break
+		}
+
+		c.RoundForResponse(response)
+		if onChunk != nil {
+			onChunk(response)
+		}
+
+		result.Model = response.Model
+		result.Embeddings = append(result.Embeddings, response.Embeddings...)
+		for _, itemErr := range response.Errors {
+			result.Errors = append(result.Errors, cache.ItemError{Index: offset + itemErr.Index, Error: itemErr.Error})
+		}
+		result.Usage.PromptTokens += response.TokenUsage.PromptTokens
+		result.Usage.TotalTokens += response.TokenUsage.TotalTokens
+
+		r.mu.Lock()
+		j.Processed = len(result.Embeddings)
+		r.mu.Unlock()
+	}
+
+	if result.Status != StatusCompleted {
+		result.Embeddings = nil
+		result.Errors = nil
+	}
+	result.FinishedAt = time.Now()
+
+	return result
+}
+
+// deliver posts the result to the job's webhook, retrying with backoff
+// until it is accepted, retries run out or the registry stops.
+func (r *Registry) deliver(j *job, result *Result) {
+	payload := *result
+	if len(payload.Embeddings) > r.config.MaxInlineInputs {
+		payload.Embeddings = nil
+		payload.ResultURL = strings.TrimSuffix(r.config.ResultBaseURL, "/") + j.path + "/" + j.ID + "/result"
+	}
+
+	delivery := DeliveryFailed
+	attempts := 0
+	for attempt := 0; attempt <= r.config.CallbackRetries && r.stopCtx.Err() == nil; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(attempt) * time.Duration(r.config.CallbackBackoffMs) * time.Millisecond
+			select {
+			case <-r.stopCtx.Done():
+				continue
+			case <-time.After(backoff):
+			}
+		}
+
+		attempts++
+		r.mu.Lock()
+		j.DeliveryAttempts = attempts
+		r.mu.Unlock()
+
+		err := r.client.post(r.stopCtx, j.callbackURL, j.ID, &payload)
+		if err == nil {
+			delivery = DeliveryDelivered
+			break
+		}
+
+		r.logger.Warn("Async job callback failed",
+			zap.String("job_id", j.ID),
+			zap.Int("attempt", attempt+1),
+			zap.Error(err))
+	}
+
+	r.mu.Lock()
+	j.Delivery = delivery
+	r.mu.Unlock()
+
+	if delivery == DeliveryFailed {
+		r.logger.Error("Giving up on async job callback",
+			zap.String("job_id", j.ID),
+			zap.Int("attempts", attempts))
+		return
+	}
+
+	r.logger.Info("Async job callback delivered", zap.String("job_id", j.ID))
+}
+
+// Get returns a job of tenant (empty without tenants), and its result once
+// it has finished.
+func (r *Registry) Get(id, tenant string) (Job, *Result, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sweepLocked()
+
+	j, ok := r.jobs[id]
+	if !ok || j.tenant != tenant {
+		return Job{}, nil, false
+	}
+	return j.Job, j.result, true
+}
+
+// Cancel stops a running job. Its callback still reports it as cancelled.
+func (r *Registry) Cancel(id, tenant string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j, ok := r.jobs[id]
+	if !ok || j.tenant != tenant {
+		return Job{}, false
+	}
+
+	if j.Status == StatusRunning {
+		j.cancel()
+		r.logger.Info("Cancelling async embedding job", zap.String("job_id", id))
+	}
+
+	return j.Job, true
+}
+
+// Stop cancels all running jobs and pending callbacks and waits for them
+// to return.
+func (r *Registry) Stop() {
+	r.stop()
+	r.wg.Wait()
+}
+
+func (r *Registry) sweepLocked() {
+	now := time.Now()
+	for id, j := range r.jobs {
+		if j.FinishedAt != nil && j.Delivery != DeliveryPending && now.Sub(*j.FinishedAt) > r.ttl {
+			delete(r.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package asyncjobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+)
+
+// Callback headers. The signature is the hex HMAC-SHA256, keyed by
+// async.callback_secret, of the timestamp, a ".", and the raw body;
+// receivers should recompute it and reject stale timestamps.
+const (
+	HeaderJobID     = "X-Meep-Job-Id"
+	HeaderTimestamp = "X-Meep-Timestamp"
+	HeaderSignature = "X-Meep-Signature"
+)
+
+type webhookClient struct {
+	client *http.Client
+	secret []byte
+}
+
+func newWebhookClient(cfg *config.AsyncConfig) *webhookClient {
+	return &webhookClient{
+		client: &http.Client{
+			Timeout: time.Duration(cfg.CallbackTimeoutSec) * time.Second,
+			// A redirect would send the signed result somewhere unvetted.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		secret: []byte(cfg.CallbackSecret),
+	}
+}
+
+// sign returns the X-Meep-Signature value for a callback body.
+func sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *webhookClient) post(ctx context.Context, callbackURL, jobID string, result *Result) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to serialize callback body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create callback request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderJobID, jobID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, sign(w.secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send callback: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+)
+
+type Recorder struct {
+	db            *database.Database
+	logger        *zap.Logger
+	entryChan     chan *database.AuditEntry
+	batchSize     int
+	flushInterval time.Duration
+	stopChan      chan struct{}
+	wg            sync.WaitGroup
+	buffer        []*database.AuditEntry
+	bufferMutex   sync.Mutex
+}
+
+func New(db *database.Database, logger *zap.Logger, batchSize int, flushInterval time.Duration) *Recorder {
+	return &Recorder{
+		db:            db,
+		logger:        logger,
+		entryChan:     make(chan *database.AuditEntry, 1000),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		stopChan:      make(chan struct{}),
+		buffer:        make([]*database.AuditEntry, 0, batchSize),
+	}
+}
+
+func (r *Recorder) Start(ctx context.Context) {
+	r.logger.Info("Starting audit recorder",
+		zap.Int("batch_size", r.batchSize),
+		zap.Duration("flush_interval", r.flushInterval))
+
+	r.wg.Add(2)
+
+	go r.processEntries(ctx)
+	go r.flushPeriodically(ctx)
+}
+
+func (r *Recorder) Stop() {
+	r.logger.Info("Stopping audit recorder")
+
+	close(r.stopChan)
+	close(r.entryChan)
+
+	r.wg.Wait()
+
+	r.flushBuffer()
+
+	r.logger.Info("Audit recorder stopped")
+}
+
+func (r *Recorder) Record(entry *database.AuditEntry) {
+	select {
+	case r.entryChan <- entry:
+	default:
+		r.logger.Warn("Audit channel full, dropping audit entry",
+			zap.String("client_ip", entry.ClientIP),
+			zap.Int("batch_size", entry.BatchSize))
+	}
+}
+
+func (r *Recorder) processEntries(ctx context.Context) {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case entry, ok := <-r.entryChan:
+			if !ok {
+				return
+			}
+
+			r.bufferMutex.Lock()
+			r.buffer = append(r.buffer, entry)
+			shouldFlush := len(r.buffer) >= r.batchSize
+			r.bufferMutex.Unlock()
+
+			if shouldFlush {
+				r.flushBuffer()
+			}
+
+		case <-r.stopChan:
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Recorder) flushPeriodically(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.flushBuffer()
+
+		case <-r.stopChan:
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Recorder) flushBuffer() {
+	r.bufferMutex.Lock()
+	if len(r.buffer) == 0 {
+		r.bufferMutex.Unlock()
+		return
+	}
+
+	batch := make([]*database.AuditEntry, len(r.buffer))
+	copy(batch, r.buffer)
+	r.buffer = r.buffer[:0]
+	r.bufferMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := r.db.RecordAudit(ctx, batch...); err != nil {
+		r.logger.Error("Failed to record audit entries",
+			zap.Error(err),
+			zap.Int("batch_size", len(batch)))
+	} else {
+		r.logger.Debug("Recorded audit entries",
+			zap.Int("batch_size", len(batch)))
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"fmt"
+)
+
+// AsyncRequest is a batch too large for one request, embedded in the
+// background in chunks of MaxBatchSize and delivered to a webhook.
+type AsyncRequest struct {
+	Input       []string `json:"input" binding:"required"`
+	Model       string   `json:"model,omitempty"`
+	Normalize   *bool    `json:"normalize,omitempty"`
+	User        string   `json:"user,omitempty"`
+	Dimensions  int      `json:"dimensions,omitempty"`
+	CallbackURL string   `json:"callback_url,omitempty"` // overrides async.callback_url; host must be allowed
+	Tenant      string   `json:"-"`
+}
+
+// Chunks splits the request into batch requests of at most MaxBatchSize
+// inputs, in input order.
+func (r *AsyncRequest) Chunks() []*EmbeddingRequest {
+	chunks := make([]*EmbeddingRequest, 0, (len(r.Input)+MaxBatchSize-1)/MaxBatchSize)
+	for start := 0; start < len(r.Input); start += MaxBatchSize {
+		end := min(start+MaxBatchSize, len(r.Input))
+
+		inputs := make([]interface{}, end-start)
+		for i, input := range r.Input[start:end] {
+			inputs[i] = input
+		}
+
+		chunks = append(chunks, &EmbeddingRequest{
+			Input:      inputs,
+			Model:      r.Model,
+			Normalize:  r.Normalize,
+			User:       r.User,
+			Dimensions: r.Dimensions,
+			Tenant:     r.Tenant,
+		})
+	}
+	return chunks
+}
+
+func (c *Cache) ValidateAsyncRequest(req *AsyncRequest, maxInputs int) error {
+	if len(req.Input) == 0 {
+		return fmt.Errorf("input cannot be empty")
+	}
+
+	if maxInputs > 0 && len(req.Input) > maxInputs {
+		return fmt.Errorf("async input too large (max %d items)", maxInputs)
+	}
+
+	if c.ai.ModelConfig(c.resolveModel(req.Model)).MultiVector {
+		return fmt.Errorf("async jobs are not supported for multi-vector models")
+	}
+
+	for i, chunk := range req.Chunks() {
+		if err := c.ValidateRequest(chunk); err != nil {
+			return fmt.Errorf("in inputs starting at index %d: %w", i*MaxBatchSize, err)
+		}
+	}
+
+	return nil
+}
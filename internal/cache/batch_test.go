@@ -0,0 +1,288 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
+)
+
+const testModel = "text-embedding-3-small"
+
+// fakeStore serves the inputs in hits from the cache and records every
+// store. Methods the tests do not reach are left to the nil Store.
+type fakeStore struct {
+	cache.Store
+
+	mu     sync.Mutex
+	hits   map[string][]float64
+	stored map[string][]float64
+}
+
+func newFakeStore(hits map[string][]float64) *fakeStore {
+	return &fakeStore{hits: hits, stored: make(map[string][]float64)}
+}
+
+func (s *fakeStore) cached(input string) *database.CachedEmbedding {
+	vector, ok := s.hits[input]
+	if !ok {
+		return nil
+	}
+	return &database.CachedEmbedding{
+		ID:              uuid.New(),
+		InputText:       input,
+		EmbeddingVector: vector,
+		ModelName:       testModel,
+		CreatedAt:       time.Now(),
+		UsedAt:          time.Now(),
+	}
+}
+
+func (s *fakeStore) GetCachedEmbedding(ctx context.Context, inputHash, modelName string) (*database.CachedEmbedding, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) GetBatchCachedEmbeddings(ctx context.Context, items []*database.BatchItem, modelName string) ([]*database.BatchItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		item.Cached = s.cached(item.Input)
+	}
+	return items, nil
+}
+
+func (s *fakeStore) StoreEmbedding(ctx context.Context, inputHash, inputText, modelName string, vector []float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stored[inputText] = vector
+	return nil
+}
+
+func (s *fakeStore) ReplaceEmbedding(ctx context.Context, inputHash, inputText, modelName string, vector []float64) error {
+	return s.StoreEmbedding(ctx, inputHash, inputText, modelName, vector)
+}
+
+// fakeEmbedder answers batch calls with embed, and everything else with a
+// mock-mode client.
+type fakeEmbedder struct {
+	*openai.Client
+
+	mu    sync.Mutex
+	calls [][]string
+	embed func(ctx context.Context, inputs []string) (*openai.EmbeddingResponse, error)
+}
+
+func newFakeEmbedder(t *testing.T, embed func(ctx context.Context, inputs []string) (*openai.EmbeddingResponse, error)) *fakeEmbedder {
+	t.Helper()
+
+	client, err := openai.New(&config.OpenAIConfig{
+		Mock:            true,
+		Model:           testModel,
+		ChunkSize:       1000,
+		ResponseOrder:   "index",
+		HealthWindowSec: 60,
+		TimeoutSec:      30,
+		MaxRetries:      1,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+
+	return &fakeEmbedder{Client: client, embed: embed}
+}
+
+func (e *fakeEmbedder) CreateBatchEmbeddingsWithModel(ctx context.Context, inputs []string, model string) (*openai.EmbeddingResponse, error) {
+	e.mu.Lock()
+	e.calls = append(e.calls, append([]string(nil), inputs...))
+	e.mu.Unlock()
+	return e.embed(ctx, inputs)
+}
+
+// vectorFor gives every input its own recognisable vector, so a result in
+// the wrong position shows up as the wrong values.
+func vectorFor(input string) []float64 {
+	var n float64
+	fmt.Sscanf(input[strings.LastIndex(input, "-")+1:], "%g", &n)
+	return []float64{n, n + 0.5}
+}
+
+func embedAll(ctx context.Context, inputs []string) (*openai.EmbeddingResponse, error) {
+	response := &openai.EmbeddingResponse{Model: testModel}
+	for _, input := range inputs {
+		response.Embeddings = append(response.Embeddings, vectorFor(input))
+	}
+	response.TokenUsage.PromptTokens = len(inputs)
+	response.TokenUsage.TotalTokens = len(inputs)
+	return response, nil
+}
+
+func batchRequest(inputs ...string) *cache.EmbeddingRequest {
+	items := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		items[i] = input
+	}
+	normalize := false
+	return &cache.EmbeddingRequest{Input: items, Normalize: &normalize}
+}
+
+func inputs(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("item-%d", i)
+	}
+	return out
+}
+
+func TestBatchKeepsInputOrderAcrossHitsAndMisses(t *testing.T) {
+	in := inputs(6)
+	store := newFakeStore(map[string][]float64{
+		in[1]: vectorFor(in[1]),
+		in[4]: vectorFor(in[4]),
+	})
+	embedder := newFakeEmbedder(t, embedAll)
+	c := cache.NewWithDeps(store, embedder, &config.CacheConfig{}, zap.NewNop())
+
+	response, err := c.GetEmbedding(context.Background(), batchRequest(in...))
+	if err != nil {
+		t.Fatalf("GetEmbedding: %v", err)
+	}
+
+	for i, input := range in {
+		if !reflect.DeepEqual(response.Embeddings[i], vectorFor(input)) {
+			t.Errorf("embedding %d = %v, want %v", i, response.Embeddings[i], vectorFor(input))
+		}
+	}
+
+	wantCached := []bool{false, true, false, false, true, false}
+	if !reflect.DeepEqual(response.CachedItems, wantCached) {
+		t.Errorf("cached_items = %v, want %v", response.CachedItems, wantCached)
+	}
+
+	wantCall := []string{in[0], in[2], in[3], in[5]}
+	if len(embedder.calls) != 1 || !reflect.DeepEqual(embedder.calls[0], wantCall) {
+		t.Errorf("provider calls = %v, want one call with %v", embedder.calls, wantCall)
+	}
+
+	if response.Partial || len(response.Errors) > 0 {
+		t.Errorf("unexpected partial response: %+v", response.Errors)
+	}
+}
+
+func TestBatchReportsFailedItemsAtTheirIndex(t *testing.T) {
+	in := inputs(5)
+	store := newFakeStore(map[string][]float64{in[0]: vectorFor(in[0])})
+	embedder := newFakeEmbedder(t, func(ctx context.Context, inputs []string) (*openai.EmbeddingResponse, error) {
+		response, _ := embedAll(ctx, inputs)
+		// in[3], the third miss, was dropped as an invalid vector.
+		response.Embeddings[2] = nil
+		return response, nil
+	})
+	c := cache.NewWithDeps(store, embedder, &config.CacheConfig{ServePartialOnProviderError: true}, zap.NewNop())
+
+	response, err := c.GetEmbedding(context.Background(), batchRequest(in...))
+	if err != nil {
+		t.Fatalf("GetEmbedding: %v", err)
+	}
+
+	if !response.Partial {
+		t.Error("response is not marked partial")
+	}
+	if len(response.Errors) != 1 || response.Errors[0].Index != 3 {
+		t.Fatalf("errors = %+v, want one error at index 3", response.Errors)
+	}
+
+	for i, input := range in {
+		want := vectorFor(input)
+		if i == 3 {
+			want = nil
+		}
+		if !reflect.DeepEqual(response.Embeddings[i], want) {
+			t.Errorf("embedding %d = %v, want %v", i, response.Embeddings[i], want)
+		}
+	}
+
+	if _, ok := store.stored[in[3]]; ok {
+		t.Error("failed item was stored")
+	}
+}
+
+func TestBatchShortProviderResponse(t *testing.T) {
+	in := inputs(4)
+	short := func(ctx context.Context, inputs []string) (*openai.EmbeddingResponse, error) {
+		response, _ := embedAll(ctx, inputs)
+		response.Embeddings = response.Embeddings[:len(inputs)-1]
+		return response, nil
+	}
+
+	t.Run("rejected without partial responses", func(t *testing.T) {
+		store := newFakeStore(map[string][]float64{in[2]: vectorFor(in[2])})
+		c := cache.NewWithDeps(store, newFakeEmbedder(t, short), &config.CacheConfig{}, zap.NewNop())
+
+		if _, err := c.GetEmbedding(context.Background(), batchRequest(in...)); err == nil {
+			t.Fatal("expected an error for a misaligned provider response")
+		}
+		if len(store.stored) > 0 {
+			t.Errorf("stored %d vectors from a misaligned response", len(store.stored))
+		}
+	})
+
+	t.Run("serves hits and fails every miss", func(t *testing.T) {
+		store := newFakeStore(map[string][]float64{in[2]: vectorFor(in[2])})
+		c := cache.NewWithDeps(store, newFakeEmbedder(t, short), &config.CacheConfig{ServePartialOnProviderError: true}, zap.NewNop())
+
+		response, err := c.GetEmbedding(context.Background(), batchRequest(in...))
+		if err != nil {
+			t.Fatalf("GetEmbedding: %v", err)
+		}
+
+		var failed []int
+		for _, itemErr := range response.Errors {
+			failed = append(failed, itemErr.Index)
+		}
+		if want := []int{0, 1, 3}; !reflect.DeepEqual(failed, want) {
+			t.Errorf("failed indexes = %v, want %v", failed, want)
+		}
+
+		if !reflect.DeepEqual(response.Embeddings[2], vectorFor(in[2])) || !response.CachedItems[2] {
+			t.Errorf("cached item 2 = %v (cached %v), want %v", response.Embeddings[2], response.CachedItems[2], vectorFor(in[2]))
+		}
+		for _, i := range failed {
+			if response.Embeddings[i] != nil || response.CachedItems[i] {
+				t.Errorf("failed item %d has embedding %v, cached %v", i, response.Embeddings[i], response.CachedItems[i])
+			}
+		}
+	})
+}
+
+func TestBatchDuplicateInputsShareOneResult(t *testing.T) {
+	in := []string{"item-7", "item-3", "item-7", "item-3", "item-9"}
+	store := newFakeStore(map[string][]float64{"item-9": vectorFor("item-9")})
+	embedder := newFakeEmbedder(t, embedAll)
+	c := cache.NewWithDeps(store, embedder, &config.CacheConfig{}, zap.NewNop())
+
+	response, err := c.GetEmbedding(context.Background(), batchRequest(in...))
+	if err != nil {
+		t.Fatalf("GetEmbedding: %v", err)
+	}
+
+	if len(response.Embeddings) != len(in) {
+		t.Fatalf("got %d embeddings for %d inputs", len(response.Embeddings), len(in))
+	}
+	for i, input := range in {
+		if !reflect.DeepEqual(response.Embeddings[i], vectorFor(input)) {
+			t.Errorf("embedding %d = %v, want %v", i, response.Embeddings[i], vectorFor(input))
+		}
+	}
+}
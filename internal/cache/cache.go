@@ -2,42 +2,153 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/hash"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/storeretry"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/tokenizer"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/tracker"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/vectorcheck"
 )
 
 type Cache struct {
-	db      *database.Database
-	ai      *openai.Client
-	hasher  *hash.Hasher
-	logger  *zap.Logger
-	tracker *tracker.UsageTracker
+	db       Store
+	ai       Embedder
+	hasher   *hash.Hasher
+	logger   *zap.Logger
+	tracker  *tracker.UsageTracker
+	retry    *storeretry.Queue
+	config   *config.CacheConfig
+	inflight *flightGroup
+	counters *runtimeCounters
 }
 
 type EmbeddingRequest struct {
-	Input interface{} `json:"input" binding:"required"` // string or []string
-	Model string      `json:"model,omitempty"`
+	Input      interface{} `json:"input" binding:"required"` // string or []string
+	Model      string      `json:"model,omitempty"`
+	Normalize  *bool       `json:"normalize,omitempty"`
+	User       string      `json:"user,omitempty"` // forwarded to the provider, not part of the hash
+	Dimensions int         `json:"dimensions,omitempty"`
+	Formats    []string    `json:"formats,omitempty"` // response representations; not part of the hash
+	MaxAge     int         `json:"max_age,omitempty"` // seconds; older hits are re-embedded, not part of the hash
+	Tenant     string      `json:"-"`
 }
 
 type EmbeddingResponse struct {
-	Embedding   []float64   `json:"embedding,omitempty"`
-	Embeddings  [][]float64 `json:"embeddings,omitempty"`
-	Model       string      `json:"model"`
-	Cached      bool        `json:"cached,omitempty"`
-	CachedItems []bool      `json:"cached_items,omitempty"`
-	TokenUsage  struct {
+	Embedding       []float64     `json:"embedding,omitempty"`
+	Embeddings      [][]float64   `json:"embeddings,omitempty"`
+	MultiEmbedding  [][]float64   `json:"multi_embedding,omitempty"`
+	MultiEmbeddings [][][]float64 `json:"multi_embeddings,omitempty"`
+	// EmbeddingFormats replaces Embedding/Embeddings when the request lists
+	// formats: one object, or one per batch item, keyed by format.
+	EmbeddingFormats interface{}   `json:"embedding_formats,omitempty"`
+	Model            string        `json:"model"`
+	ProviderModel    string        `json:"provider_model,omitempty"` // set when the provider reported a different model
+	Cached           bool          `json:"cached,omitempty"`
+	CachedItems      []bool        `json:"cached_items,omitempty"`
+	Summary          *BatchSummary `json:"summary,omitempty"`
+	Partial          bool          `json:"partial,omitempty"`
+	Errors           []ItemError   `json:"errors,omitempty"`
+	Meta             *Meta         `json:"meta,omitempty"`
+	Inputs           []EchoItem    `json:"inputs,omitempty"`
+	EmptyItems       []int         `json:"empty_items,omitempty"`
+	TruncatedItems   []int         `json:"truncated_items,omitempty"`
+	TokenUsage       struct {
 		PromptTokens int `json:"prompt_tokens"`
 		TotalTokens  int `json:"total_tokens"`
 	} `json:"usage,omitempty"`
 }
 
+// BatchSummary counts where the items of a batch response came from.
+// Failed and empty items are neither cached nor fresh.
+type BatchSummary struct {
+	Total  int `json:"total"`
+	Cached int `json:"cached"`
+	Fresh  int `json:"fresh"`
+	Failed int `json:"failed,omitempty"`
+	Empty  int `json:"empty,omitempty"`
+}
+
+func summarizeBatch(response *EmbeddingResponse) *BatchSummary {
+	summary := &BatchSummary{
+		Total:  len(response.CachedItems),
+		Failed: len(response.Errors),
+		Empty:  len(response.EmptyItems),
+	}
+
+	for _, cached := range response.CachedItems {
+		if cached {
+			summary.Cached++
+		}
+	}
+
+	summary.Fresh = summary.Total - summary.Cached - summary.Failed - summary.Empty
+	return summary
+}
+
+type ItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type Meta struct {
+	EstimatedTokens int `json:"estimated_tokens"`
+}
+
+// EchoItem identifies the input behind the embedding at the same index.
+type EchoItem struct {
+	Index     int    `json:"index"`
+	InputHash string `json:"input_hash"`
+	Text      string `json:"text"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+const echoTextLimit = 200
+
+// MaxBatchSize is the largest number of inputs accepted in one request.
+const MaxBatchSize = 1000
+
+// RefreshRequest re-embeds inputs under the key a request with the same
+// model, normalize, dimensions and tenant would use. Hashes are matched
+// against those variants for the given tenant.
+type RefreshRequest struct {
+	Input      interface{} `json:"input,omitempty"` // string or []string
+	Hashes     []string    `json:"hashes,omitempty"`
+	Model      string      `json:"model,omitempty"`
+	Normalize  *bool       `json:"normalize,omitempty"`
+	Dimensions int         `json:"dimensions,omitempty"`
+	Tenant     string      `json:"tenant,omitempty"`
+}
+
+func (r *RefreshRequest) embeddingRequest() *EmbeddingRequest {
+	return &EmbeddingRequest{
+		Model:      r.Model,
+		Normalize:  r.Normalize,
+		Dimensions: r.Dimensions,
+		Tenant:     r.Tenant,
+	}
+}
+
+type RefreshResult struct {
+	InputHash     string   `json:"input_hash"`
+	Model         string   `json:"model"`
+	Existed       bool     `json:"existed"`
+	OldDimensions int      `json:"old_dimensions,omitempty"`
+	NewDimensions int      `json:"new_dimensions,omitempty"`
+	CosineDelta   *float64 `json:"cosine_delta,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
 
 type BatchResult struct {
 	Embedding []float64
@@ -51,24 +162,47 @@ type CacheStats struct {
 	AvgInputLength int64 `json:"avg_input_length"`
 }
 
-func New(db *database.Database, ai *openai.Client, hasher *hash.Hasher, tracker *tracker.UsageTracker, logger *zap.Logger) *Cache {
+func New(db Store, ai Embedder, hasher *hash.Hasher, tracker *tracker.UsageTracker, retry *storeretry.Queue, cfg *config.CacheConfig, logger *zap.Logger) *Cache {
 	return &Cache{
-		db:      db,
-		ai:      ai,
-		hasher:  hasher,
-		logger:  logger,
-		tracker: tracker,
+		db:       db,
+		ai:       ai,
+		hasher:   hasher,
+		logger:   logger,
+		tracker:  tracker,
+		retry:    retry,
+		config:   cfg,
+		inflight: newFlightGroup(),
+		counters: newRuntimeCounters(),
 	}
 }
 
 func (c *Cache) GetEmbedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
 	isBatch := c.isBatchInput(req.Input)
+	ctx = openai.WithUser(ctx, req.User)
+	ctx = openai.WithDimensions(ctx, req.Dimensions)
+
+	modelName := c.resolveModel(req.Model)
+
+	var response *EmbeddingResponse
+	var err error
+	switch {
+	case c.ai.ModelConfig(modelName).MultiVector:
+		response, err = c.processMultiVectorRequest(ctx, req, modelName, isBatch)
+	case isBatch:
+		response, err = c.processBatchRequest(ctx, req)
+	default:
+		response, err = c.processSingleRequest(ctx, req)
+	}
+
+	if err == nil && c.truncatesOverLimit() {
+		response.TruncatedItems = c.overLimitItems(req, modelName)
+	}
 
-	if isBatch {
-		return c.processBatchRequest(ctx, req)
+	if err == nil && isBatch {
+		response.Summary = summarizeBatch(response)
 	}
 
-	return c.processSingleRequest(ctx, req)
+	return response, err
 }
 
 func (c *Cache) isBatchInput(input interface{}) bool {
@@ -88,20 +222,78 @@ func (c *Cache) normalizeInput(input interface{}) ([]string, error) {
 	switch v := input.(type) {
 	case string:
 		return []string{v}, nil
+	case map[string]interface{}:
+		str, err := c.extractInputField(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{str}, nil
 	case []interface{}:
 		result := make([]string, len(v))
 		for i, item := range v {
-			if str, ok := item.(string); ok {
+			switch value := item.(type) {
+			case string:
+				result[i] = value
+			case map[string]interface{}:
+				str, err := c.extractInputField(value)
+				if err != nil {
+					return nil, fmt.Errorf("batch input item at index %d: %w", i, err)
+				}
 				result[i] = str
-			} else {
-				return nil, fmt.Errorf("batch input item at index %d is not a string", i)
+			default:
+				return nil, fmt.Errorf("batch input item at index %d is a %s, expected string", i, jsonTypeName(item))
 			}
 		}
 		return result, nil
 	case []string:
 		return v, nil
 	default:
-		return nil, fmt.Errorf("invalid input type: expected string or array of strings")
+		return nil, fmt.Errorf("invalid input type: got %s, expected string or array of strings", jsonTypeName(input))
+	}
+}
+
+func (c *Cache) extractInputField(object map[string]interface{}) (string, error) {
+	if c.config.InputField == "" {
+		return "", fmt.Errorf("input is an object, expected string (set cache.input_field to extract a field)")
+	}
+
+	var current interface{} = object
+	for _, key := range strings.Split(c.config.InputField, ".") {
+		fields, ok := current.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("input field %q not found", c.config.InputField)
+		}
+
+		current, ok = fields[key]
+		if !ok {
+			return "", fmt.Errorf("input field %q not found", c.config.InputField)
+		}
+	}
+
+	str, ok := current.(string)
+	if !ok {
+		return "", fmt.Errorf("input field %q is a %s, expected string", c.config.InputField, jsonTypeName(current))
+	}
+
+	return str, nil
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
 	}
 }
 
@@ -116,20 +308,28 @@ func (c *Cache) processSingleRequest(ctx context.Context, req *EmbeddingRequest)
 		return nil, fmt.Errorf("input text cannot be empty")
 	}
 
-	modelName := req.Model
-	if modelName == "" {
-		modelName = c.ai.GetModel()
+	modelName := c.resolveModel(req.Model)
+
+	input = c.prepareInputs(inputs, modelName)[0]
+	if input == "" {
+		return nil, fmt.Errorf("input text cannot be empty after normalization")
 	}
 
 	startTime := time.Now()
-	inputHash := c.hasher.GenerateInputHash(input, modelName)
+	normalize := c.shouldNormalize(req)
+	inputHash := c.inputHash(input, modelName, c.hashVariants(req)...)
 
 	c.logger.Info("Processing embedding request",
 		zap.String("input_hash", inputHash[:16]+"..."),
 		zap.String("model", modelName),
 		zap.Int("input_length", len(input)))
 
-	cached, err := c.db.GetCachedEmbedding(ctx, inputHash)
+	store := c.db.StoreEmbedding
+	cached, err := c.db.GetCachedEmbedding(ctx, inputHash, modelName)
+	if errors.Is(err, database.ErrCorruptEmbedding) {
+		cached, err = nil, nil
+		store = c.db.ReplaceEmbedding
+	}
 	if err != nil {
 		c.logger.Error("Failed to check cache",
 			zap.String("input_hash", inputHash[:16]+"..."),
@@ -137,12 +337,31 @@ func (c *Cache) processSingleRequest(ctx context.Context, req *EmbeddingRequest)
 		return nil, fmt.Errorf("failed to check cache: %w", err)
 	}
 
+	if maxAge := c.maxAge(req); isStale(cached, maxAge) {
+		c.counters.staleHits.Add(1)
+		c.logger.Info("Treating stale cache hit as a miss",
+			zap.String("input_hash", inputHash[:16]+"..."),
+			zap.Time("cached_at", cached.CreatedAt),
+			zap.Duration("max_age", maxAge))
+		cached = nil
+		store = c.db.ReplaceEmbedding
+	}
+	if c.wrongSize(cached, req, modelName) {
+		c.logger.Info("Treating cache hit of the wrong size as a miss",
+			zap.String("input_hash", inputHash[:16]+"..."),
+			zap.Int("cached_dimensions", len(cached.EmbeddingVector)))
+		cached = nil
+		store = c.db.ReplaceEmbedding
+	}
+
 	if cached != nil {
-		c.logger.Info("Cache hit",
+		c.counters.record(1, 0)
+		c.logger.Info("Cache hit", append([]zap.Field{
 			zap.String("input_hash", inputHash[:16]+"..."),
 			zap.Duration("lookup_time", time.Since(startTime)),
 			zap.Time("cached_at", cached.CreatedAt),
-			zap.Time("last_used", cached.UsedAt))
+			zap.Time("last_used", cached.UsedAt),
+		}, c.savedCostFields(modelName, []string{input})...)...)
 
 		if c.tracker != nil {
 			c.tracker.TrackUsage(cached.ID)
@@ -155,29 +374,76 @@ func (c *Cache) processSingleRequest(ctx context.Context, req *EmbeddingRequest)
 		}, nil
 	}
 
+	c.counters.record(0, 1)
+
+	call, leader := c.inflight.claim(inputHash)
+	if !leader {
+		c.logger.Info("Cache miss, waiting for in-flight embedding",
+			zap.String("input_hash", inputHash[:16]+"..."),
+			zap.Duration("lookup_time", time.Since(startTime)))
+
+		embedding, err := call.wait(ctx)
+		if abandoned(ctx, err) {
+			return c.processSingleRequest(ctx, req)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embedding: %w", err)
+		}
+
+		return &EmbeddingResponse{
+			Embedding: embedding,
+			Model:     modelName,
+			Cached:    false,
+		}, nil
+	}
+
 	c.logger.Info("Cache miss, calling OpenAI API",
 		zap.String("input_hash", inputHash[:16]+"..."),
 		zap.Duration("lookup_time", time.Since(startTime)))
 
-	aiResponse, err := c.ai.CreateEmbedding(ctx, input)
+	c.counters.providerCalls.Add(1)
+	aiResponse, err := c.ai.CreateEmbeddingWithModel(ctx, input, modelName)
 	if err != nil {
+		c.inflight.finish(inputHash, call, nil, err)
 		c.logger.Error("Failed to create embedding via OpenAI",
 			zap.String("input_hash", inputHash[:16]+"..."),
 			zap.Error(err))
 		return nil, fmt.Errorf("failed to create embedding: %w", err)
 	}
 
-	err = c.db.StoreEmbedding(ctx, inputHash, input, modelName, aiResponse.Embedding)
+	if normalize {
+		NormalizeVector(aiResponse.Embedding)
+	}
+
+	if !c.ai.Cacheable(aiResponse) {
+		c.inflight.finish(inputHash, call, aiResponse.Embedding, nil)
+		c.logger.Info("Serving fallback embedding without caching",
+			zap.String("input_hash", inputHash[:16]+"..."),
+			zap.String("provider", aiResponse.Provider))
+
+		return &EmbeddingResponse{
+			Embedding:     aiResponse.Embedding,
+			Model:         aiResponse.Model,
+			ProviderModel: aiResponse.ProviderModel,
+			Cached:        false,
+			TokenUsage:    aiResponse.TokenUsage,
+		}, nil
+	}
+
+	err = store(ctx, inputHash, input, modelName, aiResponse.Embedding)
+	c.inflight.finish(inputHash, call, aiResponse.Embedding, nil)
 	if err != nil {
 		c.logger.Error("Failed to store embedding in cache",
 			zap.String("input_hash", inputHash[:16]+"..."),
 			zap.Error(err))
+		c.enqueueStoreRetry(inputHash, input, modelName, aiResponse.Embedding)
 
 		return &EmbeddingResponse{
-			Embedding:  aiResponse.Embedding,
-			Model:      aiResponse.Model,
-			Cached:     false,
-			TokenUsage: aiResponse.TokenUsage,
+			Embedding:     aiResponse.Embedding,
+			Model:         aiResponse.Model,
+			ProviderModel: aiResponse.ProviderModel,
+			Cached:        false,
+			TokenUsage:    aiResponse.TokenUsage,
 		}, nil
 	}
 
@@ -190,10 +456,11 @@ func (c *Cache) processSingleRequest(ctx context.Context, req *EmbeddingRequest)
 		zap.Int("prompt_tokens", aiResponse.TokenUsage.PromptTokens))
 
 	return &EmbeddingResponse{
-		Embedding:  aiResponse.Embedding,
-		Model:      aiResponse.Model,
-		Cached:     false,
-		TokenUsage: aiResponse.TokenUsage,
+		Embedding:     aiResponse.Embedding,
+		Model:         aiResponse.Model,
+		ProviderModel: aiResponse.ProviderModel,
+		Cached:        false,
+		TokenUsage:    aiResponse.TokenUsage,
 	}, nil
 }
 
@@ -211,13 +478,45 @@ func (c *Cache) GetStats(ctx context.Context) (map[string]interface{}, error) {
 		},
 	}
 
+	result["runtime_stats"] = c.counters.snapshot()
+	result["pool_stats"] = c.db.PoolStats()
+
 	if c.tracker != nil {
 		result["tracker_stats"] = c.tracker.GetStats()
 	}
 
+	if c.retry != nil {
+		result["store_retry_stats"] = c.retry.GetStats()
+	}
+
 	return result, nil
 }
 
+func (c *Cache) ProviderHealth() openai.ProviderHealth {
+	return c.ai.ProviderHealth()
+}
+
+func (c *Cache) Models() []openai.ModelInfo {
+	return c.ai.Models()
+}
+
+func (c *Cache) DeleteUsedBefore(ctx context.Context, t time.Time) (int64, error) {
+	return c.db.DeleteByUsedBefore(ctx, t)
+}
+
+func (c *Cache) DeleteCreatedBefore(ctx context.Context, t time.Time) (int64, error) {
+	return c.db.DeleteByCreatedBefore(ctx, t)
+}
+
+func (c *Cache) Vacuum(ctx context.Context, reindex bool) ([]database.VacuumResult, error) {
+	return c.db.Vacuum(ctx, reindex)
+}
+
+func (c *Cache) ResetCounters() {
+	c.counters.reset()
+	c.logger.Info("Runtime cache counters reset")
+}
+
 func (c *Cache) processBatchRequest(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
 	inputs, err := c.normalizeInput(req.Input)
 	if err != nil {
@@ -228,34 +527,48 @@ func (c *Cache) processBatchRequest(ctx context.Context, req *EmbeddingRequest)
 		return nil, fmt.Errorf("batch input cannot be empty")
 	}
 
-	if len(inputs) > 1000 {
-		return nil, fmt.Errorf("batch input too large (max 1000 items)")
+	if len(inputs) > MaxBatchSize {
+		return nil, fmt.Errorf("batch input too large (max %d items)", MaxBatchSize)
 	}
 
-	modelName := req.Model
-	if modelName == "" {
-		modelName = c.ai.GetModel()
+	modelName := c.resolveModel(req.Model)
+
+	inputs = c.prepareInputs(inputs, modelName)
+	if c.allowsEmptyItems(modelName) {
+		return c.processBatchWithEmptyItems(ctx, req, inputs, modelName)
 	}
+	if err := checkPreparedInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	return c.processPreparedBatch(ctx, req, inputs, modelName)
+}
 
+func (c *Cache) processPreparedBatch(ctx context.Context, req *EmbeddingRequest, inputs []string, modelName string) (*EmbeddingResponse, error) {
 	startTime := time.Now()
 
 	c.logger.Info("Processing batch embedding request",
 		zap.Int("batch_size", len(inputs)),
 		zap.String("model", modelName))
 
-	batchItems := c.prepareBatchItems(inputs, modelName)
-	batchItems, err = c.db.GetBatchCachedEmbeddings(ctx, batchItems)
+	normalize := c.shouldNormalize(req)
+	batchItems := c.prepareBatchItems(inputs, modelName, c.hashVariants(req))
+	batchItems, err := c.db.GetBatchCachedEmbeddings(ctx, batchItems, modelName)
 	if err != nil {
 		c.logger.Error("Failed to check batch cache",
 			zap.Error(err))
 		return nil, fmt.Errorf("failed to check cache: %w", err)
 	}
+	stale := withCorrupt(batchItems, c.dropStale(batchItems, c.maxAge(req)))
+	stale = c.dropWrongSize(batchItems, req, modelName, stale)
 
 	cacheHits := 0
 	cacheMisses := 0
+	var hitInputs []string
 	for _, item := range batchItems {
 		if item.Cached != nil {
 			cacheHits++
+			hitInputs = append(hitInputs, item.Input)
 			if c.tracker != nil {
 				c.tracker.TrackUsage(item.Cached.ID)
 			}
@@ -264,57 +577,218 @@ func (c *Cache) processBatchRequest(ctx context.Context, req *EmbeddingRequest)
 		}
 	}
 
-	c.logger.Info("Batch cache check completed",
+	c.counters.record(cacheHits, cacheMisses)
+
+	c.logger.Info("Batch cache check completed", append([]zap.Field{
 		zap.Int("cache_hits", cacheHits),
 		zap.Int("cache_misses", cacheMisses),
-		zap.Duration("lookup_time", time.Since(startTime)))
+		zap.Duration("lookup_time", time.Since(startTime)),
+	}, c.savedCostFields(modelName, hitInputs)...)...)
 
 	uncachedItems := c.getUncachedItems(batchItems)
 	var aiResponse *openai.EmbeddingResponse
+	itemErrors := make(map[int]string)
+
+	var ledItems []*database.BatchItem
+	var ledCalls []*flightCall
+	var waitingItems []*database.BatchItem
+	var waitingCalls []*flightCall
+	for _, item := range uncachedItems {
+		call, leader := c.inflight.claim(item.Hash)
+		if leader {
+			ledItems = append(ledItems, item)
+			ledCalls = append(ledCalls, call)
+		} else {
+			waitingItems = append(waitingItems, item)
+			waitingCalls = append(waitingCalls, call)
+		}
+	}
 
-	if len(uncachedItems) > 0 {
-		aiResponse, err = c.createBatchEmbeddings(ctx, uncachedItems, modelName)
+	if len(ledItems) > 0 {
+		c.counters.providerCalls.Add(1)
+		aiResponse, err = c.createBatchEmbeddings(ctx, ledItems, modelName)
+		if err == nil && len(aiResponse.Embeddings) != len(ledItems) {
+			// Never store or return a misaligned response: results are
+			// matched to inputs by position.
+			err = fmt.Errorf("provider returned %d embeddings for %d inputs", len(aiResponse.Embeddings), len(ledItems))
+		}
 		if err != nil {
-			c.logger.Error("Failed to create batch embeddings via OpenAI",
+			for i, item := range ledItems {
+				c.inflight.finish(item.Hash, ledCalls[i], nil, err)
+			}
+
+			if !c.config.ServePartialOnProviderError || cacheHits == 0 {
+				c.logger.Error("Failed to create batch embeddings via OpenAI",
+					zap.Error(err))
+				return nil, fmt.Errorf("failed to create embeddings: %w", err)
+			}
+
+			c.logger.Warn("Provider unavailable, serving cached items only",
+				zap.Int("cache_hits", cacheHits),
+				zap.Int("unavailable", len(uncachedItems)),
 				zap.Error(err))
-			return nil, fmt.Errorf("failed to create embeddings: %w", err)
+
+			aiResponse = nil
+			for _, item := range ledItems {
+				itemErrors[item.Index] = "provider unavailable"
+			}
+		} else {
+			if normalize {
+				for _, embedding := range aiResponse.Embeddings {
+					NormalizeVector(embedding)
+				}
+			}
+
+			err = c.storeBatchEmbeddings(ctx, ledItems, aiResponse, modelName, stale)
+			if err != nil {
+				c.logger.Error("Failed to store batch embeddings in cache",
+					zap.Error(err))
+			}
+
+			for i, item := range ledItems {
+				var embedding []float64
+				var callErr error
+				if i < len(aiResponse.Embeddings) {
+					embedding = aiResponse.Embeddings[i]
+				}
+				switch {
+				case i >= len(aiResponse.Embeddings):
+					callErr = fmt.Errorf("no embedding returned for input")
+				case embedding == nil:
+					// Dropped by openai.invalid_vectors = "skip".
+					callErr = fmt.Errorf("provider returned an %w", vectorcheck.ErrInvalidVector)
+					itemErrors[item.Index] = "provider returned an invalid vector"
+				}
+				c.inflight.finish(item.Hash, ledCalls[i], embedding, callErr)
+			}
 		}
+	}
 
-		err = c.storeBatchEmbeddings(ctx, uncachedItems, aiResponse, modelName)
+	sharedResults := make(map[int][]float64, len(waitingItems))
+	for i, item := range waitingItems {
+		embedding, err := waitingCalls[i].wait(ctx)
+		if abandoned(ctx, err) {
+			// Items this batch led are stored by now, so the rerun
+			// serves them from the cache.
+			return c.processPreparedBatch(ctx, req, inputs, modelName)
+		}
 		if err != nil {
-			c.logger.Error("Failed to store batch embeddings in cache",
-				zap.Error(err))
+			if !c.config.ServePartialOnProviderError || cacheHits == 0 {
+				return nil, fmt.Errorf("failed to create embeddings: %w", err)
+			}
+			itemErrors[item.Index] = "provider unavailable"
+			continue
+		}
+		sharedResults[item.Index] = embedding
+	}
+
+	if len(waitingItems) > 0 {
+		c.logger.Info("Shared in-flight embeddings for batch items",
+			zap.Int("shared", len(sharedResults)))
+	}
+
+	results := c.assembleBatchResults(batchItems, ledItems, aiResponse, len(inputs))
+	for index, embedding := range sharedResults {
+		results[index] = &BatchResult{
+			Embedding: embedding,
+			Cached:    false,
+			Index:     index,
 		}
 	}
 
-	results := c.assembleBatchResults(batchItems, uncachedItems, aiResponse, len(inputs))
+	for index, result := range results {
+		if result == nil || result.Embedding == nil {
+			if _, ok := itemErrors[index]; !ok {
+				itemErrors[index] = "no embedding returned by provider"
+			}
+		}
+	}
+
+	if len(itemErrors) > 0 && !c.config.ServePartialOnProviderError {
+		c.logger.Error("Batch items missing embeddings, rejecting batch",
+			zap.Int("missing", len(itemErrors)))
+		return nil, fmt.Errorf("failed to create embeddings: %d items missing or invalid in provider response", len(itemErrors))
+	}
+
+	failed := make([]ItemError, 0, len(itemErrors))
+	for index, message := range itemErrors {
+		failed = append(failed, ItemError{Index: index, Error: message})
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Index < failed[j].Index })
 
 	c.logger.Info("Successfully processed batch embedding request",
 		zap.Int("batch_size", len(inputs)),
 		zap.Int("cache_hits", cacheHits),
 		zap.Int("cache_misses", cacheMisses),
+		zap.Int("failed_items", len(failed)),
 		zap.Duration("total_time", time.Since(startTime)))
 
-	return &EmbeddingResponse{
+	response := &EmbeddingResponse{
 		Embeddings:  c.extractEmbeddings(results),
 		Model:       modelName,
 		CachedItems: c.extractCachedFlags(results),
-	}, nil
+		Partial:     len(failed) > 0,
+		Errors:      failed,
+	}
+	if aiResponse != nil {
+		response.ProviderModel = aiResponse.ProviderModel
+	}
+
+	return response, nil
 }
 
-func (c *Cache) prepareBatchItems(inputs []string, modelName string) []*database.BatchItem {
+func (c *Cache) prepareBatchItems(inputs []string, modelName string, variants []string) []*database.BatchItem {
 	items := make([]*database.BatchItem, len(inputs))
+	unique := make(map[string]bool, len(inputs))
 	for i, input := range inputs {
 		items[i] = &database.BatchItem{
 			Input:  input,
-			Hash:   c.hasher.GenerateInputHash(input, modelName),
+			Hash:   c.inputHash(input, modelName, variants...),
 			Index:  i,
 			Cached: nil,
 		}
+		unique[items[i].Hash] = true
+	}
+
+	c.counters.recordBatch(len(items), len(unique))
+	if len(unique) < len(items) {
+		c.logger.Debug("Batch contains duplicate inputs",
+			zap.Int("batch_size", len(items)),
+			zap.Int("unique_items", len(unique)))
 	}
+
 	return items
 }
 
+func (c *Cache) enqueueStoreRetry(inputHash, input, modelName string, embedding []float64) {
+	if c.retry == nil {
+		return
+	}
+
+	c.retry.Enqueue(&storeretry.Item{
+		InputHash: inputHash,
+		InputText: input,
+		ModelName: modelName,
+		Embedding: embedding,
+	})
+}
+
+// enqueueMatrixStoreRetry is enqueueStoreRetry for multi-vector models;
+// replace overwrites a row the conflict policy would keep.
+func (c *Cache) enqueueMatrixStoreRetry(inputHash, input, modelName string, matrix [][]float64, replace bool) {
+	if c.retry == nil {
+		return
+	}
+
+	c.retry.Enqueue(&storeretry.Item{
+		InputHash: inputHash,
+		InputText: input,
+		ModelName: modelName,
+		Matrix:    matrix,
+		Replace:   replace,
+	})
+}
+
 func (c *Cache) getUncachedItems(batchItems []*database.BatchItem) []*database.BatchItem {
 	var uncached []*database.BatchItem
 	for _, item := range batchItems {
@@ -331,17 +805,46 @@ func (c *Cache) createBatchEmbeddings(ctx context.Context, uncachedItems []*data
 		inputs[i] = item.Input
 	}
 
-	return c.ai.CreateBatchEmbeddings(ctx, inputs)
+	return c.ai.CreateBatchEmbeddingsWithModel(ctx, inputs, modelName)
 }
 
-func (c *Cache) storeBatchEmbeddings(ctx context.Context, uncachedItems []*database.BatchItem, aiResponse *openai.EmbeddingResponse, modelName string) error {
+// withCorrupt adds the hashes of rows that could not be parsed to replace,
+// so their fresh vectors overwrite them whatever the conflict policy.
+func withCorrupt(items []*database.BatchItem, replace map[string]bool) map[string]bool {
+	for _, item := range items {
+		if !item.Corrupt {
+			continue
+		}
+		if replace == nil {
+			replace = make(map[string]bool)
+		}
+		replace[item.Hash] = true
+	}
+	return replace
+}
+
+// storeBatchEmbeddings stores fresh vectors; hashes in replace were stale
+// hits and are overwritten regardless of the conflict policy.
+func (c *Cache) storeBatchEmbeddings(ctx context.Context, uncachedItems []*database.BatchItem, aiResponse *openai.EmbeddingResponse, modelName string, replace map[string]bool) error {
+	if !c.ai.Cacheable(aiResponse) {
+		c.logger.Info("Serving fallback batch embeddings without caching",
+			zap.String("provider", aiResponse.Provider),
+			zap.Int("batch_size", len(uncachedItems)))
+		return nil
+	}
+
 	for i, item := range uncachedItems {
-		if i < len(aiResponse.Embeddings) {
-			err := c.db.StoreEmbedding(ctx, item.Hash, item.Input, modelName, aiResponse.Embeddings[i])
+		if i < len(aiResponse.Embeddings) && aiResponse.Embeddings[i] != nil {
+			store := c.db.StoreEmbedding
+			if replace[item.Hash] {
+				store = c.db.ReplaceEmbedding
+			}
+			err := store(ctx, item.Hash, item.Input, modelName, aiResponse.Embeddings[i])
 			if err != nil {
 				c.logger.Error("Failed to store batch embedding",
 					zap.String("input_hash", item.Hash[:16]+"..."),
 					zap.Error(err))
+				c.enqueueStoreRetry(item.Hash, item.Input, modelName, aiResponse.Embeddings[i])
 			}
 		}
 	}
@@ -361,6 +864,10 @@ func (c *Cache) assembleBatchResults(batchItems []*database.BatchItem, uncachedI
 		}
 	}
 
+	if aiResponse == nil {
+		return results
+	}
+
 	for i, item := range uncachedItems {
 		if i < len(aiResponse.Embeddings) {
 			results[item.Index] = &BatchResult{
@@ -394,6 +901,11 @@ func (c *Cache) extractCachedFlags(results []*BatchResult) []bool {
 	return flags
 }
 
+// MaxInputChars returns the per-input character limit for a model.
+func (c *Cache) MaxInputChars(model string) int {
+	return c.ai.ModelConfig(model).MaxInputChars
+}
+
 func (c *Cache) ValidateRequest(req *EmbeddingRequest) error {
 	if req.Input == nil {
 		return fmt.Errorf("input is required")
@@ -408,24 +920,58 @@ func (c *Cache) ValidateRequest(req *EmbeddingRequest) error {
 		return fmt.Errorf("input cannot be empty")
 	}
 
-	isBatch := c.isBatchInput(req.Input)
-	if isBatch {
-		if len(inputs) > 1000 {
-			return fmt.Errorf("batch input too large (max 1000 items)")
+	if !c.ai.IsModelAllowed(req.Model) {
+		return fmt.Errorf("model %q is not supported (configured model: %s)", req.Model, c.ai.GetModel())
+	}
+
+	if req.Dimensions != 0 {
+		if err := c.ai.ValidateDimensions(req.Model, req.Dimensions); err != nil {
+			return err
+		}
+	}
+
+	if req.MaxAge < 0 {
+		return fmt.Errorf("invalid max_age: %d (must be seconds, 0 or more)", req.MaxAge)
+	}
+
+	if len(req.Formats) > 0 {
+		if err := validateFormats(req.Formats); err != nil {
+			return err
+		}
+		if c.ai.ModelConfig(req.Model).MultiVector {
+			return fmt.Errorf("formats are not supported for multi-vector models")
 		}
+	}
+
+	maxInputChars := c.ai.ModelConfig(req.Model).MaxInputChars
+
+	isBatch := c.isBatchInput(req.Input)
+	if isBatch && len(inputs) > MaxBatchSize {
+		return fmt.Errorf("batch input too large (max %d items)", MaxBatchSize)
+	}
+
+	// With the truncate policy, oversized inputs are cut in prepareInputs.
+	if c.RejectsOverLimit() {
 		for i, input := range inputs {
-			if len(input) > 10000 {
-				return fmt.Errorf("batch input item at index %d too long (max 10000 characters)", i)
+			if len(input) <= maxInputChars {
+				continue
 			}
+			if isBatch {
+				return fmt.Errorf("batch input item at index %d too long (max %d characters)", i, maxInputChars)
+			}
+			return fmt.Errorf("input text too long (max %d characters)", maxInputChars)
 		}
-	} else {
-		if len(inputs[0]) > 10000 {
-			return fmt.Errorf("input text too long (max 10000 characters)")
+	}
+
+	modelName := c.resolveModel(req.Model)
+	if !isBatch || !c.allowsEmptyItems(modelName) {
+		if err := checkPreparedInputs(c.prepareInputs(inputs, modelName)); err != nil {
+			return err
 		}
 	}
 
 	if req.Model != "" && req.Model != c.ai.GetModel() {
-		c.logger.Warn("Using different model than default",
+		c.logger.Debug("Using different model than default",
 			zap.String("requested_model", req.Model),
 			zap.String("default_model", c.ai.GetModel()))
 	}
@@ -433,52 +979,466 @@ func (c *Cache) ValidateRequest(req *EmbeddingRequest) error {
 	return nil
 }
 
-func (c *Cache) GetHashMetadata(inputText, modelName string) map[string]interface{} {
-	return c.hasher.GetHashMetadata(inputText, modelName)
+func (c *Cache) ValidateRefreshRequest(req *RefreshRequest) error {
+	count := len(req.Hashes)
+
+	if req.Input != nil {
+		inputs, err := c.normalizeInput(req.Input)
+		if err != nil {
+			return err
+		}
+
+		for i, input := range inputs {
+			if input == "" {
+				return fmt.Errorf("input at index %d cannot be empty", i)
+			}
+		}
+		count += len(inputs)
+	}
+
+	for _, inputHash := range req.Hashes {
+		if !c.hasher.ValidateHash(inputHash) {
+			return fmt.Errorf("invalid hash: %q", inputHash)
+		}
+	}
+
+	if count == 0 {
+		return fmt.Errorf("input or hashes are required")
+	}
+
+	if count > 1000 {
+		return fmt.Errorf("refresh request too large (max 1000 items)")
+	}
+
+	if !c.ai.IsModelAllowed(req.Model) {
+		return fmt.Errorf("model %q is not supported (configured model: %s)", req.Model, c.ai.GetModel())
+	}
+
+	if req.Dimensions != 0 {
+		if err := c.ai.ValidateDimensions(req.Model, req.Dimensions); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (c *Cache) Warmup(ctx context.Context, inputs []string, modelName string) error {
-	c.logger.Info("Starting cache warmup",
-		zap.Int("input_count", len(inputs)),
-		zap.String("model", modelName))
+func (c *Cache) Refresh(ctx context.Context, req *RefreshRequest) ([]*RefreshResult, error) {
+	if err := c.ValidateRefreshRequest(req); err != nil {
+		return nil, err
+	}
 
-	for i, input := range inputs {
-		select {
-		case <-ctx.Done():
-			c.logger.Info("Cache warmup interrupted",
-				zap.Int("completed", i),
-				zap.Int("total", len(inputs)))
-			return ctx.Err()
-		default:
+	// Items are embedded together when they share a model and the options
+	// that decide what GetEmbedding stores: the size and normalization.
+	type refreshGroup struct {
+		model      string
+		dimensions int
+		normalize  bool
+	}
+
+	var items []*refreshItem
+
+	if req.Input != nil {
+		inputs, err := c.normalizeInput(req.Input)
+		if err != nil {
+			return nil, err
+		}
+
+		modelName := c.resolveModel(req.Model)
+		embedReq := req.embeddingRequest()
+		variants := c.hashVariants(embedReq)
+
+		inputs = c.prepareInputs(inputs, modelName)
+
+		for _, input := range inputs {
+			items = append(items, &refreshItem{
+				input: input,
+				req:   embedReq,
+				result: &RefreshResult{
+					InputHash: c.inputHash(input, modelName, variants...),
+					Model:     modelName,
+				},
+			})
+		}
+	}
+
+	for _, inputHash := range req.Hashes {
+		items = append(items, &refreshItem{
+			result: &RefreshResult{InputHash: inputHash},
+		})
+	}
+
+	// Hash-only refreshes look in the requested (or default) model's table.
+	lookupModel := c.resolveModel(req.Model)
+
+	lookups := make(map[string][]*database.BatchItem)
+	batchItems := make([]*database.BatchItem, len(items))
+	for i, item := range items {
+		modelName := item.result.Model
+		if modelName == "" {
+			modelName = lookupModel
+		}
+		batchItems[i] = &database.BatchItem{Hash: item.result.InputHash, Index: i}
+		lookups[modelName] = append(lookups[modelName], batchItems[i])
+	}
+
+	for modelName, lookup := range lookups {
+		if _, err := c.db.GetBatchCachedEmbeddings(ctx, lookup, modelName); err != nil {
+			return nil, fmt.Errorf("failed to check cache: %w", err)
+		}
+	}
+
+	byModel := make(map[refreshGroup][]*refreshItem)
+	for i, item := range items {
+		// A corrupt row has no old vector to compare with; it is replaced
+		// like any other.
+		old := batchItems[i].Cached
+
+		if old != nil {
+			item.old = old
+			item.result.Existed = true
+			item.result.OldDimensions = storedDimensions(old)
+			if item.input == "" {
+				if old.InputText == "" {
+					item.result.Error = "input text not stored; refresh by input instead"
+					continue
+				}
+				input, matched, ok := c.MatchStoredRow(old.InputText, old.ModelName, item.result.InputHash, item.result.OldDimensions, []string{req.Tenant})
+				if !ok {
+					item.result.Error = "hash does not match a request for this tenant; refresh by input instead"
+					continue
+				}
+				item.input = input
+				item.req = matched
+				item.result.Model = old.ModelName
+			}
+		} else if item.input == "" {
+			item.result.Error = "hash not found in cache"
+			continue
+		}
+
+		key := refreshGroup{
+			model:      item.result.Model,
+			dimensions: item.req.Dimensions,
+			normalize:  c.shouldNormalize(item.req),
+		}
+		byModel[key] = append(byModel[key], item)
+	}
+
+	for key, group := range byModel {
+		modelName := key.model
+		inputs := make([]string, len(group))
+		for i, item := range group {
+			inputs[i] = item.input
 		}
 
-		req := &EmbeddingRequest{
-			Input: input,
-			Model: modelName,
+		embedCtx := openai.WithDimensions(ctx, key.dimensions)
+		if c.ai.ModelConfig(modelName).MultiVector {
+			if err := c.refreshMatrices(embedCtx, group, inputs, modelName, key.normalize); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
-		_, err := c.GetEmbedding(ctx, req)
+		aiResponse, err := c.ai.CreateBatchEmbeddingsWithModel(embedCtx, inputs, modelName)
 		if err != nil {
-			c.logger.Error("Failed to warmup embedding",
-				zap.Int("index", i),
-				zap.String("input_preview", input[:min(50, len(input))]),
+			c.logger.Error("Failed to refresh embeddings via OpenAI",
+				zap.String("model", modelName),
+				zap.Int("batch_size", len(group)),
 				zap.Error(err))
-			continue
+			return nil, fmt.Errorf("failed to create embeddings: %w", err)
 		}
 
-		if (i+1)%10 == 0 {
-			c.logger.Info("Cache warmup progress",
-				zap.Int("completed", i+1),
-				zap.Int("total", len(inputs)))
+		cacheable := c.ai.Cacheable(aiResponse)
+
+		for i, item := range group {
+			if !cacheable {
+				item.result.Error = "served by non-equivalent fallback provider, not stored"
+				continue
+			}
+
+			if i >= len(aiResponse.Embeddings) {
+				item.result.Error = "no embedding returned by provider"
+				continue
+			}
+			if aiResponse.Embeddings[i] == nil {
+				item.result.Error = "provider returned an invalid vector"
+				continue
+			}
+
+			embedding := aiResponse.Embeddings[i]
+			if key.normalize {
+				NormalizeVector(embedding)
+			}
+			item.result.NewDimensions = len(embedding)
+
+			if err := c.db.ReplaceEmbedding(ctx, item.result.InputHash, item.input, modelName, embedding); err != nil {
+				c.logger.Error("Failed to store refreshed embedding",
+					zap.String("input_hash", item.result.InputHash[:16]+"..."),
+					zap.Error(err))
+				item.result.Error = "failed to store refreshed embedding"
+				continue
+			}
+
+			if item.old != nil && len(item.old.EmbeddingVector) == len(embedding) {
+				delta := 1 - cosineSimilarity(item.old.EmbeddingVector, embedding)
+				item.result.CosineDelta = &delta
+			}
 		}
 	}
 
-	c.logger.Info("Cache warmup completed",
-		zap.Int("total_processed", len(inputs)))
+	results := make([]*RefreshResult, len(items))
+	for i, item := range items {
+		results[i] = item.result
+	}
+
+	c.logger.Info("Refreshed cached embeddings",
+		zap.Int("requested", len(items)),
+		zap.Int("models", len(byModel)))
+
+	return results, nil
+}
+
+type refreshItem struct {
+	input  string
+	req    *EmbeddingRequest // the options behind the item's key
+	result *RefreshResult
+	old    *database.CachedEmbedding
+}
+
+// refreshMatrices is Refresh for multi-vector models, stored the way
+// processMultiVectorRequest stores them.
+func (c *Cache) refreshMatrices(ctx context.Context, group []*refreshItem, inputs []string, modelName string, normalize bool) error {
+	aiResponse, err := c.ai.CreateMultiVectorEmbeddings(ctx, inputs, modelName)
+	if err != nil {
+		c.logger.Error("Failed to refresh multi-vector embeddings via OpenAI",
+			zap.String("model", modelName),
+			zap.Int("batch_size", len(group)),
+			zap.Error(err))
+		return fmt.Errorf("failed to create embeddings: %w", err)
+	}
+
+	for i, item := range group {
+		matrix := aiResponse.Embeddings[i]
+		if normalize {
+			for _, vector := range matrix {
+				NormalizeVector(vector)
+			}
+		}
+		if len(matrix) > 0 {
+			item.result.NewDimensions = len(matrix[0])
+		}
+
+		if err := c.db.ReplaceEmbeddingMatrix(ctx, item.result.InputHash, item.input, modelName, matrix); err != nil {
+			c.logger.Error("Failed to store refreshed embedding matrix",
+				zap.String("input_hash", item.result.InputHash[:16]+"..."),
+				zap.Error(err))
+			item.result.Error = "failed to store refreshed embedding"
+		}
+	}
 
 	return nil
 }
 
+// storedDimensions is the vector size of a cached row, single or
+// multi-vector.
+func storedDimensions(cached *database.CachedEmbedding) int {
+	if len(cached.EmbeddingMatrix) > 0 {
+		return len(cached.EmbeddingMatrix[0])
+	}
+	return len(cached.EmbeddingVector)
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func (c *Cache) EstimateTokens(req *EmbeddingRequest) int {
+	modelName := c.resolveModel(req.Model)
+
+	inputs, err := c.normalizeInput(req.Input)
+	if err != nil {
+		return 0
+	}
+	inputs = c.prepareInputs(inputs, modelName)
+
+	total := 0
+	for _, input := range inputs {
+		total += tokenizer.CountTokens(input, modelName)
+	}
+
+	return total
+}
+
+// savedCostFields estimates what the provider would have charged for inputs
+// served from the cache. Tokens are only counted when the model has a price.
+func (c *Cache) savedCostFields(modelName string, inputs []string) []zap.Field {
+	if len(inputs) == 0 || !c.ai.HasPrice(modelName) {
+		return nil
+	}
+
+	tokens := 0
+	for _, input := range inputs {
+		tokens += tokenizer.CountTokens(input, modelName)
+	}
+
+	cost, _ := c.ai.EstimateCost(modelName, tokens)
+	return []zap.Field{
+		zap.Int("saved_tokens", tokens),
+		zap.Float64("saved_cost_usd", cost),
+	}
+}
+
+func (c *Cache) InputHashes(req *EmbeddingRequest) ([]string, string) {
+	modelName := c.resolveModel(req.Model)
+
+	inputs, err := c.normalizeInput(req.Input)
+	if err != nil {
+		return nil, modelName
+	}
+	inputs = c.prepareInputs(inputs, modelName)
+
+	variants := c.hashVariants(req)
+	hashes := make([]string, len(inputs))
+	for i, input := range inputs {
+		hashes[i] = c.inputHash(input, modelName, variants...)
+	}
+
+	return hashes, modelName
+}
+
+// EchoInputs describes each input as it was hashed and embedded, with the
+// text cut to echoTextLimit characters.
+func (c *Cache) EchoInputs(req *EmbeddingRequest) []EchoItem {
+	modelName := c.resolveModel(req.Model)
+
+	inputs, err := c.normalizeInput(req.Input)
+	if err != nil {
+		return nil
+	}
+	inputs = c.prepareInputs(inputs, modelName)
+
+	variants := c.hashVariants(req)
+	items := make([]EchoItem, len(inputs))
+	for i, input := range inputs {
+		items[i] = EchoItem{
+			Index:     i,
+			InputHash: c.inputHash(input, modelName, variants...),
+			Text:      input,
+		}
+
+		if runes := []rune(input); len(runes) > echoTextLimit {
+			items[i].Text = string(runes[:echoTextLimit])
+			items[i].Truncated = true
+		}
+	}
+
+	return items
+}
+
+func (c *Cache) GetHashMetadata(inputText, modelName string) map[string]interface{} {
+	modelName = c.resolveModel(modelName)
+
+	prepared, truncated := c.prepareInput(inputText, modelName)
+	metadata := c.hasher.GetHashMetadata(prepared, modelName)
+	metadata["original_length"] = len(inputText)
+	metadata["lowercase"] = c.ai.ModelConfig(modelName).Lowercase
+	metadata["truncated"] = truncated
+	metadata["max_input_chars"] = c.ai.ModelConfig(modelName).MaxInputChars
+
+	return metadata
+}
+
+func (c *Cache) shouldNormalize(req *EmbeddingRequest) bool {
+	if req.Normalize != nil {
+		return *req.Normalize
+	}
+	return c.config.Normalize
+}
+
+const NormalizedHashVariant = "normalized"
+
+// resolveModel returns the model a request runs against: the requested one,
+// or the configured default when none was given.
+func (c *Cache) resolveModel(model string) string {
+	if model == "" {
+		return c.ai.GetModel()
+	}
+	return model
+}
+
+// inputHash is the only way the cache derives keys. It resolves the model
+// itself, so a request that omits the model and one that names the default
+// always share entries, even if a caller forgot to resolve it.
+func (c *Cache) inputHash(input, modelName string, variants ...string) string {
+	return c.hasher.GenerateInputHash(input, c.resolveModel(modelName), variants...)
+}
+
+// hashVariants lists the request options that change the cache key.
+// Tenants get their own key space so their caches never overlap. A
+// dimensions override equal to the default size shares the default key.
+func (c *Cache) hashVariants(req *EmbeddingRequest) []string {
+	var variants []string
+	if c.shouldNormalize(req) {
+		variants = append(variants, NormalizedHashVariant)
+	}
+	if req.Tenant != "" {
+		variants = append(variants, "tenant:"+req.Tenant)
+	}
+	if req.Dimensions > 0 && req.Dimensions != c.defaultDimensions(c.resolveModel(req.Model)) {
+		variants = append(variants, "dimensions:"+strconv.Itoa(req.Dimensions))
+	}
+	return variants
+}
+
+func NormalizeVector(vector []float64) {
+	var sum float64
+	for _, v := range vector {
+		sum += v * v
+	}
+
+	if sum == 0 {
+		return
+	}
+
+	norm := math.Sqrt(sum)
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
+
+func checkPreparedInputs(inputs []string) error {
+	for i, input := range inputs {
+		if input == "" {
+			return fmt.Errorf("input at index %d cannot be empty after normalization", i)
+		}
+	}
+	return nil
+}
+
+// prepareInputs applies the model's transforms and the hasher's
+// normalization, producing exactly the text that is hashed, embedded and
+// stored.
+func (c *Cache) prepareInputs(inputs []string, modelName string) []string {
+	result := make([]string, len(inputs))
+	for i, input := range inputs {
+		result[i], _ = c.prepareInput(input, modelName)
+	}
+
+	return result
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -0,0 +1,52 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
+)
+
+func TestCancelledRequestReachesProvider(t *testing.T) {
+	started := make(chan struct{})
+	embedder := newFakeEmbedder(t, func(ctx context.Context, inputs []string) (*openai.EmbeddingResponse, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	store := newFakeStore(nil)
+	c := cache.NewWithDeps(store, embedder, &config.CacheConfig{}, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetEmbedding(ctx, batchRequest(inputs(3)...))
+		done <- err
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider was never called")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetEmbedding did not return after the request was cancelled")
+	}
+
+	if len(store.stored) > 0 {
+		t.Errorf("stored %d vectors for a cancelled request", len(store.stored))
+	}
+}
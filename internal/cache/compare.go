@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+type CompareRequest struct {
+	A         string `json:"a" binding:"required"`
+	B         string `json:"b" binding:"required"`
+	Model     string `json:"model,omitempty"`
+	Normalize *bool  `json:"normalize,omitempty"`
+	User      string `json:"user,omitempty"`
+	Tenant    string `json:"-"`
+}
+
+type CompareResult struct {
+	Model            string  `json:"model"`
+	CosineSimilarity float64 `json:"cosine_similarity"`
+	CosineDistance   float64 `json:"cosine_distance"`
+	Euclidean        float64 `json:"euclidean_distance"`
+	Dimensions       int     `json:"dimensions"`
+	CachedA          bool    `json:"cached_a"`
+	CachedB          bool    `json:"cached_b"`
+	TokenUsage       struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func (r *CompareRequest) embeddingRequest() *EmbeddingRequest {
+	return &EmbeddingRequest{
+		Input:     []string{r.A, r.B},
+		Model:     r.Model,
+		Normalize: r.Normalize,
+		User:      r.User,
+		Tenant:    r.Tenant,
+	}
+}
+
+func (c *Cache) ValidateCompareRequest(req *CompareRequest) error {
+	return c.ValidateRequest(req.embeddingRequest())
+}
+
+// Compare embeds both inputs, through the cache like any batch request, and
+// returns the distance between them.
+func (c *Cache) Compare(ctx context.Context, req *CompareRequest) (*CompareResult, error) {
+	response, err := c.GetEmbedding(ctx, req.embeddingRequest())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Embeddings) != 2 || response.Embeddings[0] == nil || response.Embeddings[1] == nil {
+		return nil, fmt.Errorf("compare requires single-vector embeddings for both inputs")
+	}
+
+	a, b := response.Embeddings[0], response.Embeddings[1]
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("embedding dimensions differ: %d and %d", len(a), len(b))
+	}
+
+	similarity := cosineSimilarity(a, b)
+	result := &CompareResult{
+		Model:            response.Model,
+		CosineSimilarity: similarity,
+		CosineDistance:   1 - similarity,
+		Euclidean:        euclideanDistance(a, b),
+		Dimensions:       len(a),
+		TokenUsage:       response.TokenUsage,
+	}
+
+	if len(response.CachedItems) == 2 {
+		result.CachedA = response.CachedItems[0]
+		result.CachedB = response.CachedItems[1]
+	}
+
+	return result, nil
+}
+
+func euclideanDistance(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type runtimeCounters struct {
+	requests      atomic.Int64
+	items         atomic.Int64
+	hits          atomic.Int64
+	misses        atomic.Int64
+	providerCalls atomic.Int64
+	batchItems    atomic.Int64
+	uniqueItems   atomic.Int64
+	staleHits     atomic.Int64
+
+	sinceMutex sync.RWMutex
+	since      time.Time
+}
+
+func newRuntimeCounters() *runtimeCounters {
+	return &runtimeCounters{since: time.Now()}
+}
+
+func (rc *runtimeCounters) record(hits, misses int) {
+	rc.requests.Add(1)
+	rc.items.Add(int64(hits + misses))
+	rc.hits.Add(int64(hits))
+	rc.misses.Add(int64(misses))
+}
+
+func (rc *runtimeCounters) recordBatch(items, unique int) {
+	rc.batchItems.Add(int64(items))
+	rc.uniqueItems.Add(int64(unique))
+}
+
+func (rc *runtimeCounters) reset() {
+	rc.sinceMutex.Lock()
+	defer rc.sinceMutex.Unlock()
+
+	rc.requests.Store(0)
+	rc.items.Store(0)
+	rc.hits.Store(0)
+	rc.misses.Store(0)
+	rc.providerCalls.Store(0)
+	rc.batchItems.Store(0)
+	rc.uniqueItems.Store(0)
+	rc.staleHits.Store(0)
+	rc.since = time.Now()
+}
+
+func (rc *runtimeCounters) snapshot() map[string]interface{} {
+	rc.sinceMutex.RLock()
+	defer rc.sinceMutex.RUnlock()
+
+	hits := rc.hits.Load()
+	items := rc.items.Load()
+
+	hitRate := 0.0
+	if items > 0 {
+		hitRate = float64(hits) / float64(items)
+	}
+
+	batchItems := rc.batchItems.Load()
+	uniqueItems := rc.uniqueItems.Load()
+
+	duplicateRate := 0.0
+	if batchItems > 0 {
+		duplicateRate = float64(batchItems-uniqueItems) / float64(batchItems)
+	}
+
+	return map[string]interface{}{
+		"requests":       rc.requests.Load(),
+		"items":          items,
+		"hits":           hits,
+		"misses":         rc.misses.Load(),
+		"hit_rate":       hitRate,
+		"provider_calls": rc.providerCalls.Load(),
+		"batch_items":    batchItems,
+		"unique_items":   uniqueItems,
+		"stale_hits":     rc.staleHits.Load(),
+		"duplicate_rate": duplicateRate,
+		"since":          rc.since,
+	}
+}
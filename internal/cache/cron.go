@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a standard five-field cron expression (minute, hour,
+// day of month, month, day of week) evaluated in UTC.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week, 0 and 7 are Sunday
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+
+	masks := make([]uint64, len(parts))
+	for i, part := range parts {
+		mask, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		masks[i] = mask
+	}
+
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    masks[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var mask uint64
+
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := bounds.min, bounds.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			low, err = strconv.Atoi(lowPart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", lowPart)
+			}
+
+			high = low
+			if isRange {
+				high, err = strconv.Atoi(highPart)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q", highPart)
+				}
+			} else if hasStep {
+				high = bounds.max
+			}
+		}
+
+		if low < bounds.min || high > bounds.max || low > high {
+			return 0, fmt.Errorf("value out of range in %q (%d-%d)", item, bounds.min, bounds.max)
+		}
+
+		for v := low; v <= high; v += step {
+			mask |= 1 << v
+		}
+	}
+
+	return mask, nil
+}
+
+// cronHorizon bounds the search in next. Leap days can be eight years
+// apart (2096 to 2104), so every expression that can fire does so within
+// it.
+const cronHorizon = 8
+
+// next returns the first matching minute after t, or the zero time if the
+// expression never matches (for example "0 0 30 2 *"). Fields that do not
+// match skip ahead to the start of the next month, day or hour.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	for limit := t.AddDate(cronHorizon, 0, 1); t.Before(limit); {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<t.Day()) != 0
+	dowMatch := s.dow&(1<<int(t.Weekday())) != 0
+
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
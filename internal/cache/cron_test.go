@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2026, 10, 17, 12, 34, 56, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 10, 17, 12, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1", time.Date(2026, 10, 19, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if got := schedule.next(from); !got.Equal(tt.want) {
+				t.Errorf("next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextLeapDayAcrossSkippedLeapYear(t *testing.T) {
+	schedule, err := parseCron("0 0 29 2 *")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	// 2100 is not a leap year, so the next Feb 29 after 2096 is in 2104.
+	from := time.Date(2096, 3, 1, 0, 0, 0, 0, time.UTC)
+	want := time.Date(2104, 2, 29, 0, 0, 0, 0, time.UTC)
+	if got := schedule.next(from); !got.Equal(want) {
+		t.Errorf("next = %v, want %v", got, want)
+	}
+}
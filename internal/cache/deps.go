@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/hash"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
+)
+
+// Store is the part of *database.Database the cache uses.
+type Store interface {
+	GetCachedEmbedding(ctx context.Context, inputHash, modelName string) (*database.CachedEmbedding, error)
+	GetBatchCachedEmbeddings(ctx context.Context, batchItems []*database.BatchItem, modelName string) ([]*database.BatchItem, error)
+	StoreEmbedding(ctx context.Context, inputHash, inputText, modelName string, embeddingVector []float64) error
+	ReplaceEmbedding(ctx context.Context, inputHash, inputText, modelName string, embeddingVector []float64) error
+	StoreEmbeddingMatrix(ctx context.Context, inputHash, inputText, modelName string, matrix [][]float64) error
+	ReplaceEmbeddingMatrix(ctx context.Context, inputHash, inputText, modelName string, matrix [][]float64) error
+	DeleteByUsedBefore(ctx context.Context, t time.Time) (int64, error)
+	DeleteByCreatedBefore(ctx context.Context, t time.Time) (int64, error)
+	RecentlyUsedInputs(ctx context.Context, modelName string, limit int) ([]database.UsedInput, error)
+	GetCacheStats(ctx context.Context) (map[string]int64, error)
+	PoolStats() database.PoolStats
+	Vacuum(ctx context.Context, reindex bool) ([]database.VacuumResult, error)
+}
+
+// Embedder is the part of *openai.Client the cache uses.
+type Embedder interface {
+	CreateEmbeddingWithModel(ctx context.Context, input, model string) (*openai.EmbeddingResponse, error)
+	CreateBatchEmbeddingsWithModel(ctx context.Context, inputs []string, model string) (*openai.EmbeddingResponse, error)
+	CreateMultiVectorEmbeddings(ctx context.Context, inputs []string, model string) (*openai.MultiVectorResponse, error)
+	Cacheable(response *openai.EmbeddingResponse) bool
+	GetModel() string
+	ModelConfig(model string) config.ModelConfig
+	IsModelAllowed(model string) bool
+	MaxDimensions(model string) int
+	EffectiveDimensions(model string, override int) int
+	ValidateDimensions(model string, dimensions int) error
+	HasPrice(model string) bool
+	EstimateCost(model string, tokens int) (cost float64, ok bool)
+	ProviderHealth() openai.ProviderHealth
+	Models() []openai.ModelInfo
+}
+
+var (
+	_ Store    = (*database.Database)(nil)
+	_ Embedder = (*openai.Client)(nil)
+)
+
+// NewWithDeps builds a Cache around any Store and Embedder, with the default
+// hash settings and no usage tracker or store retry queue. It lets unit tests
+// exercise the caching logic with fakes instead of Postgres and a provider.
+func NewWithDeps(store Store, embedder Embedder, cfg *config.CacheConfig, logger *zap.Logger) *Cache {
+	if cfg == nil {
+		cfg = &config.CacheConfig{}
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return New(store, embedder, hash.New(&config.HashConfig{}, logger), nil, nil, cfg, logger)
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+)
+
+// wrongSize reports whether a cached vector differs in size from what the
+// provider would return for the request now. The default key does not
+// carry a size, so rows cached before a model's dimensions setting changed
+// would otherwise keep being served at the old size.
+func (c *Cache) wrongSize(cached *database.CachedEmbedding, req *EmbeddingRequest, modelName string) bool {
+	want := c.ai.EffectiveDimensions(modelName, req.Dimensions)
+	return cached != nil && want > 0 && len(cached.EmbeddingVector) > 0 && len(cached.EmbeddingVector) != want
+}
+
+// dropWrongSize turns hits of the wrong size into misses and adds their
+// hashes to replace, so the fresh vectors overwrite the old rows.
+func (c *Cache) dropWrongSize(items []*database.BatchItem, req *EmbeddingRequest, modelName string, replace map[string]bool) map[string]bool {
+	dropped := 0
+	for _, item := range items {
+		if !c.wrongSize(item.Cached, req, modelName) {
+			continue
+		}
+		item.Cached = nil
+		if replace == nil {
+			replace = make(map[string]bool)
+		}
+		replace[item.Hash] = true
+		dropped++
+	}
+
+	if dropped > 0 {
+		c.logger.Info("Treating cache hits of the wrong size as misses",
+			zap.Int("wrong_size", dropped),
+			zap.Int("dimensions", c.ai.EffectiveDimensions(modelName, req.Dimensions)))
+	}
+
+	return replace
+}
+
+// defaultDimensions returns the size a request without an override gets:
+// the model's configured dimensions, else its native size when known.
+func (c *Cache) defaultDimensions(modelName string) int {
+	if dimensions := c.ai.ModelConfig(modelName).Dimensions; dimensions > 0 {
+		return dimensions
+	}
+	return c.ai.MaxDimensions(modelName)
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// allowsEmptyItems reports whether empty batch items are answered per item
+// rather than failing the batch. Multi-vector models always reject them.
+func (c *Cache) allowsEmptyItems(modelName string) bool {
+	policy := c.config.EmptyItemPolicy
+	return (policy == "skip" || policy == "zero") && !c.ai.ModelConfig(modelName).MultiVector
+}
+
+// processBatchWithEmptyItems embeds the non-empty items of a batch and puts
+// the empty ones back in place according to cache.empty_item_policy: "skip"
+// returns null for them, "zero" a zero vector. Either way their indexes are
+// listed in EmptyItems and they never reach the provider or the cache.
+func (c *Cache) processBatchWithEmptyItems(ctx context.Context, req *EmbeddingRequest, inputs []string, modelName string) (*EmbeddingResponse, error) {
+	var empty []int
+	nonEmpty := make([]string, 0, len(inputs))
+	positions := make([]int, 0, len(inputs))
+	for i, input := range inputs {
+		if input == "" {
+			empty = append(empty, i)
+			continue
+		}
+		nonEmpty = append(nonEmpty, input)
+		positions = append(positions, i)
+	}
+
+	if len(empty) == 0 {
+		return c.processPreparedBatch(ctx, req, inputs, modelName)
+	}
+
+	response := &EmbeddingResponse{Model: modelName}
+	if len(nonEmpty) > 0 {
+		var err error
+		response, err = c.processPreparedBatch(ctx, req, nonEmpty, modelName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	embeddings := make([][]float64, len(inputs))
+	cachedItems := make([]bool, len(inputs))
+	for j, index := range positions {
+		if j < len(response.Embeddings) {
+			embeddings[index] = response.Embeddings[j]
+		}
+		if j < len(response.CachedItems) {
+			cachedItems[index] = response.CachedItems[j]
+		}
+	}
+	for i := range response.Errors {
+		response.Errors[i].Index = positions[response.Errors[i].Index]
+	}
+
+	if c.config.EmptyItemPolicy == "zero" {
+		dimensions, err := c.zeroVectorDimensions(req, modelName, response.Embeddings)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range empty {
+			embeddings[index] = make([]float64, dimensions)
+		}
+	}
+
+	c.logger.Debug("Batch contains empty inputs",
+		zap.Int("empty_items", len(empty)),
+		zap.String("policy", c.config.EmptyItemPolicy))
+
+	response.Embeddings = embeddings
+	response.CachedItems = cachedItems
+	response.EmptyItems = empty
+	return response, nil
+}
+
+func (c *Cache) zeroVectorDimensions(req *EmbeddingRequest, modelName string, embeddings [][]float64) (int, error) {
+	for _, embedding := range embeddings {
+		if len(embedding) > 0 {
+			return len(embedding), nil
+		}
+	}
+
+	if dimensions := c.ai.EffectiveDimensions(modelName, req.Dimensions); dimensions > 0 {
+		return dimensions, nil
+	}
+	if dimensions := c.ai.MaxDimensions(modelName); dimensions > 0 {
+		return dimensions, nil
+	}
+
+	return 0, fmt.Errorf("cannot size zero vectors for model %s: no embeddings in batch and no configured dimensions", modelName)
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	FormatFloat  = "float"
+	FormatBase64 = "base64"
+)
+
+func validateFormats(formats []string) error {
+	seen := make(map[string]bool, len(formats))
+	for _, format := range formats {
+		if format != FormatFloat && format != FormatBase64 {
+			return fmt.Errorf("invalid format %q (expected float or base64)", format)
+		}
+		if seen[format] {
+			return fmt.Errorf("format %q listed twice", format)
+		}
+		seen[format] = true
+	}
+	return nil
+}
+
+// ApplyFormats replaces the float arrays of a response with one object per
+// vector, keyed by each requested format, in embedding_formats. Missing
+// batch items stay null. Without formats the response is left unchanged.
+func ApplyFormats(response *EmbeddingResponse, formats []string) {
+	if len(formats) == 0 {
+		return
+	}
+
+	if response.Embeddings == nil {
+		response.EmbeddingFormats = formatVector(response.Embedding, formats)
+		response.Embedding = nil
+		return
+	}
+
+	items := make([]map[string]interface{}, len(response.Embeddings))
+	for i, embedding := range response.Embeddings {
+		if embedding != nil {
+			items[i] = formatVector(embedding, formats)
+		}
+	}
+	response.EmbeddingFormats = items
+	response.Embeddings = nil
+}
+
+func formatVector(vector []float64, formats []string) map[string]interface{} {
+	formatted := make(map[string]interface{}, len(formats))
+	for _, format := range formats {
+		switch format {
+		case FormatFloat:
+			formatted[format] = vector
+		case FormatBase64:
+			formatted[format] = encodeBase64Vector(vector)
+		}
+	}
+	return formatted
+}
+
+// encodeBase64Vector matches the provider's encoding_format=base64: the
+// vector as little-endian float32 values, base64 encoded.
+func encodeBase64Vector(vector []float64) string {
+	buf := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(value)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
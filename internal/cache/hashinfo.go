@@ -0,0 +1,63 @@
+package cache
+
+import "fmt"
+
+type HashResult struct {
+	Model    string     `json:"model"`
+	Variants []string   `json:"variants,omitempty"`
+	Items    []HashItem `json:"items"`
+}
+
+type HashItem struct {
+	Index      int                    `json:"index"`
+	Normalized string                 `json:"normalized"`
+	InputHash  string                 `json:"input_hash"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// HashInputs reports the normalized text and cache key for each input of a
+// request, exactly as GetEmbedding would compute them, without reading the
+// cache or calling the provider.
+func (c *Cache) HashInputs(req *EmbeddingRequest) (*HashResult, error) {
+	if req.Input == nil {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	inputs, err := c.normalizeInput(req.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("input cannot be empty")
+	}
+
+	if len(inputs) > MaxBatchSize {
+		return nil, fmt.Errorf("batch input too large (max %d items)", MaxBatchSize)
+	}
+
+	if !c.ai.IsModelAllowed(req.Model) {
+		return nil, fmt.Errorf("model %q is not supported (configured model: %s)", req.Model, c.ai.GetModel())
+	}
+
+	modelName := c.resolveModel(req.Model)
+
+	variants := c.hashVariants(req)
+	prepared := c.prepareInputs(inputs, modelName)
+
+	result := &HashResult{
+		Model:    modelName,
+		Variants: variants,
+		Items:    make([]HashItem, len(inputs)),
+	}
+	for i, input := range prepared {
+		result.Items[i] = HashItem{
+			Index:      i,
+			Normalized: input,
+			InputHash:  c.inputHash(input, modelName, variants...),
+			Metadata:   c.GetHashMetadata(inputs[i], modelName),
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
+)
+
+func newKeyTestCache(t *testing.T) *Cache {
+	t.Helper()
+
+	client, err := openai.New(&config.OpenAIConfig{
+		Mock:            true,
+		Model:           "text-embedding-3-small",
+		ChunkSize:       1000,
+		ResponseOrder:   "index",
+		HealthWindowSec: 60,
+		TimeoutSec:      30,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create mock client: %v", err)
+	}
+	return NewWithDeps(nil, client, &config.CacheConfig{}, zap.NewNop())
+}
+
+// Golden cache keys. A change here means every existing cache entry for
+// that kind of request becomes a miss, so it must be deliberate.
+func TestInputHashGoldenKeys(t *testing.T) {
+	c := newKeyTestCache(t)
+
+	yes := true
+	tests := []struct {
+		name string
+		req  EmbeddingRequest
+		want string
+	}{
+		{
+			name: "default model",
+			req:  EmbeddingRequest{},
+			want: "522172b09fea15883fadaf2c37c5baa1bbbd0ef1ef33df36b4ef4a391f711dee",
+		},
+		{
+			name: "explicit default model",
+			req:  EmbeddingRequest{Model: "text-embedding-3-small"},
+			want: "522172b09fea15883fadaf2c37c5baa1bbbd0ef1ef33df36b4ef4a391f711dee",
+		},
+		{
+			name: "other model",
+			req:  EmbeddingRequest{Model: "text-embedding-3-large"},
+			want: "e784f87d765ed357c9f7a439fa9eed7ebd426ebf8353dbfb496a6e711611583d",
+		},
+		{
+			name: "normalized",
+			req:  EmbeddingRequest{Normalize: &yes},
+			want: "7cd0ee4bee55ab4236d0cd6b4b9b9c502c14759691655930fefbcc71b5fcf908",
+		},
+		{
+			name: "tenant",
+			req:  EmbeddingRequest{Tenant: "acme"},
+			want: "d8fb3327a652cc8de880a784b03cc97ab92404970a98f6ab43ec3c70f5311b80",
+		},
+		{
+			name: "dimensions",
+			req:  EmbeddingRequest{Dimensions: 512},
+			want: "e57645aa88406109eea0d32225356f37538bb8c1cdc7aa74fc568ab502b9534b",
+		},
+		{
+			name: "native dimensions",
+			req:  EmbeddingRequest{Dimensions: 1536},
+			want: "522172b09fea15883fadaf2c37c5baa1bbbd0ef1ef33df36b4ef4a391f711dee",
+		},
+		{
+			name: "native dimensions other model",
+			req:  EmbeddingRequest{Model: "text-embedding-3-large", Dimensions: 3072},
+			want: "e784f87d765ed357c9f7a439fa9eed7ebd426ebf8353dbfb496a6e711611583d",
+		},
+		{
+			name: "normalized tenant dimensions",
+			req:  EmbeddingRequest{Normalize: &yes, Tenant: "acme", Dimensions: 512},
+			want: "e5c7e10690c332d5f114b41ff26df7162ea9eb7cd37a4b8276de453c622fd344",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.inputHash("hello world", tt.req.Model, c.hashVariants(&tt.req)...)
+			if got != tt.want {
+				t.Errorf("key = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputHashNeverUsesEmptyModel(t *testing.T) {
+	c := newKeyTestCache(t)
+
+	implicit := c.inputHash("hello world", "")
+	explicit := c.inputHash("hello world", "text-embedding-3-small")
+	if implicit != explicit {
+		t.Errorf("implicit default model key %s differs from explicit %s", implicit, explicit)
+	}
+	if empty := c.hasher.GenerateInputHash("hello world", ""); implicit == empty {
+		t.Error("key was computed with an empty model name")
+	}
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// flightGroup coalesces concurrent provider calls for the same input hash.
+// Unlike singleflight.Group it lets a batch claim many keys at once and
+// resolve them from a single provider call, so single and batch requests
+// for the same uncached input share one embedding.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done      chan struct{}
+	embedding []float64
+	matrix    [][]float64 // multi-vector models
+	err       error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		calls: make(map[string]*flightCall),
+	}
+}
+
+func (g *flightGroup) claim(key string) (*flightCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if call, ok := g.calls[key]; ok {
+		return call, false
+	}
+
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	return call, true
+}
+
+func (g *flightGroup) finish(key string, call *flightCall, embedding []float64, err error) {
+	call.embedding = embedding
+	g.release(key, call, err)
+}
+
+func (g *flightGroup) finishMatrix(key string, call *flightCall, matrix [][]float64, err error) {
+	call.matrix = matrix
+	g.release(key, call, err)
+}
+
+func (g *flightGroup) release(key string, call *flightCall, err error) {
+	g.mu.Lock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+}
+
+// abandoned reports whether a waiter's error only means that the leading
+// request was cancelled by its client while the waiter is still live, in
+// which case the waiter should redo the work itself.
+func abandoned(ctx context.Context, err error) bool {
+	return errors.Is(err, context.Canceled) && ctx.Err() == nil
+}
+
+func (call *flightCall) wait(ctx context.Context) ([]float64, error) {
+	select {
+	case <-call.done:
+		return call.embedding, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (call *flightCall) waitMatrix(ctx context.Context) ([][]float64, error) {
+	select {
+	case <-call.done:
+		return call.matrix, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
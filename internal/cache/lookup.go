@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+)
+
+type LookupResult struct {
+	Response  *EmbeddingResponse
+	InputHash string
+	// Digest changes only when the stored vector does. updated_at is no
+	// use for that: usage tracking and the touch conflict policy bump it
+	// on every hit.
+	Digest string
+}
+
+// Lookup returns the cached embedding for a single input without calling
+// the provider. It returns nil when the input is not cached.
+func (c *Cache) Lookup(ctx context.Context, req *EmbeddingRequest) (*LookupResult, error) {
+	input, ok := req.Input.(string)
+	if !ok {
+		return nil, fmt.Errorf("lookup input must be a single string")
+	}
+
+	modelName := c.resolveModel(req.Model)
+
+	input = c.prepareInputs([]string{input}, modelName)[0]
+	if input == "" {
+		return nil, fmt.Errorf("input text cannot be empty after normalization")
+	}
+
+	inputHash := c.inputHash(input, modelName, c.hashVariants(req)...)
+
+	cached, err := c.db.GetCachedEmbedding(ctx, inputHash, modelName)
+	if errors.Is(err, database.ErrCorruptEmbedding) {
+		cached, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check cache: %w", err)
+	}
+	if isStale(cached, c.maxAge(req)) {
+		c.counters.staleHits.Add(1)
+		cached = nil
+	}
+	if c.wrongSize(cached, req, modelName) {
+		cached = nil
+	}
+
+	if cached == nil {
+		c.counters.record(0, 1)
+		c.logger.Info("Cache lookup miss",
+			zap.String("input_hash", inputHash[:16]+"..."),
+			zap.String("model", modelName))
+		return nil, nil
+	}
+
+	c.counters.record(1, 0)
+	if c.tracker != nil {
+		c.tracker.TrackUsage(cached.ID)
+	}
+
+	response := &EmbeddingResponse{
+		Model:  cached.ModelName,
+		Cached: true,
+	}
+	if cached.EmbeddingMatrix != nil {
+		response.MultiEmbedding = cached.EmbeddingMatrix
+	} else {
+		response.Embedding = cached.EmbeddingVector
+	}
+
+	return &LookupResult{
+		Response:  response,
+		InputHash: inputHash,
+		Digest:    vectorDigest(cached),
+	}, nil
+}
+
+// vectorDigest hashes the stored vector, or matrix, bit for bit.
+func vectorDigest(cached *database.CachedEmbedding) string {
+	digest := sha256.New()
+	var buf [8]byte
+	write := func(vector []float64) {
+		for _, value := range vector {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(value))
+			digest.Write(buf[:])
+		}
+	}
+
+	if cached.EmbeddingMatrix != nil {
+		for _, vector := range cached.EmbeddingMatrix {
+			binary.LittleEndian.PutUint64(buf[:], uint64(len(vector)))
+			digest.Write(buf[:])
+			write(vector)
+		}
+	} else {
+		write(cached.EmbeddingVector)
+	}
+
+	return hex.EncodeToString(digest.Sum(nil)[:8])
+}
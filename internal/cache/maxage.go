@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+)
+
+// maxAge is how old a cached vector may be and still be served: the
+// request's max_age if set, else cache.max_age_sec. Zero means no limit.
+func (c *Cache) maxAge(req *EmbeddingRequest) time.Duration {
+	if req.MaxAge > 0 {
+		return time.Duration(req.MaxAge) * time.Second
+	}
+	return time.Duration(c.config.MaxAgeSec) * time.Second
+}
+
+func isStale(cached *database.CachedEmbedding, maxAge time.Duration) bool {
+	return cached != nil && maxAge > 0 && time.Since(cached.CreatedAt) > maxAge
+}
+
+// dropStale turns hits older than maxAge into misses and returns their
+// hashes, so the fresh vectors overwrite the old rows.
+func (c *Cache) dropStale(items []*database.BatchItem, maxAge time.Duration) map[string]bool {
+	if maxAge <= 0 {
+		return nil
+	}
+
+	stale := make(map[string]bool)
+	for _, item := range items {
+		if isStale(item.Cached, maxAge) {
+			item.Cached = nil
+			stale[item.Hash] = true
+		}
+	}
+
+	if len(stale) > 0 {
+		c.counters.staleHits.Add(int64(len(stale)))
+		c.logger.Info("Treating stale cache hits as misses",
+			zap.Int("stale", len(stale)),
+			zap.Duration("max_age", maxAge))
+	}
+
+	return stale
+}
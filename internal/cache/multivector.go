@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+)
+
+func (c *Cache) processMultiVectorRequest(ctx context.Context, req *EmbeddingRequest, modelName string, isBatch bool) (*EmbeddingResponse, error) {
+	inputs, err := c.normalizeInput(req.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("batch input cannot be empty")
+	}
+
+	if len(inputs) > MaxBatchSize {
+		return nil, fmt.Errorf("batch input too large (max %d items)", MaxBatchSize)
+	}
+
+	for i, input := range inputs {
+		if input == "" {
+			return nil, fmt.Errorf("input at index %d cannot be empty", i)
+		}
+	}
+
+	inputs = c.prepareInputs(inputs, modelName)
+	if err := checkPreparedInputs(inputs); err != nil {
+		return nil, err
+	}
+	normalize := c.shouldNormalize(req)
+	startTime := time.Now()
+
+	batchItems := c.prepareBatchItems(inputs, modelName, c.hashVariants(req))
+	batchItems, err = c.db.GetBatchCachedEmbeddings(ctx, batchItems, modelName)
+	if err != nil {
+		c.logger.Error("Failed to check multi-vector cache",
+			zap.Error(err))
+		return nil, fmt.Errorf("failed to check cache: %w", err)
+	}
+	replace := withCorrupt(batchItems, c.dropStale(batchItems, c.maxAge(req)))
+
+	matrices := make([][][]float64, len(inputs))
+	cachedFlags := make([]bool, len(inputs))
+	var missing []*database.BatchItem
+	for _, item := range batchItems {
+		if item.Cached != nil && len(item.Cached.EmbeddingMatrix) > 0 {
+			matrices[item.Index] = item.Cached.EmbeddingMatrix
+			cachedFlags[item.Index] = true
+			if c.tracker != nil {
+				c.tracker.TrackUsage(item.Cached.ID)
+			}
+			continue
+		}
+		if item.Cached != nil {
+			// A single-vector row from before the model switched to
+			// multi_vector: the conflict policy would keep it, so
+			// overwrite it with the matrix.
+			if replace == nil {
+				replace = make(map[string]bool)
+			}
+			replace[item.Hash] = true
+		}
+		missing = append(missing, item)
+	}
+
+	c.counters.record(len(inputs)-len(missing), len(missing))
+
+	response := &EmbeddingResponse{Model: modelName}
+
+	// Misses another request is already embedding are waited for rather
+	// than embedded again, as in processPreparedBatch.
+	var led, waiting []*database.BatchItem
+	var ledCalls, waitingCalls []*flightCall
+	for _, item := range missing {
+		call, leader := c.inflight.claim(item.Hash)
+		if leader {
+			led = append(led, item)
+			ledCalls = append(ledCalls, call)
+		} else {
+			waiting = append(waiting, item)
+			waitingCalls = append(waitingCalls, call)
+		}
+	}
+
+	if len(led) > 0 {
+		ledInputs := make([]string, len(led))
+		for i, item := range led {
+			ledInputs[i] = item.Input
+		}
+
+		c.counters.providerCalls.Add(1)
+		aiResponse, err := c.ai.CreateMultiVectorEmbeddings(ctx, ledInputs, modelName)
+		if err != nil {
+			for i, item := range led {
+				c.inflight.finishMatrix(item.Hash, ledCalls[i], nil, err)
+			}
+			c.logger.Error("Failed to create multi-vector embeddings via OpenAI",
+				zap.Error(err))
+			return nil, fmt.Errorf("failed to create embeddings: %w", err)
+		}
+
+		for i, item := range led {
+			matrix := aiResponse.Embeddings[i]
+			if normalize {
+				for _, vector := range matrix {
+					NormalizeVector(vector)
+				}
+			}
+			matrices[item.Index] = matrix
+
+			store := c.db.StoreEmbeddingMatrix
+			if replace[item.Hash] {
+				store = c.db.ReplaceEmbeddingMatrix
+			}
+			err := store(ctx, item.Hash, item.Input, modelName, matrix)
+			c.inflight.finishMatrix(item.Hash, ledCalls[i], matrix, nil)
+			if err != nil {
+				c.logger.Error("Failed to store embedding matrix",
+					zap.String("input_hash", item.Hash[:16]+"..."),
+					zap.Error(err))
+				c.enqueueMatrixStoreRetry(item.Hash, item.Input, modelName, matrix, replace[item.Hash])
+			}
+		}
+
+		response.Model = aiResponse.Model
+		response.ProviderModel = aiResponse.ProviderModel
+		response.TokenUsage.PromptTokens = aiResponse.TokenUsage.PromptTokens
+		response.TokenUsage.TotalTokens = aiResponse.TokenUsage.TotalTokens
+	}
+
+	for i, item := range waiting {
+		matrix, err := waitingCalls[i].waitMatrix(ctx)
+		if abandoned(ctx, err) {
+			// Items this request led are stored by now, so the rerun
+			// serves them from the cache.
+			return c.processMultiVectorRequest(ctx, req, modelName, isBatch)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embeddings: %w", err)
+		}
+		matrices[item.Index] = matrix
+	}
+
+	if len(waiting) > 0 {
+		c.logger.Info("Shared in-flight multi-vector embeddings",
+			zap.Int("shared", len(waiting)))
+	}
+
+	c.logger.Info("Successfully processed multi-vector request",
+		zap.Int("batch_size", len(inputs)),
+		zap.Int("cache_hits", len(inputs)-len(missing)),
+		zap.Int("cache_misses", len(missing)),
+		zap.Duration("total_time", time.Since(startTime)))
+
+	if !isBatch {
+		response.MultiEmbedding = matrices[0]
+		response.Cached = cachedFlags[0]
+		return response, nil
+	}
+
+	response.MultiEmbeddings = matrices
+	response.CachedItems = cachedFlags
+	return response, nil
+}
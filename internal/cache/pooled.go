@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+)
+
+const (
+	PoolingMean = "mean"
+	PoolingMax  = "max"
+)
+
+type PooledRequest struct {
+	Inputs    []string  `json:"inputs" binding:"required"`
+	Pooling   string    `json:"pooling,omitempty"` // "mean" (default) or "max"
+	Weights   []float64 `json:"weights,omitempty"` // one per input, mean pooling only
+	Model     string    `json:"model,omitempty"`
+	Normalize *bool     `json:"normalize,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Tenant    string    `json:"-"`
+}
+
+type PooledResponse struct {
+	Embedding   []float64 `json:"embedding"`
+	Model       string    `json:"model"`
+	Pooling     string    `json:"pooling"`
+	Inputs      int       `json:"inputs"`
+	Cached      bool      `json:"cached,omitempty"`
+	CachedItems int       `json:"cached_items"`
+	TokenUsage  struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func (r *PooledRequest) embeddingRequest() *EmbeddingRequest {
+	inputs := make([]interface{}, len(r.Inputs))
+	for i, input := range r.Inputs {
+		inputs[i] = input
+	}
+
+	return &EmbeddingRequest{
+		Input:     inputs,
+		Model:     r.Model,
+		Normalize: r.Normalize,
+		User:      r.User,
+		Tenant:    r.Tenant,
+	}
+}
+
+func (c *Cache) ValidatePooledRequest(req *PooledRequest) error {
+	if len(req.Inputs) == 0 {
+		return fmt.Errorf("inputs cannot be empty")
+	}
+
+	if req.Pooling == "" {
+		req.Pooling = PoolingMean
+	}
+	if req.Pooling != PoolingMean && req.Pooling != PoolingMax {
+		return fmt.Errorf("invalid pooling %q (expected mean or max)", req.Pooling)
+	}
+
+	if len(req.Weights) > 0 {
+		if req.Pooling != PoolingMean {
+			return fmt.Errorf("weights are only supported with mean pooling")
+		}
+		if len(req.Weights) != len(req.Inputs) {
+			return fmt.Errorf("got %d weights for %d inputs", len(req.Weights), len(req.Inputs))
+		}
+		var total float64
+		for i, weight := range req.Weights {
+			if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+				return fmt.Errorf("invalid weight at index %d: %v", i, weight)
+			}
+			total += weight
+		}
+		if total == 0 {
+			return fmt.Errorf("weights cannot all be zero")
+		}
+	}
+
+	if c.ai.ModelConfig(c.resolveModel(req.Model)).MultiVector {
+		return fmt.Errorf("pooling is not supported for multi-vector models")
+	}
+
+	return c.ValidateRequest(req.embeddingRequest())
+}
+
+// Pooled returns one vector for a group of inputs, pooled from their
+// embeddings and cached under a key derived from the members, so the same
+// group in any order is a hit.
+func (c *Cache) Pooled(ctx context.Context, req *PooledRequest) (*PooledResponse, error) {
+	startTime := time.Now()
+	embedReq := req.embeddingRequest()
+	modelName := c.resolveModel(req.Model)
+
+	inputs := c.prepareInputs(req.Inputs, modelName)
+	if err := checkPreparedInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	variants := c.hashVariants(embedReq)
+	members := make([]string, len(inputs))
+	for i, input := range inputs {
+		members[i] = c.inputHash(input, modelName, variants...)
+		if len(req.Weights) > 0 {
+			members[i] += "*" + strconv.FormatFloat(req.Weights[i], 'g', -1, 64)
+		}
+	}
+	groupHash := c.hasher.GenerateGroupHash(members, "pool:"+req.Pooling)
+
+	response := &PooledResponse{
+		Model:   modelName,
+		Pooling: req.Pooling,
+		Inputs:  len(inputs),
+	}
+
+	store := c.db.StoreEmbedding
+	cached, err := c.db.GetCachedEmbedding(ctx, groupHash, modelName)
+	if errors.Is(err, database.ErrCorruptEmbedding) {
+		cached, err = nil, nil
+		store = c.db.ReplaceEmbedding
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check cache: %w", err)
+	}
+	// Stale or wrongly sized pooled vectors are re-pooled and overwrite
+	// the row, as single hits are.
+	if isStale(cached, c.maxAge(embedReq)) {
+		c.counters.staleHits.Add(1)
+		cached = nil
+		store = c.db.ReplaceEmbedding
+	}
+	if c.wrongSize(cached, embedReq, modelName) {
+		cached = nil
+		store = c.db.ReplaceEmbedding
+	}
+	if cached != nil {
+		c.counters.record(1, 0)
+		if c.tracker != nil {
+			c.tracker.TrackUsage(cached.ID)
+		}
+
+		c.logger.Info("Pooled cache hit",
+			zap.String("group_hash", groupHash[:16]+"..."),
+			zap.Int("inputs", len(inputs)),
+			zap.Duration("lookup_time", time.Since(startTime)))
+
+		response.Embedding = cached.EmbeddingVector
+		response.Cached = true
+		response.CachedItems = len(inputs)
+		return response, nil
+	}
+
+	embeddings, err := c.GetEmbedding(ctx, embedReq)
+	if err != nil {
+		return nil, err
+	}
+	if embeddings.Partial || len(embeddings.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("failed to embed every input in the group")
+	}
+
+	pooled, err := poolVectors(embeddings.Embeddings, req.Pooling, req.Weights)
+	if err != nil {
+		return nil, err
+	}
+	if c.shouldNormalize(embedReq) {
+		NormalizeVector(pooled)
+	}
+
+	// No input text is stored: re-embedding it would not reproduce the
+	// pooled vector, so /refresh skips the row.
+	if err := store(ctx, groupHash, "", modelName, pooled); err != nil {
+		c.logger.Error("Failed to store pooled embedding",
+			zap.String("group_hash", groupHash[:16]+"..."),
+			zap.Error(err))
+	}
+
+	c.logger.Info("Pooled embedding created",
+		zap.String("group_hash", groupHash[:16]+"..."),
+		zap.String("pooling", req.Pooling),
+		zap.Int("inputs", len(inputs)),
+		zap.Duration("total_time", time.Since(startTime)))
+
+	response.Embedding = pooled
+	response.TokenUsage = embeddings.TokenUsage
+	for _, hit := range embeddings.CachedItems {
+		if hit {
+			response.CachedItems++
+		}
+	}
+
+	return response, nil
+}
+
+func poolVectors(vectors [][]float64, pooling string, weights []float64) ([]float64, error) {
+	dims := len(vectors[0])
+	for i, vector := range vectors {
+		if len(vector) != dims {
+			return nil, fmt.Errorf("embedding dimensions differ: %d at index 0, %d at index %d", dims, len(vector), i)
+		}
+	}
+
+	pooled := make([]float64, dims)
+	switch pooling {
+	case PoolingMax:
+		copy(pooled, vectors[0])
+		for _, vector := range vectors[1:] {
+			for j, v := range vector {
+				pooled[j] = math.Max(pooled[j], v)
+			}
+		}
+	default:
+		var total float64
+		for i, vector := range vectors {
+			weight := 1.0
+			if len(weights) > 0 {
+				weight = weights[i]
+			}
+			total += weight
+			for j, v := range vector {
+				pooled[j] += weight * v
+			}
+		}
+		for j := range pooled {
+			pooled[j] /= total
+		}
+	}
+
+	return pooled, nil
+}
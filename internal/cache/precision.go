@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"math"
+	"strconv"
+)
+
+// RoundForResponse rounds the vectors of a response to
+// cache.response_float_precision significant digits, which shortens their
+// JSON encoding. The vectors are copied first because they may be shared
+// with concurrent requests or still queued for storage.
+func (c *Cache) RoundForResponse(response *EmbeddingResponse) {
+	digits := c.config.ResponseFloatPrecision
+	if digits <= 0 || response == nil {
+		return
+	}
+
+	response.Embedding = roundVector(response.Embedding, digits)
+	response.Embeddings = roundMatrix(response.Embeddings, digits)
+	response.MultiEmbedding = roundMatrix(response.MultiEmbedding, digits)
+	for i, matrix := range response.MultiEmbeddings {
+		response.MultiEmbeddings[i] = roundMatrix(matrix, digits)
+	}
+}
+
+func roundMatrix(matrix [][]float64, digits int) [][]float64 {
+	if matrix == nil {
+		return nil
+	}
+
+	rounded := make([][]float64, len(matrix))
+	for i, vector := range matrix {
+		rounded[i] = roundVector(vector, digits)
+	}
+	return rounded
+}
+
+func roundVector(vector []float64, digits int) []float64 {
+	if vector == nil {
+		return nil
+	}
+
+	rounded := make([]float64, len(vector))
+	for i, v := range vector {
+		if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			rounded[i] = v
+			continue
+		}
+		rounded[i], _ = strconv.ParseFloat(strconv.FormatFloat(v, 'g', digits, 64), 64)
+	}
+	return rounded
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+)
+
+type scheduledWarmup struct {
+	name     string
+	schedule *cronSchedule
+	cfg      config.WarmupScheduleConfig
+}
+
+func (job *scheduledWarmup) request(inputs []WarmupInput) *WarmupRequest {
+	return &WarmupRequest{Inputs: inputs, Model: job.cfg.Model}
+}
+
+// Scheduler runs the configured warmups on their cron schedules, keeping
+// hot inputs cached without manual /warmup calls.
+type Scheduler struct {
+	cache    *Cache
+	logger   *zap.Logger
+	jobs     []*scheduledWarmup
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewScheduler(c *Cache, cfgs []config.WarmupScheduleConfig, logger *zap.Logger) (*Scheduler, error) {
+	s := &Scheduler{
+		cache:    c,
+		logger:   logger,
+		stopChan: make(chan struct{}),
+	}
+
+	for i, cfg := range cfgs {
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("schedule-%d", i)
+		}
+
+		schedule, err := parseCron(cfg.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse warmup schedule %s: %w", name, err)
+		}
+		if schedule.next(time.Now()).IsZero() {
+			return nil, fmt.Errorf("warmup schedule %s never fires: %q", name, cfg.Cron)
+		}
+
+		if !c.ai.IsModelAllowed(cfg.Model) {
+			return nil, fmt.Errorf("warmup schedule %s uses unsupported model %q", name, cfg.Model)
+		}
+
+		s.jobs = append(s.jobs, &scheduledWarmup{
+			name:     name,
+			schedule: schedule,
+			cfg:      cfg,
+		})
+	}
+
+	return s, nil
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.logger.Info("Scheduling cache warmup",
+			zap.String("schedule", job.name),
+			zap.String("cron", job.cfg.Cron),
+			zap.Time("next_run", job.schedule.next(time.Now())))
+
+		s.wg.Add(1)
+		go s.run(ctx, job)
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stopChan)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, job *scheduledWarmup) {
+	defer s.wg.Done()
+
+	for {
+		next := job.schedule.next(time.Now())
+		if next.IsZero() {
+			s.logger.Error("Warmup schedule never fires, disabling it",
+				zap.String("schedule", job.name),
+				zap.String("cron", job.cfg.Cron))
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			s.runOnce(ctx, job)
+		case <-s.stopChan:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, job *scheduledWarmup) {
+	inputs, err := s.inputs(ctx, job)
+	if err != nil {
+		s.logger.Error("Failed to load scheduled warmup inputs",
+			zap.String("schedule", job.name),
+			zap.Error(err))
+		return
+	}
+
+	if len(inputs) == 0 {
+		s.logger.Info("Scheduled warmup has no inputs",
+			zap.String("schedule", job.name))
+		return
+	}
+
+	result, err := s.cache.Warmup(ctx, job.request(inputs))
+	if err != nil {
+		s.logger.Error("Scheduled warmup failed",
+			zap.String("schedule", job.name),
+			zap.Error(err))
+		return
+	}
+
+	s.logger.Info("Scheduled warmup finished",
+		zap.String("schedule", job.name),
+		zap.Int("processed", result.Processed),
+		zap.Int("failed", result.Failed),
+		zap.Bool("interrupted", result.Interrupted))
+}
+
+// inputs gathers the fixed inputs, the inputs file (one per line, re-read
+// on every run) and the most recently used cached texts.
+func (s *Scheduler) inputs(ctx context.Context, job *scheduledWarmup) ([]WarmupInput, error) {
+	var inputs []WarmupInput
+	for _, text := range job.cfg.Inputs {
+		inputs = append(inputs, WarmupInput{Text: text})
+	}
+
+	if job.cfg.InputsFile != "" {
+		lines, err := readInputsFile(job.cfg.InputsFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, text := range lines {
+			inputs = append(inputs, WarmupInput{Text: text})
+		}
+	}
+
+	if job.cfg.TopUsed > 0 {
+		modelName := s.cache.resolveModel(job.cfg.Model)
+
+		used, err := s.cache.db.RecentlyUsedInputs(ctx, modelName, job.cfg.TopUsed)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only rows the warmup would key the same way are refreshed; the
+		// rest belong to tenants or dimensions this schedule does not use,
+		// and warming them would only cache an unused key.
+		variants := s.cache.hashVariants(job.request(nil).embeddingRequest(nil))
+		skipped := 0
+		for _, row := range used {
+			input, _ := s.cache.prepareInput(row.InputText, modelName)
+			if s.cache.inputHash(input, modelName, variants...) != row.InputHash {
+				skipped++
+				continue
+			}
+			inputs = append(inputs, WarmupInput{Text: row.InputText})
+		}
+
+		if skipped > 0 {
+			s.logger.Info("Skipped recently used inputs keyed for other requests",
+				zap.String("schedule", job.name),
+				zap.Int("skipped", skipped))
+		}
+	}
+
+	return inputs, nil
+}
+
+func readInputsFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open warmup inputs file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read warmup inputs file: %w", err)
+	}
+
+	return lines, nil
+}
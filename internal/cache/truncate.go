@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+func (c *Cache) truncatesOverLimit() bool {
+	return c.config.OverLimitPolicy == "truncate"
+}
+
+// RejectsOverLimit reports whether inputs longer than the model's limit are
+// rejected, as opposed to truncated before hashing and embedding.
+func (c *Cache) RejectsOverLimit() bool {
+	return !c.truncatesOverLimit()
+}
+
+// prepareInput is the one place an input is turned into the text that is
+// hashed and embedded: lowercased when the model asks for it, normalized,
+// and with the truncate policy cut to the model's limit. truncated reports
+// whether the cut happened.
+func (c *Cache) prepareInput(input, modelName string) (prepared string, truncated bool) {
+	modelConfig := c.ai.ModelConfig(modelName)
+	if modelConfig.Lowercase {
+		input = strings.ToLower(input)
+	}
+	prepared = c.hasher.Normalize(input)
+
+	if !c.truncatesOverLimit() || len(prepared) <= modelConfig.MaxInputChars {
+		return prepared, false
+	}
+	return c.truncateInput(prepared, modelConfig.MaxInputChars), true
+}
+
+// truncateInput cuts normalized text to limit bytes on a rune boundary and
+// normalizes again, so trailing whitespace left by the cut does not make the
+// embedded text differ from the hashed text.
+func (c *Cache) truncateInput(input string, limit int) string {
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(input[cut]) {
+		cut--
+	}
+
+	return c.hasher.Normalize(input[:cut])
+}
+
+// overLimitItems returns the indexes of inputs that prepareInputs truncated.
+func (c *Cache) overLimitItems(req *EmbeddingRequest, modelName string) []int {
+	inputs, err := c.normalizeInput(req.Input)
+	if err != nil {
+		return nil
+	}
+
+	var truncated []int
+	for i, input := range inputs {
+		if _, cut := c.prepareInput(input, modelName); cut {
+			truncated = append(truncated, i)
+		}
+	}
+
+	return truncated
+}
@@ -0,0 +1,34 @@
+package cache
+
+// MatchStoredRow recovers the request behind a stored row. It prepares the
+// stored text the way requests are prepared and tries every normalize,
+// tenant and dimensions combination hashVariants can produce until one
+// reproduces the row's key. tenants lists the candidate tenant IDs, with ""
+// for requests made without one; dimensions is the stored vector length,
+// or 0 when it is unknown.
+func (c *Cache) MatchStoredRow(inputText, modelName, inputHash string, dimensions int, tenants []string) (string, *EmbeddingRequest, bool) {
+	input, _ := c.prepareInput(inputText, modelName)
+
+	sizes := []int{0}
+	if dimensions > 0 {
+		sizes = append(sizes, dimensions)
+	}
+
+	for _, tenant := range tenants {
+		for _, normalize := range []bool{false, true} {
+			for _, size := range sizes {
+				req := &EmbeddingRequest{
+					Model:      modelName,
+					Normalize:  &normalize,
+					Dimensions: size,
+					Tenant:     tenant,
+				}
+				if c.inputHash(input, modelName, c.hashVariants(req)...) == inputHash {
+					return input, req, true
+				}
+			}
+		}
+	}
+
+	return input, nil, false
+}
@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWarmupBatchSize = 100
+	maxWarmupErrors        = 10
+)
+
+// MaxWarmupInputs caps the inputs of one warmup, matching the largest
+// Meilisearch warmup.
+const MaxWarmupInputs = 100000
+
+type WarmupInput struct {
+	Text     string `json:"text"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+// WarmupRequest embeds inputs under the same key a request with the same
+// model, normalize, dimensions and tenant would use.
+type WarmupRequest struct {
+	Inputs     []WarmupInput `json:"inputs" binding:"required"`
+	Model      string        `json:"model,omitempty"`
+	Normalize  *bool         `json:"normalize,omitempty"`
+	Dimensions int           `json:"dimensions,omitempty"`
+	Tenant     string        `json:"tenant,omitempty"`
+	BatchSize  int           `json:"batch_size,omitempty"`
+}
+
+func (r *WarmupRequest) embeddingRequest(inputs []interface{}) *EmbeddingRequest {
+	return &EmbeddingRequest{
+		Input:      inputs,
+		Model:      r.Model,
+		Normalize:  r.Normalize,
+		Dimensions: r.Dimensions,
+		Tenant:     r.Tenant,
+	}
+}
+
+type WarmupResult struct {
+	Total       int      `json:"total"`
+	Processed   int      `json:"processed"`
+	Failed      int      `json:"failed"`
+	Hits        int      `json:"hits"`
+	Misses      int      `json:"misses"`
+	Errors      []string `json:"errors,omitempty"` // first maxWarmupErrors batch errors
+	Interrupted bool     `json:"interrupted,omitempty"`
+}
+
+func (c *Cache) ValidateWarmupRequest(req *WarmupRequest) error {
+	if len(req.Inputs) == 0 {
+		return fmt.Errorf("inputs cannot be empty")
+	}
+
+	if len(req.Inputs) > MaxWarmupInputs {
+		return fmt.Errorf("warmup too large (max %d inputs)", MaxWarmupInputs)
+	}
+
+	// Like ValidateRequest: with the truncate policy, oversized inputs are
+	// cut in prepareInputs.
+	maxInputChars := c.ai.ModelConfig(req.Model).MaxInputChars
+	for i, input := range req.Inputs {
+		if input.Text == "" {
+			return fmt.Errorf("input at index %d cannot be empty", i)
+		}
+		if c.RejectsOverLimit() && len(input.Text) > maxInputChars {
+			return fmt.Errorf("input at index %d too long (max %d characters)", i, maxInputChars)
+		}
+	}
+
+	if req.BatchSize < 0 || req.BatchSize > 1000 {
+		return fmt.Errorf("batch_size must be between 1 and 1000, or 0 for the default of %d", defaultWarmupBatchSize)
+	}
+
+	if !c.ai.IsModelAllowed(req.Model) {
+		return fmt.Errorf("model %q is not supported (configured model: %s)", req.Model, c.ai.GetModel())
+	}
+
+	if req.Dimensions != 0 {
+		if err := c.ai.ValidateDimensions(req.Model, req.Dimensions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Warmup embeds inputs in descending priority order, in batches, so the most
+// important entries are cached first if the warmup is cut short.
+func (c *Cache) Warmup(ctx context.Context, req *WarmupRequest) (*WarmupResult, error) {
+	return c.WarmupWithProgress(ctx, req, nil)
+}
+
+// WarmupWithProgress is Warmup that calls progress after every batch.
+func (c *Cache) WarmupWithProgress(ctx context.Context, req *WarmupRequest, progress func(*WarmupResult)) (*WarmupResult, error) {
+	inputs := make([]WarmupInput, len(req.Inputs))
+	copy(inputs, req.Inputs)
+	sort.SliceStable(inputs, func(i, j int) bool {
+		return inputs[i].Priority > inputs[j].Priority
+	})
+
+	batchSize := req.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultWarmupBatchSize
+	}
+
+	result := &WarmupResult{Total: len(inputs)}
+
+	c.logger.Info("Starting cache warmup",
+		zap.Int("input_count", len(inputs)),
+		zap.Int("batch_size", batchSize),
+		zap.String("model", req.Model))
+
+	for start := 0; start < len(inputs); start += batchSize {
+		if ctx.Err() != nil {
+			result.Interrupted = true
+			c.logger.Info("Cache warmup interrupted",
+				zap.Int("completed", result.Processed+result.Failed),
+				zap.Int("total", len(inputs)))
+			return result, nil
+		}
+
+		end := min(start+batchSize, len(inputs))
+		texts := make([]interface{}, 0, end-start)
+		for _, input := range inputs[start:end] {
+			texts = append(texts, input.Text)
+		}
+
+		response, err := c.GetEmbedding(ctx, req.embeddingRequest(texts))
+		if err != nil {
+			result.Failed += len(texts)
+			if len(result.Errors) < maxWarmupErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("batch at %d: %v", start, err))
+			}
+			c.logger.Error("Failed to warmup batch",
+				zap.Int("batch_start", start),
+				zap.Int("batch_size", len(texts)),
+				zap.Int("priority", inputs[start].Priority),
+				zap.Error(err))
+			if progress != nil {
+				progress(result)
+			}
+			continue
+		}
+
+		result.Processed += len(texts)
+		for _, cached := range response.CachedItems {
+			if cached {
+				result.Hits++
+			} else {
+				result.Misses++
+			}
+		}
+		if progress != nil {
+			progress(result)
+		}
+		c.logger.Info("Cache warmup progress",
+			zap.Int("completed", result.Processed+result.Failed),
+			zap.Int("total", len(inputs)),
+			zap.Int("priority", inputs[end-1].Priority))
+	}
+
+	c.logger.Info("Cache warmup completed",
+		zap.Int("processed", result.Processed),
+		zap.Int("failed", result.Failed))
+
+	return result, nil
+}
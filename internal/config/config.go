@@ -2,10 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/pelletier/go-toml/v2"
 	"go.uber.org/zap"
+	"golang.org/x/net/http/httpguts"
 )
 
 type Config struct {
@@ -14,11 +18,37 @@ type Config struct {
 	OpenAI   OpenAIConfig   `toml:"openai"`
 	Logging  LoggingConfig  `toml:"logging"`
 	Tracker  TrackerConfig  `toml:"tracker"`
+	Hash     HashConfig     `toml:"hash"`
+	Cache    CacheConfig    `toml:"cache"`
+	Tenants  []TenantConfig `toml:"tenants"`
+	Warmup   WarmupConfig   `toml:"warmup"`
+	Async    AsyncConfig    `toml:"async"`
 }
 
 type ServerConfig struct {
-	Port int    `toml:"port"`
-	Host string `toml:"host"`
+	Port               int      `toml:"port"`
+	Host               string   `toml:"host"`
+	CORSAllowedOrigins []string `toml:"cors_allowed_origins"`
+	CORSAllowedMethods []string `toml:"cors_allowed_methods"`
+	CORSAllowedHeaders []string `toml:"cors_allowed_headers"`
+	ResponseTemplate   string   `toml:"response_template"`
+	EnablePprof        bool     `toml:"enable_pprof"`
+	AdminPort          int      `toml:"admin_port"`
+	IdempotencyTTLSec  int      `toml:"idempotency_ttl_sec"`
+	Audit              bool     `toml:"audit"`
+
+	MaxConcurrentRequests int    `toml:"max_concurrent_requests"`
+	MaxQueuedRequests     int    `toml:"max_queued_requests"`
+	QueueTimeoutMs        int    `toml:"queue_timeout_ms"`
+	AdminToken            string `toml:"admin_token"`
+	MaxBodyBytes          int64  `toml:"max_body_bytes"`
+	UnixSocket            string `toml:"unix_socket"`
+	UnixSocketMode        string `toml:"unix_socket_mode"`
+	StatsAccess           string `toml:"stats_access"`
+	WarmupJobTTLSec       int    `toml:"warmup_job_ttl_sec"`
+	LookupMaxAgeSec       int    `toml:"lookup_max_age_sec"`
+
+	MeilisearchAllowedHosts []string `toml:"meilisearch_allowed_hosts"` // hosts POST /warmup/meilisearch may read from
 }
 
 type DatabaseConfig struct {
@@ -28,31 +58,181 @@ type DatabaseConfig struct {
 	Password string `toml:"password"`
 	DBName   string `toml:"dbname"`
 	SSLMode  string `toml:"sslmode"`
+
+	AcquireTimeoutMs   int  `toml:"acquire_timeout_ms"`
+	ShardCount         int  `toml:"shard_count"`
+	TablePerModel      bool `toml:"table_per_model"`
+	ReadRetries        int  `toml:"read_retries"`
+	ReadRetryBackoffMs int  `toml:"read_retry_backoff_ms"`
+	MaxVectorDims      int  `toml:"max_vector_dimensions"`
+	CompressVectors    bool `toml:"compress_vectors"` // same as vector_format = "compressed"
+
+	VectorFormat string `toml:"vector_format"`
+}
+
+// StoredVectorFormat is the format new vectors are written in, honouring
+// the older compress_vectors flag when vector_format is not set.
+func (d *DatabaseConfig) StoredVectorFormat() string {
+	switch {
+	case d.VectorFormat != "":
+		return d.VectorFormat
+	case d.CompressVectors:
+		return "compressed"
+	default:
+		return "json"
+	}
 }
 
 type OpenAIConfig struct {
-	APIKey      string `toml:"api_key"`
-	Model       string `toml:"model"`
-	BaseURL     string `toml:"base_url"`
-	MaxRetries  int    `toml:"max_retries"`
-	TimeoutSec  int    `toml:"timeout_sec"`
+	APIKeyFile           string        `toml:"api_key_file"`
+	APIKey               string        `toml:"api_key"`
+	Model                string        `toml:"model"`
+	BaseURL              string        `toml:"base_url"`
+	MaxRetries           int           `toml:"max_retries"`
+	TimeoutSec           int           `toml:"timeout_sec"`
+	PerRequestTimeoutSec int           `toml:"per_request_timeout_sec"`
+	StrictModel          bool          `toml:"strict_model"`
+	AllowedModels        []string      `toml:"allowed_models"`
+	RequireListedModels  bool          `toml:"require_listed_models"`
+	DebugHTTP            bool          `toml:"debug_http"`
+	HealthErrorRate      float64       `toml:"health_error_rate"`
+	HealthWindowSec      int           `toml:"health_window_sec"`
+	HealthMinRequests    int           `toml:"health_min_requests"`
+	Models               []ModelConfig `toml:"models"`
+	ChunkSize            int           `toml:"chunk_size"`
+	RetryBudget          int           `toml:"retry_budget"` // 0 = no retries, -1 = max_retries
+	MaxTokensPerRequest  int           `toml:"max_tokens_per_request"`
+	ChunkConcurrency     int           `toml:"chunk_concurrency"`
+	ResponseOrder        string        `toml:"response_order"`  // "index" or "position", how vectors are matched to inputs
+	InvalidVectors       string        `toml:"invalid_vectors"` // "fail" or "skip" zero, NaN and Inf vectors
+
+	PricePer1KTokens map[string]float64 `toml:"price_per_1k_tokens"` // USD, keyed by model
+
+	Fallbacks          []FallbackConfig `toml:"fallbacks"`
+	FallbackEquivalent bool             `toml:"fallback_equivalent"`
+
+	AllowedBaseHosts     []string `toml:"allowed_base_hosts"` // empty allows any public host
+	DeniedBaseHosts      []string `toml:"denied_base_hosts"`
+	AllowPrivateBaseURLs bool     `toml:"allow_private_base_urls"`
+
+	Mock bool `toml:"mock"` // serve deterministic fake vectors instead of calling the provider
+
+	ExtraHeaders map[string]string `toml:"extra_headers"` // sent with every provider request
+}
+
+type FallbackConfig struct {
+	Name         string            `toml:"name"`
+	BaseURL      string            `toml:"base_url"`
+	APIKey       string            `toml:"api_key"`
+	ExtraHeaders map[string]string `toml:"extra_headers"`
+}
+
+type ModelConfig struct {
+	Name          string `toml:"name"`
+	Dimensions    int    `toml:"dimensions"`
+	MaxDimensions int    `toml:"max_dimensions"` // largest per-request dimensions override
+	MaxInputChars int    `toml:"max_input_chars"`
+	Lowercase     bool   `toml:"lowercase"`
+	MultiVector   bool   `toml:"multi_vector"`
+
+	MaxConcurrency int     `toml:"max_concurrency"`
+	QPS            float64 `toml:"qps"`
 }
 
 type LoggingConfig struct {
-	Level  string `toml:"level"`
-	Format string `toml:"format"`
+	Level         string `toml:"level"`
+	Format        string `toml:"format"`
+	SlowRequestMs int    `toml:"slow_request_ms"`
+}
+
+type CacheConfig struct {
+	ServePartialOnProviderError bool   `toml:"serve_partial_on_provider_error"`
+	InputField                  string `toml:"input_field"`
+	Normalize                   bool   `toml:"normalize"`
+	StoreInputText              bool   `toml:"store_input_text"`
+	ConflictPolicy              string `toml:"conflict_policy"`
+	StoreRetryQueueSize         int    `toml:"store_retry_queue_size"`
+	StoreRetryAttempts          int    `toml:"store_retry_attempts"`
+	StoreRetryBackoffMs         int    `toml:"store_retry_backoff_ms"`
+	EmptyItemPolicy             string `toml:"empty_item_policy"`
+	OverLimitPolicy             string `toml:"over_limit_policy"`
+	ResponseFloatPrecision      int    `toml:"response_float_precision"`
+	MaxAgeSec                   int    `toml:"max_age_sec"` // hits created longer ago are re-embedded, 0 = no limit
+}
+
+type WarmupConfig struct {
+	Schedule []WarmupScheduleConfig `toml:"schedule"`
+}
+
+type WarmupScheduleConfig struct {
+	Name       string   `toml:"name"`
+	Cron       string   `toml:"cron"`
+	Model      string   `toml:"model"`
+	Inputs     []string `toml:"inputs"`
+	InputsFile string   `toml:"inputs_file"`
+	TopUsed    int      `toml:"top_used"`
+}
+
+type AsyncConfig struct {
+	CallbackURL          string   `toml:"callback_url"`           // default webhook for POST /embed/async
+	AllowedCallbackHosts []string `toml:"allowed_callback_hosts"` // hosts a request's callback_url may name
+	CallbackSecret       string   `toml:"callback_secret"`        // HMAC-SHA256 key signing every callback
+	CallbackRetries      int      `toml:"callback_retries"`
+	CallbackBackoffMs    int      `toml:"callback_backoff_ms"`
+	CallbackTimeoutSec   int      `toml:"callback_timeout_sec"`
+	MaxInputs            int      `toml:"max_inputs"`
+	MaxInlineInputs      int      `toml:"max_inline_inputs"` // larger results are sent as a result_url instead
+	ResultBaseURL        string   `toml:"result_base_url"`   // prefixes result_url, e.g. https://meep.example.com
+	JobTTLSec            int      `toml:"job_ttl_sec"`
+	MaxRunning           int      `toml:"max_running"`            // running jobs across all tenants, 0 = no limit
+	MaxRunningPerTenant  int      `toml:"max_running_per_tenant"` // running jobs per tenant, 0 = no limit
+}
+
+// Enabled reports whether POST /embed/async is served.
+func (a *AsyncConfig) Enabled() bool {
+	return a.CallbackURL != "" || len(a.AllowedCallbackHosts) > 0
+}
+
+type TenantConfig struct {
+	ID          string `toml:"id"`
+	APIKey      string `toml:"api_key"`
+	TokenBudget int64  `toml:"token_budget"` // provider tokens per UTC day, 0 = unlimited
+}
+
+type HashConfig struct {
+	Algorithm          string   `toml:"algorithm"` // "sha256", "xxhash" or "blake3"; switching invalidates the cache
+	Namespace          string   `toml:"namespace"`
+	PreserveWhitespace bool     `toml:"preserve_whitespace"`
+	Transforms         []string `toml:"transforms"` // applied in order before hashing and embedding
 }
 
 type TrackerConfig struct {
 	BatchSize        int `toml:"batch_size"`
 	FlushIntervalSec int `toml:"flush_interval_sec"`
+	ChannelBuffer    int `toml:"channel_buffer"`
+	BlockTimeoutMs   int `toml:"block_timeout_ms"`
+
+	RetryAttempts  int    `toml:"retry_attempts"`
+	RetryBackoffMs int    `toml:"retry_backoff_ms"`
+	OnFailure      string `toml:"on_failure"`
+	SpillFile      string `toml:"spill_file"`
+	SpillMaxBytes  int64  `toml:"spill_max_bytes"` // updates that would grow spill_file past this are dropped
 }
 
 func Load(configPath string) (*Config, error) {
 	config := &Config{
 		Server: ServerConfig{
-			Port: 9090,
-			Host: "0.0.0.0",
+			Port:               9090,
+			Host:               "0.0.0.0",
+			CORSAllowedOrigins: []string{},
+			CORSAllowedMethods: []string{"GET", "POST", "OPTIONS"},
+			CORSAllowedHeaders: []string{"Content-Type", "Authorization"},
+			IdempotencyTTLSec:  300,
+			MaxBodyBytes:       16 << 20,
+			UnixSocketMode:     "0660",
+			StatsAccess:        "public",
+			WarmupJobTTLSec:    3600,
+			QueueTimeoutMs:     1000,
 		},
 		Database: DatabaseConfig{
 			Host:     "localhost",
@@ -61,21 +241,63 @@ func Load(configPath string) (*Config, error) {
 			Password: "",
 			DBName:   "meep",
 			SSLMode:  "disable",
+
+			AcquireTimeoutMs:   2000,
+			ReadRetries:        2,
+			ReadRetryBackoffMs: 100,
+			MaxVectorDims:      8192,
 		},
 		OpenAI: OpenAIConfig{
-			APIKey:     "",
-			Model:      "text-embedding-3-small",
-			BaseURL:    "https://api.openai.com/v1",
-			MaxRetries: 3,
-			TimeoutSec: 30,
+			APIKey:           "",
+			Model:            "text-embedding-3-small",
+			BaseURL:          "https://api.openai.com/v1",
+			MaxRetries:       3,
+			TimeoutSec:       30,
+			ChunkSize:        1000,
+			ChunkConcurrency: 1,
+			RetryBudget:      3,
+			ResponseOrder:    "index",
+			InvalidVectors:   "fail",
+
+			HealthWindowSec:   60,
+			HealthMinRequests: 10,
+
+			FallbackEquivalent: true,
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
 			Format: "json",
 		},
+		Hash: HashConfig{
+			Algorithm: "sha256",
+		},
+		Async: AsyncConfig{
+			CallbackRetries:     3,
+			CallbackBackoffMs:   1000,
+			CallbackTimeoutSec:  10,
+			MaxInputs:           10000,
+			MaxInlineInputs:     1000,
+			JobTTLSec:           3600,
+			MaxRunning:          16,
+			MaxRunningPerTenant: 4,
+		},
 		Tracker: TrackerConfig{
 			BatchSize:        50,
 			FlushIntervalSec: 5,
+			ChannelBuffer:    1000,
+			RetryAttempts:    2,
+			RetryBackoffMs:   200,
+			OnFailure:        "drop",
+			SpillMaxBytes:    16 << 20,
+		},
+		Cache: CacheConfig{
+			StoreInputText:      true,
+			ConflictPolicy:      "touch",
+			EmptyItemPolicy:     "reject",
+			OverLimitPolicy:     "reject",
+			StoreRetryQueueSize: 1000,
+			StoreRetryAttempts:  5,
+			StoreRetryBackoffMs: 1000,
 		},
 	}
 
@@ -94,6 +316,12 @@ func Load(configPath string) (*Config, error) {
 		}
 	}
 
+	apiKey, err := config.OpenAI.ResolveAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	config.OpenAI.APIKey = apiKey
+
 	if err := config.validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
@@ -106,6 +334,14 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
+	if c.Server.AdminPort < 0 || c.Server.AdminPort > 65535 {
+		return fmt.Errorf("invalid admin port: %d", c.Server.AdminPort)
+	}
+
+	if c.Server.AdminPort != 0 && c.Server.AdminPort == c.Server.Port {
+		return fmt.Errorf("admin port must differ from server port: %d", c.Server.AdminPort)
+	}
+
 	if c.Database.Port < 1 || c.Database.Port > 65535 {
 		return fmt.Errorf("invalid database port: %d", c.Database.Port)
 	}
@@ -118,7 +354,118 @@ func (c *Config) validate() error {
 		return fmt.Errorf("database name is required")
 	}
 
-	if c.OpenAI.APIKey == "" {
+	if c.Server.LookupMaxAgeSec < 0 {
+		return fmt.Errorf("invalid lookup max age: %d", c.Server.LookupMaxAgeSec)
+	}
+
+	if c.Server.WarmupJobTTLSec < 0 {
+		return fmt.Errorf("invalid warmup job TTL: %d", c.Server.WarmupJobTTLSec)
+	}
+
+	switch c.Server.StatsAccess {
+	case "public", "admin", "disabled":
+	default:
+		return fmt.Errorf("invalid server stats access: %q (expected public, admin or disabled)", c.Server.StatsAccess)
+	}
+
+	if c.Server.UnixSocket != "" {
+		if _, err := strconv.ParseUint(c.Server.UnixSocketMode, 8, 32); err != nil {
+			return fmt.Errorf("invalid unix socket mode: %q", c.Server.UnixSocketMode)
+		}
+	}
+
+	if c.Server.MaxBodyBytes < 0 {
+		return fmt.Errorf("invalid max body bytes: %d", c.Server.MaxBodyBytes)
+	}
+
+	switch c.Database.VectorFormat {
+	case "", "json", "compressed", "binary":
+	default:
+		return fmt.Errorf("invalid database vector format: %q (expected json, compressed or binary)", c.Database.VectorFormat)
+	}
+
+	if c.Database.CompressVectors && c.Database.StoredVectorFormat() != "compressed" {
+		return fmt.Errorf("database.compress_vectors conflicts with vector_format = %q", c.Database.VectorFormat)
+	}
+
+	if c.Server.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("invalid max concurrent requests: %d", c.Server.MaxConcurrentRequests)
+	}
+
+	if c.Server.MaxQueuedRequests < 0 {
+		return fmt.Errorf("invalid max queued requests: %d", c.Server.MaxQueuedRequests)
+	}
+
+	if c.Server.MaxQueuedRequests > 0 {
+		if c.Server.MaxConcurrentRequests == 0 {
+			return fmt.Errorf("max_queued_requests requires max_concurrent_requests")
+		}
+		if c.Server.QueueTimeoutMs <= 0 {
+			return fmt.Errorf("invalid queue timeout: %dms", c.Server.QueueTimeoutMs)
+		}
+	}
+
+	switch c.Hash.Algorithm {
+	case "sha256", "xxhash", "blake3":
+	default:
+		return fmt.Errorf("invalid hash algorithm: %q (expected sha256, xxhash or blake3)", c.Hash.Algorithm)
+	}
+
+	for _, transform := range c.Hash.Transforms {
+		switch transform {
+		case "strip_html", "collapse_digits", "lowercase", "nfc":
+		default:
+			return fmt.Errorf("invalid hash transform: %q (expected strip_html, collapse_digits, lowercase or nfc)", transform)
+		}
+	}
+
+	switch c.Cache.ConflictPolicy {
+	case "overwrite", "touch", "ignore":
+	default:
+		return fmt.Errorf("invalid cache conflict policy: %q (expected overwrite, touch or ignore)", c.Cache.ConflictPolicy)
+	}
+
+	switch c.Cache.EmptyItemPolicy {
+	case "reject", "skip", "zero":
+	default:
+		return fmt.Errorf("invalid cache empty item policy: %q (expected reject, skip or zero)", c.Cache.EmptyItemPolicy)
+	}
+
+	if c.Cache.MaxAgeSec < 0 {
+		return fmt.Errorf("invalid cache max age: %d", c.Cache.MaxAgeSec)
+	}
+
+	if c.Cache.ResponseFloatPrecision < 0 || c.Cache.ResponseFloatPrecision > 17 {
+		return fmt.Errorf("invalid cache response float precision: %d (must be 0-17)", c.Cache.ResponseFloatPrecision)
+	}
+
+	switch c.Cache.OverLimitPolicy {
+	case "reject", "truncate":
+	default:
+		return fmt.Errorf("invalid cache over limit policy: %q (expected reject or truncate)", c.Cache.OverLimitPolicy)
+	}
+
+	if c.Cache.StoreRetryQueueSize < 0 || c.Cache.StoreRetryAttempts < 0 || c.Cache.StoreRetryBackoffMs < 0 {
+		return fmt.Errorf("invalid cache store retry settings")
+	}
+
+	if c.Database.ShardCount < 0 || c.Database.ShardCount > 256 {
+		return fmt.Errorf("invalid database shard count: %d (must be 0-256)", c.Database.ShardCount)
+	}
+
+	if c.Database.ReadRetries < 0 || c.Database.ReadRetryBackoffMs < 0 {
+		return fmt.Errorf("invalid database read retry settings: %d retries, %dms backoff", c.Database.ReadRetries, c.Database.ReadRetryBackoffMs)
+	}
+
+	if c.Database.MaxVectorDims < 1 {
+		return fmt.Errorf("invalid database max vector dimensions: %d", c.Database.MaxVectorDims)
+	}
+
+	if c.Database.AcquireTimeoutMs < 0 {
+		return fmt.Errorf("invalid database acquire timeout: %d", c.Database.AcquireTimeoutMs)
+	}
+
+	if c.OpenAI.APIKey == "" && !c.OpenAI.Mock {
 		return fmt.Errorf("OpenAI API key is required")
 	}
 
@@ -126,9 +473,195 @@ func (c *Config) validate() error {
 		return fmt.Errorf("OpenAI model is required")
 	}
 
+	seenModels := make(map[string]bool, len(c.OpenAI.Models))
+	for i, model := range c.OpenAI.Models {
+		if model.Name == "" {
+			return fmt.Errorf("OpenAI model at index %d requires a name", i)
+		}
+		if seenModels[model.Name] {
+			return fmt.Errorf("duplicate OpenAI model: %s", model.Name)
+		}
+		seenModels[model.Name] = true
+
+		if model.Dimensions < 0 || model.MaxDimensions < 0 {
+			return fmt.Errorf("invalid dimensions for model %s: %d (max %d)", model.Name, model.Dimensions, model.MaxDimensions)
+		}
+		if model.MaxInputChars < 0 {
+			return fmt.Errorf("invalid max_input_chars for model %s: %d", model.Name, model.MaxInputChars)
+		}
+		if model.MaxConcurrency < 0 || model.QPS < 0 {
+			return fmt.Errorf("invalid rate limits for model %s: max_concurrency %d, qps %v", model.Name, model.MaxConcurrency, model.QPS)
+		}
+	}
+
+	if c.OpenAI.ChunkSize < 1 || c.OpenAI.ChunkSize > 2048 {
+		return fmt.Errorf("invalid OpenAI chunk size: %d (must be 1-2048)", c.OpenAI.ChunkSize)
+	}
+
+	if c.OpenAI.ChunkConcurrency < 0 {
+		return fmt.Errorf("invalid OpenAI chunk concurrency: %d", c.OpenAI.ChunkConcurrency)
+	}
+
+	if c.OpenAI.ResponseOrder != "index" && c.OpenAI.ResponseOrder != "position" {
+		return fmt.Errorf("invalid OpenAI response order: %q (expected index or position)", c.OpenAI.ResponseOrder)
+	}
+
+	if c.OpenAI.InvalidVectors != "fail" && c.OpenAI.InvalidVectors != "skip" {
+		return fmt.Errorf("invalid OpenAI invalid_vectors policy: %q (expected fail or skip)", c.OpenAI.InvalidVectors)
+	}
+
+	if c.OpenAI.MaxTokensPerRequest < 0 {
+		return fmt.Errorf("invalid OpenAI max tokens per request: %d", c.OpenAI.MaxTokensPerRequest)
+	}
+
+	for model, price := range c.OpenAI.PricePer1KTokens {
+		if price < 0 {
+			return fmt.Errorf("invalid price for model %s: %v", model, price)
+		}
+	}
+
+	for i, fallback := range c.OpenAI.Fallbacks {
+		if fallback.BaseURL == "" || fallback.APIKey == "" {
+			return fmt.Errorf("fallback %d: base_url and api_key are required", i)
+		}
+		if err := validateExtraHeaders(fallback.ExtraHeaders); err != nil {
+			return fmt.Errorf("fallback %d: %w", i, err)
+		}
+	}
+
+	if err := validateExtraHeaders(c.OpenAI.ExtraHeaders); err != nil {
+		return err
+	}
+
+	if c.OpenAI.HealthErrorRate < 0 || c.OpenAI.HealthErrorRate > 1 {
+		return fmt.Errorf("invalid OpenAI health error rate: %v (must be 0-1)", c.OpenAI.HealthErrorRate)
+	}
+
+	if c.OpenAI.HealthWindowSec < 1 {
+		return fmt.Errorf("invalid OpenAI health window: %d", c.OpenAI.HealthWindowSec)
+	}
+
+	if c.OpenAI.PerRequestTimeoutSec < 0 {
+		return fmt.Errorf("invalid OpenAI per-request timeout: %d", c.OpenAI.PerRequestTimeoutSec)
+	}
+
+	if c.OpenAI.RetryBudget < -1 {
+		return fmt.Errorf("invalid OpenAI retry budget: %d", c.OpenAI.RetryBudget)
+	}
+
+	if c.Tracker.ChannelBuffer < 1 {
+		return fmt.Errorf("invalid tracker channel buffer: %d", c.Tracker.ChannelBuffer)
+	}
+
+	if c.Tracker.BlockTimeoutMs < 0 {
+		return fmt.Errorf("invalid tracker block timeout: %d", c.Tracker.BlockTimeoutMs)
+	}
+
+	if c.Tracker.RetryAttempts < 0 || c.Tracker.RetryBackoffMs < 0 {
+		return fmt.Errorf("invalid tracker retry settings: attempts %d, backoff %dms", c.Tracker.RetryAttempts, c.Tracker.RetryBackoffMs)
+	}
+
+	switch c.Tracker.OnFailure {
+	case "drop", "requeue":
+	case "spill":
+		if c.Tracker.SpillFile == "" {
+			return fmt.Errorf("tracker.on_failure = \"spill\" requires tracker.spill_file")
+		}
+		if c.Tracker.SpillMaxBytes < 1 {
+			return fmt.Errorf("invalid tracker spill_max_bytes: %d", c.Tracker.SpillMaxBytes)
+		}
+	default:
+		return fmt.Errorf("invalid tracker on_failure: %q (expected drop, requeue or spill)", c.Tracker.OnFailure)
+	}
+
+	if c.Async.Enabled() {
+		if c.Async.CallbackSecret == "" {
+			return fmt.Errorf("async callbacks require async.callback_secret")
+		}
+		if c.Async.CallbackURL != "" {
+			if u, err := url.Parse(c.Async.CallbackURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return fmt.Errorf("invalid async callback URL: %q", c.Async.CallbackURL)
+			}
+		}
+		if c.Async.CallbackRetries < 0 || c.Async.CallbackBackoffMs < 0 || c.Async.CallbackTimeoutSec < 1 {
+			return fmt.Errorf("invalid async callback settings: retries %d, backoff %dms, timeout %ds",
+				c.Async.CallbackRetries, c.Async.CallbackBackoffMs, c.Async.CallbackTimeoutSec)
+		}
+		if c.Async.MaxInputs < 1 || c.Async.MaxInlineInputs < 0 || c.Async.JobTTLSec < 1 {
+			return fmt.Errorf("invalid async limits: max_inputs %d, max_inline_inputs %d, job_ttl_sec %d",
+				c.Async.MaxInputs, c.Async.MaxInlineInputs, c.Async.JobTTLSec)
+		}
+		if c.Async.MaxInlineInputs < c.Async.MaxInputs {
+			if u, err := url.Parse(c.Async.ResultBaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return fmt.Errorf("async.result_base_url must be an absolute URL when max_inline_inputs (%d) is below max_inputs (%d), got %q",
+					c.Async.MaxInlineInputs, c.Async.MaxInputs, c.Async.ResultBaseURL)
+			}
+		}
+		if c.Async.MaxRunning < 0 || c.Async.MaxRunningPerTenant < 0 {
+			return fmt.Errorf("invalid async running job limits: max_running %d, max_running_per_tenant %d",
+				c.Async.MaxRunning, c.Async.MaxRunningPerTenant)
+		}
+	}
+
+	for i, schedule := range c.Warmup.Schedule {
+		if schedule.Cron == "" {
+			return fmt.Errorf("warmup schedule %d: cron is required", i)
+		}
+		if schedule.TopUsed < 0 {
+			return fmt.Errorf("warmup schedule %d: invalid top_used: %d", i, schedule.TopUsed)
+		}
+		if len(schedule.Inputs) == 0 && schedule.InputsFile == "" && schedule.TopUsed == 0 {
+			return fmt.Errorf("warmup schedule %d: set inputs, inputs_file or top_used", i)
+		}
+	}
+
+	ids := make(map[string]bool)
+	keys := make(map[string]bool)
+	for _, tenant := range c.Tenants {
+		if tenant.ID == "" || tenant.APIKey == "" {
+			return fmt.Errorf("tenant id and api_key are required")
+		}
+		if ids[tenant.ID] {
+			return fmt.Errorf("duplicate tenant id: %s", tenant.ID)
+		}
+		if keys[tenant.APIKey] {
+			return fmt.Errorf("duplicate api_key for tenant %s", tenant.ID)
+		}
+		if tenant.TokenBudget < 0 {
+			return fmt.Errorf("invalid token budget for tenant %s: %d", tenant.ID, tenant.TokenBudget)
+		}
+		ids[tenant.ID] = true
+		keys[tenant.APIKey] = true
+	}
+
+	if c.Logging.SlowRequestMs < 0 {
+		return fmt.Errorf("invalid logging slow request threshold: %d", c.Logging.SlowRequestMs)
+	}
+
 	return nil
 }
 
+func (o *OpenAIConfig) ResolveAPIKey() (string, error) {
+	if key := strings.TrimSpace(os.Getenv("OPENAI_API_KEY")); key != "" {
+		return key, nil
+	}
+
+	if o.APIKeyFile != "" {
+		data, err := os.ReadFile(o.APIKeyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read OpenAI API key file: %w", err)
+		}
+
+		key := strings.TrimRight(string(data), "\r\n")
+		if key == "" {
+			return "", fmt.Errorf("OpenAI API key file %s is empty", o.APIKeyFile)
+		}
+		return key, nil
+	}
+
+	return o.APIKey, nil
+}
+
 func (c *Config) DatabaseDSN() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.Database.Host,
@@ -162,4 +695,18 @@ func (c *Config) ToZapConfig() *zap.Config {
 	}
 
 	return &zapConfig
-}
\ No newline at end of file
+}
+
+// validateExtraHeaders rejects malformed header names and Authorization,
+// which is always derived from api_key.
+func validateExtraHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("invalid extra header %q", name)
+		}
+		if strings.EqualFold(name, "Authorization") {
+			return fmt.Errorf("extra header %q cannot be set; it is derived from api_key", name)
+		}
+	}
+	return nil
+}
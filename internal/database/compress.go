@@ -0,0 +1,51 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxDecompressedVector bounds the decoder so a corrupt row cannot make it
+// allocate without limit; 64 MiB is far above any real vector or matrix.
+const maxDecompressedVector = 64 << 20
+
+type vectorCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newVectorCodec() (*vectorCodec, error) {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedVector))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+
+	return &vectorCodec{encoder: encoder, decoder: decoder}, nil
+}
+
+func (vc *vectorCodec) close() {
+	vc.encoder.Close()
+	vc.decoder.Close()
+}
+
+// storedVectorText returns the serialized vector of a row, decompressing it
+// when the row was written with compression. Rows are readable either way,
+// regardless of the current setting.
+func (db *Database) storedVectorText(text string, compressed []byte) (string, error) {
+	if len(compressed) == 0 {
+		return text, nil
+	}
+
+	decoded, err := db.codec.decoder.DecodeAll(compressed, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to decompress vector: %v", ErrCorruptEmbedding, err)
+	}
+
+	return string(decoded), nil
+}
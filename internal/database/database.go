@@ -2,20 +2,93 @@ package database
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
+const batchLookupChunkSize = 500
+
+const (
+	ConflictOverwrite = "overwrite"
+	ConflictTouch     = "touch"
+	ConflictIgnore    = "ignore"
+)
+
+const insertEmbeddingQuery = `
+	INSERT INTO %s (input_hash, input_text, embedding_vector, model_name, input_length, dimensions, shard, embedding_compressed, used_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+`
+
+var conflictClauses = map[string]string{
+	ConflictOverwrite: `
+	ON CONFLICT (input_hash) DO UPDATE SET
+		embedding_vector = EXCLUDED.embedding_vector,
+		embedding_compressed = EXCLUDED.embedding_compressed,
+		dimensions = EXCLUDED.dimensions,
+		shard = EXCLUDED.shard,
+		created_at = NOW(),
+		updated_at = NOW(),
+		used_at = NOW()
+`,
+	ConflictTouch: `
+	ON CONFLICT (input_hash) DO UPDATE SET
+		used_at = NOW()
+`,
+	ConflictIgnore: `
+	ON CONFLICT (input_hash) DO NOTHING
+`,
+}
+
+func upsertQuery(table, policy string) string {
+	clause, ok := conflictClauses[policy]
+	if !ok {
+		clause = conflictClauses[ConflictOverwrite]
+	}
+	return fmt.Sprintf(insertEmbeddingQuery, pgx.Identifier{table}.Sanitize()) + clause
+}
+
+var ErrOverloaded = errors.New("database overloaded: no connection available")
+
+// ErrCorruptEmbedding marks a stored vector that cannot be trusted, such as
+// one with more elements than Options.MaxDimensions allows.
+var ErrCorruptEmbedding = errors.New("corrupt stored embedding")
+
 type Database struct {
-	pool   *pgxpool.Pool
-	logger *zap.Logger
+	pool        *pgxpool.Pool
+	logger      *zap.Logger
+	options     Options
+	codec       *vectorCodec
+	tables      map[string]bool
+	tablesMutex sync.Mutex
+
+	acquireTimeouts atomic.Int64
+}
+
+type Options struct {
+	AcquireTimeout time.Duration
+	StoreInputText bool
+	ShardCount     int
+	ConflictPolicy string
+	TablePerModel  bool
+
+	ReadRetries      int
+	ReadRetryBackoff time.Duration
+
+	MaxDimensions int // 0 disables the cap
+
+	VectorFormat string // json (default), compressed or binary; applies to new writes
 }
 
 type BatchItem struct {
@@ -23,6 +96,11 @@ type BatchItem struct {
 	Hash   string
 	Index  int
 	Cached *CachedEmbedding
+
+	// Corrupt is set when the stored row exists but cannot be parsed, so
+	// the fresh vector must replace it rather than go through the conflict
+	// policy.
+	Corrupt bool
 }
 
 func (db *Database) Pool() *pgxpool.Pool {
@@ -30,18 +108,31 @@ func (db *Database) Pool() *pgxpool.Pool {
 }
 
 type CachedEmbedding struct {
-	ID              uuid.UUID `json:"id"`
-	InputHash       string    `json:"input_hash"`
-	InputText       string    `json:"input_text"`
-	EmbeddingVector []float64 `json:"embedding_vector"`
-	ModelName       string    `json:"model_name"`
-	InputLength     int       `json:"input_length"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	UsedAt          time.Time `json:"used_at"`
-}
-
-func New(databaseDSN string, logger *zap.Logger) (*Database, error) {
+	ID              uuid.UUID   `json:"id"`
+	InputHash       string      `json:"input_hash"`
+	InputText       string      `json:"input_text"`
+	EmbeddingVector []float64   `json:"embedding_vector"`
+	EmbeddingMatrix [][]float64 `json:"embedding_matrix,omitempty"`
+	ModelName       string      `json:"model_name"`
+	InputLength     int         `json:"input_length"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+	UsedAt          time.Time   `json:"used_at"`
+}
+
+type AuditEntry struct {
+	ClientIP    string
+	APIKeyID    string
+	ModelName   string
+	InputHashes []string
+	BatchSize   int
+	CachedItems int
+	StatusCode  int
+	Latency     time.Duration
+	CreatedAt   time.Time
+}
+
+func New(databaseDSN string, options Options, logger *zap.Logger) (*Database, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -59,9 +150,18 @@ func New(databaseDSN string, logger *zap.Logger) (*Database, error) {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
+	codec, err := newVectorCodec()
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
 	db := &Database{
-		pool:   pool,
-		logger: logger,
+		pool:    pool,
+		logger:  logger,
+		options: options,
+		codec:   codec,
+		tables:  make(map[string]bool),
 	}
 
 	if err := db.ping(ctx); err != nil {
@@ -79,8 +179,80 @@ func (db *Database) ping(ctx context.Context) error {
 	return db.pool.Ping(ctx)
 }
 
+func (db *Database) acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	if db.options.AcquireTimeout <= 0 {
+		return db.pool.Acquire(ctx)
+	}
+
+	acquireCtx, cancel := context.WithTimeout(ctx, db.options.AcquireTimeout)
+	defer cancel()
+
+	conn, err := db.pool.Acquire(acquireCtx)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(acquireCtx.Err(), context.DeadlineExceeded) {
+			db.acquireTimeouts.Add(1)
+			db.logger.Warn("Timed out acquiring database connection",
+				zap.Duration("acquire_timeout", db.options.AcquireTimeout),
+				zap.Int32("max_conns", db.pool.Stat().MaxConns()))
+			return nil, ErrOverloaded
+		}
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (db *Database) inputTextParam(inputText string) interface{} {
+	if !db.options.StoreInputText || inputText == "" {
+		return nil
+	}
+	return inputText
+}
+
+// ShardFor maps a hash to its shard: the first byte of the hash modulo
+// the configured shard count, or -1 when sharding is disabled.
+func (db *Database) ShardFor(inputHash string) int {
+	if db.options.ShardCount <= 0 || len(inputHash) < 2 {
+		return -1
+	}
+
+	firstByte, err := strconv.ParseUint(inputHash[:2], 16, 8)
+	if err != nil {
+		return -1
+	}
+
+	return int(firstByte) % db.options.ShardCount
+}
+
+func (db *Database) shardParam(inputHash string) interface{} {
+	shard := db.ShardFor(inputHash)
+	if shard < 0 {
+		return nil
+	}
+	return shard
+}
+
+func (db *Database) shardsParam(hashes []string) interface{} {
+	if db.options.ShardCount <= 0 {
+		return nil
+	}
+
+	seen := make(map[int]bool)
+	shards := make([]int16, 0, db.options.ShardCount)
+	for _, inputHash := range hashes {
+		shard := db.ShardFor(inputHash)
+		if shard >= 0 && !seen[shard] {
+			seen[shard] = true
+			shards = append(shards, int16(shard))
+		}
+	}
+
+	return shards
+}
+
 func (db *Database) Close() {
 	db.pool.Close()
+	db.codec.close()
 	db.logger.Info("Database connection pool closed")
 }
 
@@ -133,21 +305,48 @@ func (db *Database) executeSQL(ctx context.Context, sql string) error {
 	return nil
 }
 
-func (db *Database) GetCachedEmbedding(ctx context.Context, inputHash string) (*CachedEmbedding, error) {
+// GetCachedEmbedding returns nil without a row. A row that cannot be parsed
+// returns an error wrapping ErrCorruptEmbedding, which callers treat as a
+// miss whose fresh vector replaces the row.
+func (db *Database) GetCachedEmbedding(ctx context.Context, inputHash, modelName string) (*CachedEmbedding, error) {
+	var embedding *CachedEmbedding
+	err := db.withReadRetry(ctx, "get_cached_embedding", func() error {
+		var err error
+		embedding, err = db.getCachedEmbedding(ctx, inputHash, modelName)
+		return err
+	})
+	return embedding, err
+}
+
+func (db *Database) getCachedEmbedding(ctx context.Context, inputHash, modelName string) (*CachedEmbedding, error) {
 	var embedding CachedEmbedding
 	var embeddingVectorJSON string
+	var embeddingCompressed []byte
 
-	query := `
-		SELECT id, input_hash, input_text, embedding_vector, model_name, input_length, created_at, updated_at, used_at
-		FROM embedding_cache
+	table, err := db.tableFor(ctx, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, input_hash, COALESCE(input_text, ''), COALESCE(embedding_vector::text, ''), embedding_compressed, model_name, input_length, created_at, updated_at, used_at
+		FROM %s
 		WHERE input_hash = $1
-	`
+		  AND ($2::smallint IS NULL OR shard IS NULL OR shard = $2)
+	`, pgx.Identifier{table}.Sanitize())
 
-	err := db.pool.QueryRow(ctx, query, inputHash).Scan(
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, query, inputHash, db.shardParam(inputHash)).Scan(
 		&embedding.ID,
 		&embedding.InputHash,
 		&embedding.InputText,
 		&embeddingVectorJSON,
+		&embeddingCompressed,
 		&embedding.ModelName,
 		&embedding.InputLength,
 		&embedding.CreatedAt,
@@ -156,54 +355,113 @@ func (db *Database) GetCachedEmbedding(ctx context.Context, inputHash string) (*
 	)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to query cached embedding: %w", err)
 	}
 
-	if err := db.parseEmbeddingVector(embeddingVectorJSON, &embedding.EmbeddingVector); err != nil {
+	if err := db.parseStoredEmbedding(embeddingVectorJSON, embeddingCompressed, &embedding.EmbeddingVector, &embedding.EmbeddingMatrix); err != nil {
+		if errors.Is(err, ErrCorruptEmbedding) {
+			db.logger.Warn("Ignoring corrupt cached embedding",
+				zap.String("input_hash", inputHash),
+				zap.Error(err))
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to parse embedding vector: %w", err)
 	}
 
 	return &embedding, nil
 }
 
-func (db *Database) GetBatchCachedEmbeddings(ctx context.Context, batchItems []*BatchItem) ([]*BatchItem, error) {
+func (db *Database) GetBatchCachedEmbeddings(ctx context.Context, batchItems []*BatchItem, modelName string) ([]*BatchItem, error) {
 	if len(batchItems) == 0 {
 		return batchItems, nil
 	}
 
-	hashes := make([]string, len(batchItems))
-	hashToItem := make(map[string]*BatchItem)
+	table, err := db.tableFor(ctx, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(batchItems))
+	hashToItems := make(map[string][]*BatchItem)
 
-	for i, item := range batchItems {
-		hashes[i] = item.Hash
-		hashToItem[item.Hash] = item
+	for _, item := range batchItems {
+		if _, seen := hashToItems[item.Hash]; !seen {
+			hashes = append(hashes, item.Hash)
+		}
+		hashToItems[item.Hash] = append(hashToItems[item.Hash], item)
 	}
 
-	query := `
-		SELECT id, input_hash, input_text, embedding_vector, model_name, input_length, created_at, updated_at, used_at
-		FROM embedding_cache
+	for start := 0; start < len(hashes); start += batchLookupChunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("batch cache lookup cancelled: %w", err)
+		}
+
+		end := min(start+batchLookupChunkSize, len(hashes))
+
+		var embeddings []*CachedEmbedding
+		var corrupt []string
+		err := db.withReadRetry(ctx, "get_batch_cached_embeddings", func() error {
+			var err error
+			embeddings, corrupt, err = db.queryCachedEmbeddings(ctx, table, hashes[start:end])
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, embedding := range embeddings {
+			for _, item := range hashToItems[embedding.InputHash] {
+				item.Cached = embedding
+			}
+		}
+		for _, hash := range corrupt {
+			for _, item := range hashToItems[hash] {
+				item.Corrupt = true
+			}
+		}
+	}
+
+	return batchItems, nil
+}
+
+// queryCachedEmbeddings also returns the hashes of rows that could not be
+// parsed, which are left out of the embeddings.
+func (db *Database) queryCachedEmbeddings(ctx context.Context, table string, hashes []string) ([]*CachedEmbedding, []string, error) {
+	query := fmt.Sprintf(`
+		SELECT id, input_hash, COALESCE(input_text, ''), COALESCE(embedding_vector::text, ''), embedding_compressed, model_name, input_length, created_at, updated_at, used_at
+		FROM %s
 		WHERE input_hash = ANY($1)
-	`
+		  AND ($2::smallint[] IS NULL OR shard IS NULL OR shard = ANY($2))
+	`, pgx.Identifier{table}.Sanitize())
 
-	rows, err := db.pool.Query(ctx, query, hashes)
+	conn, err := db.acquire(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query batch cached embeddings: %w", err)
+		return nil, nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, query, hashes, db.shardsParam(hashes))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query batch cached embeddings: %w", err)
 	}
 	defer rows.Close()
 
 	var embeddings []*CachedEmbedding
+	var corrupt []string
 	for rows.Next() {
 		var embedding CachedEmbedding
 		var embeddingVectorJSON string
+		var embeddingCompressed []byte
 
 		err := rows.Scan(
 			&embedding.ID,
 			&embedding.InputHash,
 			&embedding.InputText,
 			&embeddingVectorJSON,
+			&embeddingCompressed,
 			&embedding.ModelName,
 			&embedding.InputLength,
 			&embedding.CreatedAt,
@@ -212,45 +470,59 @@ func (db *Database) GetBatchCachedEmbeddings(ctx context.Context, batchItems []*
 		)
 
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan cached embedding: %w", err)
+			return nil, nil, fmt.Errorf("failed to scan cached embedding: %w", err)
 		}
 
-		if err := db.parseEmbeddingVector(embeddingVectorJSON, &embedding.EmbeddingVector); err != nil {
-			return nil, fmt.Errorf("failed to parse embedding vector: %w", err)
+		if err := db.parseStoredEmbedding(embeddingVectorJSON, embeddingCompressed, &embedding.EmbeddingVector, &embedding.EmbeddingMatrix); err != nil {
+			if errors.Is(err, ErrCorruptEmbedding) {
+				db.logger.Warn("Ignoring corrupt cached embedding",
+					zap.String("input_hash", embedding.InputHash),
+					zap.Error(err))
+				corrupt = append(corrupt, embedding.InputHash)
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to parse embedding vector: %w", err)
 		}
 
 		embeddings = append(embeddings, &embedding)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating batch results: %w", err)
+		return nil, nil, fmt.Errorf("error iterating batch results: %w", err)
 	}
 
-	for _, embedding := range embeddings {
-		if item, exists := hashToItem[embedding.InputHash]; exists {
-			item.Cached = embedding
-		}
-	}
-
-	return batchItems, nil
+	return embeddings, corrupt, nil
 }
 
+// StoreEmbedding caches a freshly computed vector. When the hash already
+// exists, the configured conflict policy decides whether it is rewritten.
 func (db *Database) StoreEmbedding(ctx context.Context, inputHash, inputText, modelName string, embeddingVector []float64) error {
-	embeddingJSON, err := db.serializeEmbeddingVector(embeddingVector)
+	return db.storeEmbedding(ctx, db.options.ConflictPolicy, inputHash, inputText, modelName, embeddingVector)
+}
+
+// ReplaceEmbedding always overwrites an existing vector, for refreshes.
+func (db *Database) ReplaceEmbedding(ctx context.Context, inputHash, inputText, modelName string, embeddingVector []float64) error {
+	return db.storeEmbedding(ctx, ConflictOverwrite, inputHash, inputText, modelName, embeddingVector)
+}
+
+func (db *Database) storeEmbedding(ctx context.Context, policy, inputHash, inputText, modelName string, embeddingVector []float64) error {
+	vectorText, vectorBlob, err := db.encodeVector(embeddingVector, nil, db.options.VectorFormat)
 	if err != nil {
-		return fmt.Errorf("failed to serialize embedding vector: %w", err)
+		return err
 	}
 
-	query := `
-		INSERT INTO embedding_cache (input_hash, input_text, embedding_vector, model_name, input_length, used_at)
-		VALUES ($1, $2, $3, $4, $5, NOW())
-		ON CONFLICT (input_hash) DO UPDATE SET
-			embedding_vector = EXCLUDED.embedding_vector,
-			updated_at = NOW(),
-			used_at = NOW()
-	`
+	table, err := db.tableFor(ctx, modelName)
+	if err != nil {
+		return err
+	}
 
-	_, err = db.pool.Exec(ctx, query, inputHash, inputText, embeddingJSON, modelName, len(inputText))
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, upsertQuery(table, policy), inputHash, db.inputTextParam(inputText), vectorText, modelName, len(inputText), len(embeddingVector), db.shardParam(inputHash), vectorBlob)
 	if err != nil {
 		return fmt.Errorf("failed to store embedding: %w", err)
 	}
@@ -258,24 +530,324 @@ func (db *Database) StoreEmbedding(ctx context.Context, inputHash, inputText, mo
 	db.logger.Info("Stored embedding in cache",
 		zap.String("input_hash", inputHash),
 		zap.String("model", modelName),
+		zap.String("conflict_policy", policy),
 		zap.Int("vector_length", len(embeddingVector)))
 
 	return nil
 }
 
+func (db *Database) StoreEmbeddingMatrix(ctx context.Context, inputHash, inputText, modelName string, matrix [][]float64) error {
+	return db.storeEmbeddingMatrix(ctx, db.options.ConflictPolicy, inputHash, inputText, modelName, matrix)
+}
+
+func (db *Database) ReplaceEmbeddingMatrix(ctx context.Context, inputHash, inputText, modelName string, matrix [][]float64) error {
+	return db.storeEmbeddingMatrix(ctx, ConflictOverwrite, inputHash, inputText, modelName, matrix)
+}
+
+func (db *Database) storeEmbeddingMatrix(ctx context.Context, policy, inputHash, inputText, modelName string, matrix [][]float64) error {
+	if len(matrix) == 0 {
+		return fmt.Errorf("embedding matrix cannot be empty")
+	}
+
+	vectorText, vectorBlob, err := db.encodeVector(nil, matrix, db.options.VectorFormat)
+	if err != nil {
+		return err
+	}
+
+	table, err := db.tableFor(ctx, modelName)
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, upsertQuery(table, policy), inputHash, db.inputTextParam(inputText), vectorText, modelName, len(inputText), len(matrix[0]), db.shardParam(inputHash), vectorBlob)
+	if err != nil {
+		return fmt.Errorf("failed to store embedding matrix: %w", err)
+	}
+
+	db.logger.Info("Stored embedding matrix in cache",
+		zap.String("input_hash", inputHash),
+		zap.String("model", modelName),
+		zap.String("conflict_policy", policy),
+		zap.Int("vector_count", len(matrix)))
+
+	return nil
+}
+
+func (db *Database) ImportEmbeddings(ctx context.Context, embeddings []*CachedEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, embedding := range embeddings {
+		vectorText, vectorBlob, err := db.encodeVector(embedding.EmbeddingVector, embedding.EmbeddingMatrix, db.options.VectorFormat)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", embedding.InputHash, err)
+		}
+		dimensions := len(embedding.EmbeddingVector)
+		inputLength := len(embedding.InputText)
+		if inputLength == 0 {
+			inputLength = embedding.InputLength
+		}
+
+		if len(embedding.EmbeddingMatrix) > 0 {
+			dimensions = len(embedding.EmbeddingMatrix[0])
+		}
+
+		table, err := db.tableFor(ctx, embedding.ModelName)
+		if err != nil {
+			return err
+		}
+
+		batch.Queue(upsertQuery(table, ConflictOverwrite),
+			embedding.InputHash,
+			db.inputTextParam(embedding.InputText),
+			vectorText,
+			embedding.ModelName,
+			inputLength,
+			dimensions,
+			db.shardParam(embedding.InputHash),
+			vectorBlob)
+	}
+
+	if err := db.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to import embeddings: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) ExportEmbeddings(ctx context.Context, fn func(*CachedEmbedding) error) error {
+	tables, err := db.cacheTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if err := db.exportTable(ctx, table, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *Database) exportTable(ctx context.Context, table string, fn func(*CachedEmbedding) error) error {
+	query := fmt.Sprintf(`
+		SELECT id, input_hash, COALESCE(input_text, ''), COALESCE(embedding_vector::text, ''), embedding_compressed, model_name, input_length, created_at, updated_at, used_at
+		FROM %s
+		ORDER BY created_at
+	`, pgx.Identifier{table}.Sanitize())
+
+	rows, err := db.pool.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to query embeddings for export: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var embedding CachedEmbedding
+		var embeddingVectorJSON string
+		var embeddingCompressed []byte
+
+		err := rows.Scan(
+			&embedding.ID,
+			&embedding.InputHash,
+			&embedding.InputText,
+			&embeddingVectorJSON,
+			&embeddingCompressed,
+			&embedding.ModelName,
+			&embedding.InputLength,
+			&embedding.CreatedAt,
+			&embedding.UpdatedAt,
+			&embedding.UsedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan embedding for export: %w", err)
+		}
+
+		if err := db.parseStoredEmbedding(embeddingVectorJSON, embeddingCompressed, &embedding.EmbeddingVector, &embedding.EmbeddingMatrix); err != nil {
+			return fmt.Errorf("failed to parse embedding vector: %w", err)
+		}
+
+		if err := fn(&embedding); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating export rows: %w", err)
+	}
+
+	return nil
+}
+
+type VerifyRow struct {
+	ID              uuid.UUID
+	Table           string
+	InputHash       string
+	InputText       string
+	ModelName       string
+	InputLength     int
+	Dimensions      *int
+	Shard           *int
+	EmbeddingVector []float64
+	EmbeddingMatrix [][]float64
+	ParseErr        error
+}
+
+func (db *Database) ScanForVerify(ctx context.Context, fn func(*VerifyRow) error) error {
+	tables, err := db.cacheTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if err := db.scanTableForVerify(ctx, table, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *Database) scanTableForVerify(ctx context.Context, table string, fn func(*VerifyRow) error) error {
+	query := fmt.Sprintf(`
+		SELECT id, input_hash, COALESCE(input_text, ''), COALESCE(embedding_vector::text, ''), embedding_compressed, model_name, input_length, dimensions, shard
+		FROM %s
+		ORDER BY created_at
+	`, pgx.Identifier{table}.Sanitize())
+
+	rows, err := db.pool.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to query embeddings for verify: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := VerifyRow{Table: table}
+		var embeddingVectorJSON string
+		var embeddingCompressed []byte
+
+		err := rows.Scan(
+			&row.ID,
+			&row.InputHash,
+			&row.InputText,
+			&embeddingVectorJSON,
+			&embeddingCompressed,
+			&row.ModelName,
+			&row.InputLength,
+			&row.Dimensions,
+			&row.Shard,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan embedding for verify: %w", err)
+		}
+
+		row.ParseErr = db.parseStoredEmbedding(embeddingVectorJSON, embeddingCompressed, &row.EmbeddingVector, &row.EmbeddingMatrix)
+
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating verify rows: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) UpdateEmbeddingMetadata(ctx context.Context, row *VerifyRow, inputLength, dimensions int) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET input_length = $2, dimensions = $3, shard = $4
+		WHERE id = $1
+	`, pgx.Identifier{row.Table}.Sanitize())
+
+	if _, err := db.pool.Exec(ctx, query, row.ID, inputLength, dimensions, db.shardParam(row.InputHash)); err != nil {
+		return fmt.Errorf("failed to update embedding metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) ShardOutdated(row *VerifyRow) bool {
+	shard := db.ShardFor(row.InputHash)
+	if shard < 0 {
+		return row.Shard != nil
+	}
+	return row.Shard == nil || *row.Shard != shard
+}
+
+// UsedInput is a stored text and the key it was cached under.
+type UsedInput struct {
+	InputHash string
+	InputText string
+}
+
+// RecentlyUsedInputs returns the stored texts of the model's most recently
+// used entries. Entries cached without their text are skipped.
+func (db *Database) RecentlyUsedInputs(ctx context.Context, modelName string, limit int) ([]UsedInput, error) {
+	table, err := db.tableFor(ctx, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT input_hash, input_text
+		FROM %s
+		WHERE model_name = $1 AND input_text IS NOT NULL
+		ORDER BY used_at DESC
+		LIMIT $2
+	`, pgx.Identifier{table}.Sanitize())
+
+	rows, err := db.pool.Query(ctx, query, modelName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recently used inputs: %w", err)
+	}
+
+	inputs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (UsedInput, error) {
+		var input UsedInput
+		err := row.Scan(&input.InputHash, &input.InputText)
+		return input, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recently used inputs: %w", err)
+	}
+
+	return inputs, nil
+}
+
 func (db *Database) GetCacheStats(ctx context.Context) (map[string]int64, error) {
-	query := `
+	tables, err := db.cacheTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	selects := make([]string, len(tables))
+	for i, table := range tables {
+		selects[i] = fmt.Sprintf("SELECT model_name, input_length FROM %s", pgx.Identifier{table}.Sanitize())
+	}
+
+	query := fmt.Sprintf(`
 		SELECT
 			COUNT(*) as total_entries,
 			COUNT(DISTINCT model_name) as unique_models,
-			AVG(input_length) as avg_input_length
-		FROM embedding_cache
-	`
+			COALESCE(AVG(input_length), 0) as avg_input_length
+		FROM (%s) AS entries
+	`, strings.Join(selects, " UNION ALL "))
 
 	var totalEntries, uniqueModels int64
 	var avgInputLength float64
 
-	err := db.pool.QueryRow(ctx, query).Scan(&totalEntries, &uniqueModels, &avgInputLength)
+	err = db.pool.QueryRow(ctx, query).Scan(&totalEntries, &uniqueModels, &avgInputLength)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cache stats: %w", err)
 	}
@@ -289,10 +861,67 @@ func (db *Database) GetCacheStats(ctx context.Context) (map[string]int64, error)
 	return stats, nil
 }
 
+func (db *Database) RecordAudit(ctx context.Context, entries ...*AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	rows := make([][]interface{}, len(entries))
+	for i, entry := range entries {
+		var apiKeyID interface{}
+		if entry.APIKeyID != "" {
+			apiKeyID = entry.APIKeyID
+		}
+
+		rows[i] = []interface{}{
+			entry.ClientIP,
+			apiKeyID,
+			entry.ModelName,
+			entry.InputHashes,
+			entry.BatchSize,
+			entry.CachedItems,
+			entry.StatusCode,
+			int(entry.Latency.Milliseconds()),
+			entry.CreatedAt,
+		}
+	}
+
+	_, err := db.pool.CopyFrom(ctx,
+		pgx.Identifier{"audit_log"},
+		[]string{"client_ip", "api_key_id", "model_name", "input_hashes", "batch_size", "cached_items", "status_code", "latency_ms", "created_at"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entries: %w", err)
+	}
+
+	return nil
+}
+
 func (db *Database) serializeEmbeddingVector(vector []float64) (string, error) {
 	return "[" + strings.Trim(strings.Replace(fmt.Sprint(vector), " ", ",", -1), "[]") + "]", nil
 }
 
+func (db *Database) parseStoredEmbedding(jsonStr string, compressed []byte, vector *[]float64, matrix *[][]float64) error {
+	if storedVectorFormat(compressed) == VectorFormatBinary {
+		return db.decodeBinaryVector(compressed, vector, matrix)
+	}
+
+	jsonStr, err := db.storedVectorText(jsonStr, compressed)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(jsonStr), "[[") {
+		if err := json.Unmarshal([]byte(jsonStr), matrix); err != nil {
+			return fmt.Errorf("invalid embedding matrix: %w", err)
+		}
+		return nil
+	}
+
+	return db.parseEmbeddingVector(jsonStr, vector)
+}
+
 func (db *Database) parseEmbeddingVector(jsonStr string, vector *[]float64) error {
 	jsonStr = strings.TrimSpace(jsonStr)
 	if len(jsonStr) == 0 {
@@ -308,6 +937,12 @@ func (db *Database) parseEmbeddingVector(jsonStr string, vector *[]float64) erro
 		return nil
 	}
 
+	if limit := db.options.MaxDimensions; limit > 0 {
+		if n := strings.Count(jsonStr, ",") + 1; n > limit {
+			return fmt.Errorf("%w: %d elements exceeds max dimensions %d", ErrCorruptEmbedding, n, limit)
+		}
+	}
+
 	parts := strings.Split(jsonStr, ",")
 	*vector = make([]float64, len(parts))
 
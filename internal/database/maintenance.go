@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type VacuumResult struct {
+	Table            string   `json:"table"`
+	VacuumMs         int64    `json:"vacuum_ms"`
+	ReindexedIndexes []string `json:"reindexed_indexes,omitempty"`
+	ReindexMs        int64    `json:"reindex_ms,omitempty"`
+}
+
+// Vacuum runs VACUUM ANALYZE on every cache table and, with reindex set,
+// rebuilds their vector indexes concurrently. Both statements refuse to run
+// inside a transaction block, so they are sent on a dedicated connection
+// with the simple protocol.
+func (db *Database) Vacuum(ctx context.Context, reindex bool) ([]VacuumResult, error) {
+	tables, err := db.cacheTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection for maintenance: %w", err)
+	}
+	defer conn.Release()
+
+	results := make([]VacuumResult, 0, len(tables))
+	for _, table := range tables {
+		result := VacuumResult{Table: table}
+
+		start := time.Now()
+		query := "VACUUM (ANALYZE) " + pgx.Identifier{table}.Sanitize()
+		if _, err := conn.Exec(ctx, query, pgx.QueryExecModeSimpleProtocol); err != nil {
+			return results, fmt.Errorf("failed to vacuum %s: %w", table, err)
+		}
+		result.VacuumMs = time.Since(start).Milliseconds()
+
+		if reindex {
+			start = time.Now()
+			indexes, err := db.vectorIndexes(ctx, table)
+			if err != nil {
+				return results, err
+			}
+
+			for _, index := range indexes {
+				query := "REINDEX INDEX CONCURRENTLY " + pgx.Identifier{index}.Sanitize()
+				if _, err := conn.Exec(ctx, query, pgx.QueryExecModeSimpleProtocol); err != nil {
+					return results, fmt.Errorf("failed to reindex %s: %w", index, err)
+				}
+				result.ReindexedIndexes = append(result.ReindexedIndexes, index)
+			}
+			result.ReindexMs = time.Since(start).Milliseconds()
+		}
+
+		db.logger.Info("Cache table maintenance completed",
+			zap.String("table", table),
+			zap.Int64("vacuum_ms", result.VacuumMs),
+			zap.Int("reindexed_indexes", len(result.ReindexedIndexes)),
+			zap.Int64("reindex_ms", result.ReindexMs))
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (db *Database) vectorIndexes(ctx context.Context, table string) ([]string, error) {
+	query := `
+		SELECT indexname
+		FROM pg_indexes
+		WHERE schemaname = current_schema()
+		  AND tablename = $1
+		  AND (indexdef ILIKE '%USING hnsw%' OR indexdef ILIKE '%USING ivfflat%')
+		ORDER BY indexname
+	`
+
+	rows, err := db.pool.Query(ctx, query, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vector indexes on %s: %w", table, err)
+	}
+
+	indexes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vector indexes on %s: %w", table, err)
+	}
+
+	return indexes, nil
+}
@@ -0,0 +1,40 @@
+package database
+
+type PoolStats struct {
+	AcquiredConns        int32   `json:"acquired_conns"`
+	IdleConns            int32   `json:"idle_conns"`
+	TotalConns           int32   `json:"total_conns"`
+	MaxConns             int32   `json:"max_conns"`
+	AcquireCount         int64   `json:"acquire_count"`
+	AcquireDurationMs    float64 `json:"acquire_duration_ms"`     // total time spent waiting, all acquires
+	AvgAcquireDurationMs float64 `json:"avg_acquire_duration_ms"` // per acquire
+	EmptyAcquireCount    int64   `json:"empty_acquire_count"`     // acquires that had to wait for a connection
+	CanceledAcquireCount int64   `json:"canceled_acquire_count"`
+	AcquireTimeouts      int64   `json:"acquire_timeouts"` // acquires that hit database.acquire_timeout_ms
+	AcquireTimeoutMs     int64   `json:"acquire_timeout_ms"`
+}
+
+// PoolStats reports connection pool usage. A growing empty_acquire_count or
+// acquire_timeouts points at database contention rather than provider latency.
+func (db *Database) PoolStats() PoolStats {
+	stat := db.pool.Stat()
+
+	stats := PoolStats{
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		TotalConns:           stat.TotalConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		AcquireDurationMs:    float64(stat.AcquireDuration().Microseconds()) / 1000,
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireTimeouts:      db.acquireTimeouts.Load(),
+		AcquireTimeoutMs:     db.options.AcquireTimeout.Milliseconds(),
+	}
+
+	if stats.AcquireCount > 0 {
+		stats.AvgAcquireDurationMs = stats.AcquireDurationMs / float64(stats.AcquireCount)
+	}
+
+	return stats
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// DeleteByUsedBefore removes entries that have not been used since t, across
+// every cache table, and returns how many were deleted.
+func (db *Database) DeleteByUsedBefore(ctx context.Context, t time.Time) (int64, error) {
+	return db.deleteBefore(ctx, "used_at", t)
+}
+
+// DeleteByCreatedBefore removes entries created before t.
+func (db *Database) DeleteByCreatedBefore(ctx context.Context, t time.Time) (int64, error) {
+	return db.deleteBefore(ctx, "created_at", t)
+}
+
+func (db *Database) deleteBefore(ctx context.Context, column string, t time.Time) (int64, error) {
+	tables, err := db.cacheTables(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, table := range tables {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE %s < $1`,
+			pgx.Identifier{table}.Sanitize(),
+			pgx.Identifier{column}.Sanitize())
+
+		tag, err := db.pool.Exec(ctx, query, t)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete entries from %s: %w", table, err)
+		}
+
+		deleted += tag.RowsAffected()
+		if tag.RowsAffected() > 0 {
+			db.logger.Info("Deleted cache entries",
+				zap.String("table", table),
+				zap.String("column", column),
+				zap.Time("before", t),
+				zap.Int64("deleted", tag.RowsAffected()))
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type ReformatResult struct {
+	Table     string `json:"table"`
+	Rewritten int    `json:"rewritten"`
+	Skipped   int    `json:"skipped"` // changed by a live write since they were read
+	Failed    int    `json:"failed"`
+}
+
+// reformatFilters select the rows not yet in a format; $3, where used, is
+// the binary magic prefix.
+var reformatFilters = map[string]string{
+	VectorFormatJSON:       `embedding_compressed IS NOT NULL`,
+	VectorFormatCompressed: `(embedding_compressed IS NULL OR substring(embedding_compressed from 1 for 4) = $3)`,
+	VectorFormatBinary:     `(embedding_compressed IS NULL OR substring(embedding_compressed from 1 for 4) <> $3)`,
+}
+
+// Reformat rewrites every row not stored in format (the configured one when
+// empty), batchSize rows per transaction, so a live cache can move to a new
+// format without downtime. Rows that cannot be parsed are counted as failed
+// and left as they are.
+func (db *Database) Reformat(ctx context.Context, format string, batchSize int) ([]ReformatResult, error) {
+	if format == "" {
+		format = db.options.VectorFormat
+	}
+	if format == "" {
+		format = VectorFormatJSON
+	}
+
+	filter, ok := reformatFilters[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid vector format: %q (expected json, compressed or binary)", format)
+	}
+
+	tables, err := db.cacheTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows moved to embedding_compressed drop out of the pgvector
+	// expression indexes, so a reformat would silently empty them.
+	if format != VectorFormatJSON {
+		for _, table := range tables {
+			indexes, err := db.vectorIndexes(ctx, table)
+			if err != nil {
+				return nil, err
+			}
+			if len(indexes) > 0 {
+				return nil, fmt.Errorf("table %s has vector indexes (%s) that %s rows would drop out of; drop them first or keep the json format",
+					table, strings.Join(indexes, ", "), format)
+			}
+		}
+	}
+
+	results := make([]ReformatResult, 0, len(tables))
+	for _, table := range tables {
+		result, err := db.reformatTable(ctx, table, filter, format, batchSize)
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+func (db *Database) reformatTable(ctx context.Context, table, filter, format string, batchSize int) (ReformatResult, error) {
+	result := ReformatResult{Table: table}
+	identifier := pgx.Identifier{table}.Sanitize()
+
+	selectQuery := fmt.Sprintf(`
+		SELECT id, COALESCE(embedding_vector::text, ''), embedding_compressed
+		FROM %s
+		WHERE id > $1 AND %s
+		ORDER BY id
+		LIMIT $2
+	`, identifier, filter)
+	// A row overwritten between the read and the write is left alone: the
+	// live write is newer, and is already in the configured format.
+	updateQuery := fmt.Sprintf(`
+		UPDATE %s SET embedding_vector = $2, embedding_compressed = $3
+		WHERE id = $1
+		  AND embedding_vector IS NOT DISTINCT FROM $4::jsonb
+		  AND embedding_compressed IS NOT DISTINCT FROM $5
+	`, identifier)
+
+	var cursor uuid.UUID
+	for {
+		args := []interface{}{cursor, batchSize}
+		if format != VectorFormatJSON {
+			args = append(args, binaryVectorMagic)
+		}
+
+		rows, err := db.pool.Query(ctx, selectQuery, args...)
+		if err != nil {
+			return result, fmt.Errorf("failed to query %s for reformat: %w", table, err)
+		}
+
+		batch := &pgx.Batch{}
+		seen := 0
+		for rows.Next() {
+			var id uuid.UUID
+			var text string
+			var compressed []byte
+			if err := rows.Scan(&id, &text, &compressed); err != nil {
+				rows.Close()
+				return result, fmt.Errorf("failed to scan row for reformat: %w", err)
+			}
+			seen++
+			cursor = id
+
+			var vector []float64
+			var matrix [][]float64
+			if err := db.parseStoredEmbedding(text, compressed, &vector, &matrix); err != nil {
+				result.Failed++
+				db.logger.Warn("Skipping unreadable row during reformat",
+					zap.String("table", table),
+					zap.String("id", id.String()),
+					zap.Error(err))
+				continue
+			}
+
+			vectorText, vectorBlob, err := db.encodeVector(vector, matrix, format)
+			if err != nil {
+				result.Failed++
+				db.logger.Warn("Skipping invalid row during reformat",
+					zap.String("table", table),
+					zap.String("id", id.String()),
+					zap.Error(err))
+				continue
+			}
+			var original interface{}
+			if text != "" {
+				original = text
+			}
+			batch.Queue(updateQuery, id, vectorText, vectorBlob, original, compressed)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return result, fmt.Errorf("failed to read rows for reformat: %w", err)
+		}
+
+		if batch.Len() > 0 {
+			results := db.pool.SendBatch(ctx, batch)
+			for range batch.Len() {
+				tag, err := results.Exec()
+				if err != nil {
+					results.Close()
+					return result, fmt.Errorf("failed to rewrite rows in %s: %w", table, err)
+				}
+				if tag.RowsAffected() == 0 {
+					result.Skipped++
+				} else {
+					result.Rewritten++
+				}
+			}
+			if err := results.Close(); err != nil {
+				return result, fmt.Errorf("failed to rewrite rows in %s: %w", table, err)
+			}
+
+			db.logger.Info("Reformatted cache rows",
+				zap.String("table", table),
+				zap.String("format", format),
+				zap.Int("rewritten", result.Rewritten))
+		}
+
+		if seen < batchSize {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// isConnectionError reports whether err came from losing or failing to
+// reach the server, as opposed to a problem with the query itself.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, ErrOverloaded) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is connection exception; 57P01-57P03 are sent while the
+		// server shuts down or is not yet accepting connections.
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		pgconn.SafeToRetry(err)
+}
+
+// withReadRetry runs a read, retrying connection errors up to
+// Options.ReadRetries times with linear backoff.
+func (db *Database) withReadRetry(ctx context.Context, operation string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= db.options.ReadRetries || !isConnectionError(err) {
+			return err
+		}
+
+		backoff := time.Duration(attempt+1) * db.options.ReadRetryBackoff
+		db.logger.Warn("Retrying cache read after connection error",
+			zap.String("operation", operation),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
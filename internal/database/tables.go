@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const defaultCacheTable = "embedding_cache"
+
+// tableName maps a model to its cache table. With per-model tables enabled
+// every model gets embedding_cache_<model>, with non-alphanumerics replaced
+// by underscores. Names too long for Postgres are shortened by
+// shortIdentifier.
+func (db *Database) tableName(modelName string) string {
+	if !db.options.TablePerModel || modelName == "" {
+		return defaultCacheTable
+	}
+
+	var suffix strings.Builder
+	for _, r := range strings.ToLower(modelName) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			suffix.WriteRune(r)
+		} else {
+			suffix.WriteRune('_')
+		}
+	}
+
+	return shortIdentifier(defaultCacheTable+"_"+suffix.String(), "")
+}
+
+// maxIdentifierLength is Postgres' limit; longer identifiers are silently
+// truncated, so names sharing their first 63 bytes would collide.
+const maxIdentifierLength = 63
+
+// shortIdentifier returns base+suffix, or when that is too long, base cut
+// short and followed by a hash of the full name, then suffix.
+func shortIdentifier(base, suffix string) string {
+	name := base + suffix
+	if len(name) <= maxIdentifierLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	hash := "_" + hex.EncodeToString(sum[:4])
+	return base[:maxIdentifierLength-len(hash)-len(suffix)] + hash + suffix
+}
+
+func (db *Database) tableFor(ctx context.Context, modelName string) (string, error) {
+	table := db.tableName(modelName)
+	if table == defaultCacheTable {
+		return table, nil
+	}
+
+	db.tablesMutex.Lock()
+	defer db.tablesMutex.Unlock()
+
+	if db.tables[table] {
+		return table, nil
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (LIKE %s INCLUDING ALL)`,
+		pgx.Identifier{table}.Sanitize(),
+		pgx.Identifier{defaultCacheTable}.Sanitize())
+
+	if _, err := db.pool.Exec(ctx, query); err != nil {
+		return "", fmt.Errorf("failed to create cache table for model %s: %w", modelName, err)
+	}
+
+	// Tables created before the compressed column existed lack it and still
+	// require embedding_vector; those created before migration 008 limit
+	// model_name to 50 characters.
+	query = fmt.Sprintf(`
+		ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS embedding_compressed BYTEA;
+		ALTER TABLE %[1]s ALTER COLUMN embedding_vector DROP NOT NULL;
+		ALTER TABLE %[1]s ALTER COLUMN model_name TYPE TEXT
+	`, pgx.Identifier{table}.Sanitize())
+
+	if _, err := db.pool.Exec(ctx, query); err != nil {
+		return "", fmt.Errorf("failed to upgrade cache table for model %s: %w", modelName, err)
+	}
+
+	db.tables[table] = true
+	db.logger.Info("Using per-model cache table",
+		zap.String("model", modelName),
+		zap.String("table", table))
+
+	return table, nil
+}
+
+func (db *Database) cacheTables(ctx context.Context) ([]string, error) {
+	if !db.options.TablePerModel {
+		return []string{defaultCacheTable}, nil
+	}
+
+	query := `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = current_schema()
+		  AND (table_name = $1 OR table_name LIKE $2)
+		ORDER BY table_name
+	`
+
+	rows, err := db.pool.Query(ctx, query, defaultCacheTable, defaultCacheTable+`\_%`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache tables: %w", err)
+	}
+
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+func (db *Database) TouchEmbeddings(ctx context.Context, ids []string) error {
+	tables, err := db.cacheTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		query := fmt.Sprintf(`
+			UPDATE %s
+			SET used_at = NOW()
+			WHERE id = ANY($1)
+		`, pgx.Identifier{table}.Sanitize())
+
+		if _, err := db.pool.Exec(ctx, query, ids); err != nil {
+			return fmt.Errorf("failed to update usage timestamps in %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
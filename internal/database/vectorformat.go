@@ -0,0 +1,140 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/vectorcheck"
+)
+
+// Storage formats for vectors. Every row records its own: JSON lives in
+// embedding_vector, the others in embedding_compressed, where binary rows
+// start with binaryVectorMagic and compressed rows are zstd frames. Reads
+// detect the format per row, so the configured format can change at any
+// time and old rows are rewritten lazily or by Reformat.
+const (
+	VectorFormatJSON       = "json"
+	VectorFormatCompressed = "compressed"
+	VectorFormatBinary     = "binary"
+)
+
+// binaryVectorMagic prefixes binary rows: then the matrix row count (0 for
+// a single vector) and the dimensions as uint32, then little-endian float64
+// values. The values round-trip exactly.
+var binaryVectorMagic = []byte("MEV1")
+
+const binaryVectorHeader = 12
+
+// encodeVector returns the embedding_vector and embedding_compressed
+// arguments for a vector, or for matrix when it is non-empty. Exactly one of
+// them is non-nil, so an overwrite never leaves a stale copy in the other
+// column. Zero, NaN and Inf vectors are refused, whatever wrote them.
+func (db *Database) encodeVector(vector []float64, matrix [][]float64, format string) (interface{}, interface{}, error) {
+	if len(matrix) > 0 {
+		if err := vectorcheck.ValidateMatrix(matrix); err != nil {
+			return nil, nil, err
+		}
+	} else if err := vectorcheck.Validate(vector); err != nil {
+		return nil, nil, err
+	}
+
+	if format == VectorFormatBinary {
+		return nil, encodeBinaryVector(vector, matrix), nil
+	}
+
+	var serialized string
+	if len(matrix) > 0 {
+		matrixJSON, err := json.Marshal(matrix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to serialize embedding matrix: %w", err)
+		}
+		serialized = string(matrixJSON)
+	} else {
+		vectorJSON, err := db.serializeEmbeddingVector(vector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to serialize embedding vector: %w", err)
+		}
+		serialized = vectorJSON
+	}
+
+	if format == VectorFormatCompressed {
+		return nil, db.codec.encoder.EncodeAll([]byte(serialized), nil), nil
+	}
+	return serialized, nil, nil
+}
+
+// storedVectorFormat reports which format a row was written in.
+func storedVectorFormat(compressed []byte) string {
+	switch {
+	case len(compressed) == 0:
+		return VectorFormatJSON
+	case bytes.HasPrefix(compressed, binaryVectorMagic):
+		return VectorFormatBinary
+	default:
+		return VectorFormatCompressed
+	}
+}
+
+func encodeBinaryVector(vector []float64, matrix [][]float64) []byte {
+	rows, dims := 0, len(vector)
+	values := vector
+	if len(matrix) > 0 {
+		rows, dims = len(matrix), len(matrix[0])
+		values = make([]float64, 0, rows*dims)
+		for _, row := range matrix {
+			values = append(values, row...)
+		}
+	}
+
+	buf := make([]byte, binaryVectorHeader+8*len(values))
+	copy(buf, binaryVectorMagic)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(rows))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(dims))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(buf[binaryVectorHeader+8*i:], math.Float64bits(v))
+	}
+
+	return buf
+}
+
+func (db *Database) decodeBinaryVector(data []byte, vector *[]float64, matrix *[][]float64) error {
+	if len(data) < binaryVectorHeader {
+		return fmt.Errorf("%w: binary vector header truncated", ErrCorruptEmbedding)
+	}
+
+	rows := int(binary.LittleEndian.Uint32(data[4:]))
+	dims := int(binary.LittleEndian.Uint32(data[8:]))
+	if available := (len(data) - binaryVectorHeader) / 8; rows > available || dims > available {
+		return fmt.Errorf("%w: binary vector header does not match its length", ErrCorruptEmbedding)
+	}
+	if limit := db.options.MaxDimensions; limit > 0 && dims > limit {
+		return fmt.Errorf("%w: %d elements exceeds max dimensions %d", ErrCorruptEmbedding, dims, limit)
+	}
+
+	count := dims
+	if rows > 0 {
+		count = rows * dims
+	}
+	if len(data) != binaryVectorHeader+8*count {
+		return fmt.Errorf("%w: binary vector has %d bytes, want %d", ErrCorruptEmbedding, len(data), binaryVectorHeader+8*count)
+	}
+
+	values := make([]float64, count)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[binaryVectorHeader+8*i:]))
+	}
+
+	if rows == 0 {
+		*vector = values
+		return nil
+	}
+
+	*matrix = make([][]float64, rows)
+	for i := range *matrix {
+		(*matrix)[i] = values[i*dims : (i+1)*dims : (i+1)*dims]
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	IndexHNSW    = "hnsw"
+	IndexIVFFlat = "ivfflat"
+)
+
+var vectorOpClasses = map[string]string{
+	"cosine": "vector_cosine_ops",
+	"l2":     "vector_l2_ops",
+	"ip":     "vector_ip_ops",
+}
+
+type VectorIndexParams struct {
+	Method         string
+	Metric         string
+	M              int
+	EfConstruction int
+	Lists          int
+}
+
+func (p *VectorIndexParams) validate() error {
+	if _, ok := vectorOpClasses[p.Metric]; !ok {
+		return fmt.Errorf("invalid vector index metric: %q (expected cosine, l2 or ip)", p.Metric)
+	}
+
+	switch p.Method {
+	case IndexHNSW:
+		if p.M < 2 || p.EfConstruction < 4 {
+			return fmt.Errorf("invalid hnsw parameters: m must be >= 2 and ef_construction >= 4")
+		}
+	case IndexIVFFlat:
+		if p.Lists < 1 {
+			return fmt.Errorf("invalid ivfflat parameters: lists must be >= 1")
+		}
+	default:
+		return fmt.Errorf("invalid vector index method: %q (expected hnsw or ivfflat)", p.Method)
+	}
+
+	return nil
+}
+
+func (p *VectorIndexParams) with() string {
+	if p.Method == IndexHNSW {
+		return fmt.Sprintf("m = %d, ef_construction = %d", p.M, p.EfConstruction)
+	}
+	return fmt.Sprintf("lists = %d", p.Lists)
+}
+
+// EnsureVectorIndex creates a pgvector index for every vector dimension
+// present in the cache. Vectors are stored as JSONB, so each index is a
+// partial expression index casting single-vector rows of one dimension to
+// vector(n). Existing indexes are left untouched.
+func (db *Database) EnsureVectorIndex(ctx context.Context, params VectorIndexParams) error {
+	if err := params.validate(); err != nil {
+		return err
+	}
+
+	if _, err := db.pool.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS vector`); err != nil {
+		return fmt.Errorf("failed to enable pgvector extension: %w", err)
+	}
+
+	tables, err := db.cacheTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		dimensions, err := db.tableDimensions(ctx, table)
+		if err != nil {
+			return err
+		}
+
+		for _, dims := range dimensions {
+			if err := db.createVectorIndex(ctx, table, dims, params); err != nil {
+				return err
+			}
+		}
+
+		if err := db.reportUnindexable(ctx, table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reportUnindexable warns about rows stored in embedding_compressed, which
+// the expression indexes cannot cover.
+func (db *Database) reportUnindexable(ctx context.Context, table string) error {
+	var count int64
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE embedding_compressed IS NOT NULL`, pgx.Identifier{table}.Sanitize())
+	if err := db.pool.QueryRow(ctx, query).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count unindexable rows in %s: %w", table, err)
+	}
+
+	if count > 0 {
+		db.logger.Warn("Rows stored compressed or binary are not in the vector indexes",
+			zap.String("table", table),
+			zap.Int64("excluded_rows", count))
+	}
+
+	return nil
+}
+
+func (db *Database) tableDimensions(ctx context.Context, table string) ([]int, error) {
+	query := fmt.Sprintf(`
+		SELECT DISTINCT dimensions
+		FROM %s
+		WHERE dimensions IS NOT NULL
+		ORDER BY dimensions
+	`, pgx.Identifier{table}.Sanitize())
+
+	rows, err := db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dimensions in %s: %w", table, err)
+	}
+
+	dimensions, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dimensions in %s: %w", table, err)
+	}
+
+	return dimensions, nil
+}
+
+func (db *Database) createVectorIndex(ctx context.Context, table string, dims int, params VectorIndexParams) error {
+	indexName := shortIdentifier(table, fmt.Sprintf("_%s_%s_%d", params.Method, params.Metric, dims))
+
+	query := fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s ON %s
+		USING %s (((embedding_vector::text)::vector(%d)) %s)
+		WITH (%s)
+		WHERE dimensions = %d AND jsonb_typeof(embedding_vector->0) = 'number'
+	`,
+		pgx.Identifier{indexName}.Sanitize(),
+		pgx.Identifier{table}.Sanitize(),
+		params.Method, dims, vectorOpClasses[params.Metric],
+		params.with(),
+		dims)
+
+	db.logger.Info("Ensuring vector index",
+		zap.String("table", table),
+		zap.String("index", indexName),
+		zap.Int("dimensions", dims))
+
+	if _, err := db.pool.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create vector index %s: %w", indexName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package hash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/cespare/xxhash/v2"
+	"lukechampine.com/blake3"
+)
+
+// Hash algorithms for cache keys. Keys need not be cryptographic, so the
+// faster ones trade nothing but compatibility: keys from one algorithm never
+// match another's, and switching starts with an empty cache.
+const (
+	AlgorithmSHA256 = "sha256"
+	AlgorithmXXHash = "xxhash"
+	AlgorithmBLAKE3 = "blake3"
+)
+
+type algorithm struct {
+	sum    func([]byte) string
+	length int // hex characters in a key
+}
+
+var algorithms = map[string]algorithm{
+	AlgorithmSHA256: {sum: sumSHA256, length: 64},
+	AlgorithmXXHash: {sum: sumXXHash, length: 16},
+	AlgorithmBLAKE3: {sum: sumBLAKE3, length: 64},
+}
+
+func sumSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func sumXXHash(data []byte) string {
+	return fmt.Sprintf("%016x", xxhash.Sum64(data))
+}
+
+func sumBLAKE3(data []byte) string {
+	sum := blake3.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -1,54 +1,111 @@
 package hash
 
 import (
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"strings"
 	"unicode"
 
 	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
 )
 
 type Hasher struct {
-	logger *zap.Logger
+	logger             *zap.Logger
+	algorithmName      string
+	algorithm          algorithm
+	namespace          string
+	preserveWhitespace bool
+	transformNames     []string
+	transforms         []func(string) string
 }
 
-func New(logger *zap.Logger) *Hasher {
-	return &Hasher{
-		logger: logger,
+func New(cfg *config.HashConfig, logger *zap.Logger) *Hasher {
+	algorithmName := cfg.Algorithm
+	if _, ok := algorithms[algorithmName]; !ok {
+		algorithmName = AlgorithmSHA256
+	}
+
+	h := &Hasher{
+		logger:             logger,
+		algorithmName:      algorithmName,
+		algorithm:          algorithms[algorithmName],
+		namespace:          cfg.Namespace,
+		preserveWhitespace: cfg.PreserveWhitespace,
+	}
+
+	for _, name := range cfg.Transforms {
+		if transform, ok := transforms[name]; ok {
+			h.transformNames = append(h.transformNames, name)
+			h.transforms = append(h.transforms, transform)
+		}
 	}
+
+	return h
 }
 
-func (h *Hasher) GenerateInputHash(inputText, modelName string) string {
+// GenerateInputHash derives the cache key for an input. Variants mark
+// request options that change the stored vector; with none, keys are
+// unchanged from earlier releases.
+func (h *Hasher) GenerateInputHash(inputText, modelName string, variants ...string) string {
 	normalizedInput := h.normalizeInput(inputText)
 
 	data := fmt.Sprintf("%s|%s", normalizedInput, modelName)
+	if h.namespace != "" {
+		data = fmt.Sprintf("%s|%s", h.namespace, data)
+	}
+	if len(h.transformNames) > 0 {
+		data = fmt.Sprintf("%s|transforms:%s", data, strings.Join(h.transformNames, ","))
+	}
+	for _, variant := range variants {
+		data = fmt.Sprintf("%s|%s", data, variant)
+	}
 
-	hash := sha256.Sum256([]byte(data))
-	hashHex := hex.EncodeToString(hash[:])
+	hashHex := h.algorithm.sum([]byte(data))
 
 	h.logger.Debug("Generated input hash",
 		zap.String("input_preview", h.truncateForLog(normalizedInput, 50)),
 		zap.String("model", modelName),
+		zap.String("namespace", h.namespace),
 		zap.String("hash", hashHex[:16]+"..."),
 		zap.Int("input_length", len(normalizedInput)))
 
 	return hashHex
 }
 
-func (h *Hasher) normalizeInput(input string) string {
-	input = strings.TrimSpace(input)
+// GenerateGroupHash derives the key for a vector built from several cached
+// inputs. Members are sorted, so the key does not depend on their order;
+// each member string should already carry anything that sets it apart,
+// such as a weight.
+func (h *Hasher) GenerateGroupHash(members []string, variant string) string {
+	sorted := append([]string(nil), members...)
+	sort.Strings(sorted)
 
-	input = h.normalizeUnicode(input)
+	data := fmt.Sprintf("group|%s|%s", variant, strings.Join(sorted, ","))
+	return h.algorithm.sum([]byte(data))
+}
+
+// Normalize returns the text a hash represents. Embedding this instead of
+// the raw input keeps every cached vector consistent with its key. It never
+// shortens the text: length limits belong to the model and are applied by
+// the cache.
+func (h *Hasher) Normalize(input string) string {
+	return h.normalizeInput(input)
+}
 
-	input = h.normalizeWhitespace(input)
+func (h *Hasher) normalizeInput(input string) string {
+	for _, transform := range h.transforms {
+		input = transform(input)
+	}
 
-	if len(input) > 10000 {
-		h.logger.Warn("Input text truncated for hashing",
-			zap.Int("original_length", len(input)),
-			zap.Int("truncated_length", 10000))
-		input = input[:10000]
+	if h.preserveWhitespace {
+		input = h.normalizeUnicode(normalizeLineEndings(input))
+	} else {
+		input = strings.TrimSpace(input)
+		input = h.normalizeUnicode(input)
+		input = h.normalizeWhitespace(input)
 	}
 
 	return input
@@ -67,9 +124,13 @@ func (h *Hasher) normalizeUnicode(input string) string {
 	return normalized.String()
 }
 
-func (h *Hasher) normalizeWhitespace(input string) string {
+func normalizeLineEndings(input string) string {
 	input = strings.ReplaceAll(input, "\r\n", "\n")
-	input = strings.ReplaceAll(input, "\r", "\n")
+	return strings.ReplaceAll(input, "\r", "\n")
+}
+
+func (h *Hasher) normalizeWhitespace(input string) string {
+	input = normalizeLineEndings(input)
 
 	lines := strings.Split(input, "\n")
 	var normalizedLines []string
@@ -85,7 +146,7 @@ func (h *Hasher) normalizeWhitespace(input string) string {
 }
 
 func (h *Hasher) ValidateHash(hash string) bool {
-	if len(hash) != 64 {
+	if len(hash) != h.algorithm.length {
 		return false
 	}
 
@@ -109,12 +170,15 @@ func (h *Hasher) GetHashMetadata(inputText, modelName string) map[string]interfa
 	normalizedInput := h.normalizeInput(inputText)
 
 	return map[string]interface{}{
-		"original_length":    len(inputText),
-		"normalized_length":  len(normalizedInput),
-		"model_name":        modelName,
-		"has_newlines":      strings.Contains(inputText, "\n"),
-		"has_tabs":          strings.Contains(inputText, "\t"),
-		"has_extra_spaces":  strings.Contains(inputText, "  "),
-		"truncated":         len(inputText) > 10000,
+		"original_length":     len(inputText),
+		"normalized_length":   len(normalizedInput),
+		"model_name":          modelName,
+		"algorithm":           h.algorithmName,
+		"namespace":           h.namespace,
+		"preserve_whitespace": h.preserveWhitespace,
+		"transforms":          h.transformNames,
+		"has_newlines":        strings.Contains(inputText, "\n"),
+		"has_tabs":            strings.Contains(inputText, "\t"),
+		"has_extra_spaces":    strings.Contains(inputText, "  "),
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,47 @@
+package hash
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+)
+
+// Typical inputs: a search query, a product title with description, and a
+// document chunk.
+var benchmarkSizes = []int{64, 1024, 8192}
+
+func benchmarkInput(size int) string {
+	words := strings.Repeat("lorem ipsum dolor sit amet ", size/27+1)
+	return words[:size]
+}
+
+func BenchmarkGenerateInputHash(b *testing.B) {
+	for _, algorithm := range []string{AlgorithmSHA256, AlgorithmXXHash, AlgorithmBLAKE3} {
+		h := New(&config.HashConfig{Algorithm: algorithm}, zap.NewNop())
+
+		for _, size := range benchmarkSizes {
+			input := benchmarkInput(size)
+
+			b.Run(fmt.Sprintf("%s/%dB", algorithm, size), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for b.Loop() {
+					h.GenerateInputHash(input, "text-embedding-3-small")
+				}
+			})
+
+			// Under load: every CPU hashing at once, as concurrent requests do.
+			b.Run(fmt.Sprintf("%s/%dB/parallel", algorithm, size), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						h.GenerateInputHash(input, "text-embedding-3-small")
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package hash
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/text/unicode/norm"
+)
+
+// transforms are the optional normalization steps selectable with
+// hash.transforms. They run in configured order before the built-in
+// trimming and whitespace handling, and their names are part of the key.
+var transforms = map[string]func(string) string{
+	"strip_html":      stripHTML,
+	"collapse_digits": collapseDigits,
+	"lowercase":       strings.ToLower,
+	"nfc":             norm.NFC.String,
+}
+
+// stripHTML keeps the text content of an HTML fragment, dropping tags,
+// comments and the contents of script and style elements. Tags become
+// spaces so words in adjacent elements stay apart.
+func stripHTML(input string) string {
+	if !strings.ContainsAny(input, "<&") {
+		return input
+	}
+
+	var text strings.Builder
+	skip := 0
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(input))
+
+	for {
+		switch tokenizer.Next() {
+		case xhtml.ErrorToken:
+			return text.String()
+		case xhtml.TextToken:
+			if skip == 0 {
+				text.WriteString(html.UnescapeString(string(tokenizer.Raw())))
+			}
+		case xhtml.StartTagToken:
+			if isRawTextElement(tokenizer) {
+				skip++
+			}
+			text.WriteByte(' ')
+		case xhtml.EndTagToken:
+			if isRawTextElement(tokenizer) && skip > 0 {
+				skip--
+			}
+			text.WriteByte(' ')
+		case xhtml.SelfClosingTagToken:
+			text.WriteByte(' ')
+		}
+	}
+}
+
+func isRawTextElement(tokenizer *xhtml.Tokenizer) bool {
+	name, _ := tokenizer.TagName()
+	return string(name) == "script" || string(name) == "style"
+}
+
+// collapseDigits replaces every run of digits with a single 0, so inputs
+// that differ only in numbers (SKUs, order ids) share a key.
+func collapseDigits(input string) string {
+	var collapsed strings.Builder
+	collapsed.Grow(len(input))
+
+	inDigits := false
+	for _, r := range input {
+		if unicode.IsDigit(r) {
+			if !inDigits {
+				collapsed.WriteByte('0')
+			}
+			inDigits = true
+			continue
+		}
+		inDigits = false
+		collapsed.WriteRune(r)
+	}
+
+	return collapsed.String()
+}
@@ -0,0 +1,123 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrKeyReused = errors.New("idempotency key reused with a different request")
+
+type Store struct {
+	logger    *zap.Logger
+	ttl       time.Duration
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	fingerprint string
+	done        chan struct{}
+	status      int
+	body        interface{}
+	kept        bool // set before done closes when the outcome is replayed
+	expiresAt   time.Time
+}
+
+type Result struct {
+	Status   int
+	Body     interface{}
+	Replayed bool
+}
+
+func New(ttl time.Duration, logger *zap.Logger) *Store {
+	return &Store{
+		logger:    logger,
+		ttl:       ttl,
+		entries:   make(map[string]*entry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Do runs fn once per key and replays its outcome to later requests with
+// the same key until the TTL passes. Server errors, and outcomes of a
+// leader whose ctx ended while fn ran, are not kept: requests waiting on
+// such a leader run fn themselves instead of replaying its cancellation.
+func (s *Store) Do(ctx context.Context, key, fingerprint string, fn func() (int, interface{})) (*Result, error) {
+	for {
+		s.mu.Lock()
+		s.sweepLocked()
+
+		e, ok := s.entries[key]
+		if !ok || (!e.expiresAt.IsZero() && !time.Now().Before(e.expiresAt)) {
+			break
+		}
+		s.mu.Unlock()
+
+		if e.fingerprint != fingerprint {
+			return nil, ErrKeyReused
+		}
+
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if !e.kept {
+			continue
+		}
+
+		s.logger.Debug("Replaying idempotent request", zap.String("idempotency_key", key))
+
+		return &Result{Status: e.status, Body: e.body, Replayed: true}, nil
+	}
+
+	e := &entry{
+		fingerprint: fingerprint,
+		done:        make(chan struct{}),
+	}
+	s.entries[key] = e
+	s.mu.Unlock()
+
+	e.status, e.body = fn()
+
+	s.mu.Lock()
+	if e.status >= http.StatusInternalServerError || ctx.Err() != nil {
+		delete(s.entries, key)
+	} else {
+		e.kept = true
+		e.expiresAt = time.Now().Add(s.ttl)
+	}
+	s.mu.Unlock()
+
+	close(e.done)
+
+	return &Result{Status: e.status, Body: e.body}, nil
+}
+
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+func (s *Store) sweepLocked() {
+	now := time.Now()
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
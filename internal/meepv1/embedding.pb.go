@@ -0,0 +1,462 @@
+// Wire format of /embed responses sent with Content-Type
+// application/x-protobuf. Generate client code from this file with protoc.
+// The server's Go code is generated into internal/meepv1:
+//
+//   protoc --go_out=. --go_opt=module=github.com/zanmato/meilisearch-embedder-proxy proto/embedding.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: proto/embedding.proto
+
+package meepv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Embedding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []float32              `protobuf:"fixed32,1,rep,packed,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Embedding) Reset() {
+	*x = Embedding{}
+	mi := &file_proto_embedding_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Embedding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Embedding) ProtoMessage() {}
+
+func (x *Embedding) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_embedding_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Embedding.ProtoReflect.Descriptor instead.
+func (*Embedding) Descriptor() ([]byte, []int) {
+	return file_proto_embedding_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Embedding) GetValues() []float32 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type ItemError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ItemError) Reset() {
+	*x = ItemError{}
+	mi := &file_proto_embedding_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ItemError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemError) ProtoMessage() {}
+
+func (x *ItemError) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_embedding_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemError.ProtoReflect.Descriptor instead.
+func (*ItemError) Descriptor() ([]byte, []int) {
+	return file_proto_embedding_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ItemError) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ItemError) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type Usage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PromptTokens  int32                  `protobuf:"varint,1,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	TotalTokens   int32                  `protobuf:"varint,2,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Usage) Reset() {
+	*x = Usage{}
+	mi := &file_proto_embedding_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Usage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Usage) ProtoMessage() {}
+
+func (x *Usage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_embedding_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Usage.ProtoReflect.Descriptor instead.
+func (*Usage) Descriptor() ([]byte, []int) {
+	return file_proto_embedding_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Usage) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+type BatchSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         int32                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Cached        int32                  `protobuf:"varint,2,opt,name=cached,proto3" json:"cached,omitempty"`
+	Fresh         int32                  `protobuf:"varint,3,opt,name=fresh,proto3" json:"fresh,omitempty"`
+	Failed        int32                  `protobuf:"varint,4,opt,name=failed,proto3" json:"failed,omitempty"`
+	Empty         int32                  `protobuf:"varint,5,opt,name=empty,proto3" json:"empty,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchSummary) Reset() {
+	*x = BatchSummary{}
+	mi := &file_proto_embedding_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchSummary) ProtoMessage() {}
+
+func (x *BatchSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_embedding_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchSummary.ProtoReflect.Descriptor instead.
+func (*BatchSummary) Descriptor() ([]byte, []int) {
+	return file_proto_embedding_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchSummary) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *BatchSummary) GetCached() int32 {
+	if x != nil {
+		return x.Cached
+	}
+	return 0
+}
+
+func (x *BatchSummary) GetFresh() int32 {
+	if x != nil {
+		return x.Fresh
+	}
+	return 0
+}
+
+func (x *BatchSummary) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *BatchSummary) GetEmpty() int32 {
+	if x != nil {
+		return x.Empty
+	}
+	return 0
+}
+
+type EmbeddingResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One entry per input; a single-input request has exactly one. Items
+	// listed in errors or empty_items have no values.
+	Embeddings    []*Embedding `protobuf:"bytes,1,rep,name=embeddings,proto3" json:"embeddings,omitempty"`
+	Model         string       `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	ProviderModel string       `protobuf:"bytes,3,opt,name=provider_model,json=providerModel,proto3" json:"provider_model,omitempty"`
+	// One entry per input, parallel to embeddings.
+	CachedItems    []bool        `protobuf:"varint,4,rep,packed,name=cached_items,json=cachedItems,proto3" json:"cached_items,omitempty"`
+	Partial        bool          `protobuf:"varint,5,opt,name=partial,proto3" json:"partial,omitempty"`
+	Errors         []*ItemError  `protobuf:"bytes,6,rep,name=errors,proto3" json:"errors,omitempty"`
+	Usage          *Usage        `protobuf:"bytes,7,opt,name=usage,proto3" json:"usage,omitempty"`
+	EmptyItems     []int32       `protobuf:"varint,8,rep,packed,name=empty_items,json=emptyItems,proto3" json:"empty_items,omitempty"`
+	TruncatedItems []int32       `protobuf:"varint,9,rep,packed,name=truncated_items,json=truncatedItems,proto3" json:"truncated_items,omitempty"`
+	Summary        *BatchSummary `protobuf:"bytes,10,opt,name=summary,proto3" json:"summary,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *EmbeddingResponse) Reset() {
+	*x = EmbeddingResponse{}
+	mi := &file_proto_embedding_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbeddingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbeddingResponse) ProtoMessage() {}
+
+func (x *EmbeddingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_embedding_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbeddingResponse.ProtoReflect.Descriptor instead.
+func (*EmbeddingResponse) Descriptor() ([]byte, []int) {
+	return file_proto_embedding_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EmbeddingResponse) GetEmbeddings() []*Embedding {
+	if x != nil {
+		return x.Embeddings
+	}
+	return nil
+}
+
+func (x *EmbeddingResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *EmbeddingResponse) GetProviderModel() string {
+	if x != nil {
+		return x.ProviderModel
+	}
+	return ""
+}
+
+func (x *EmbeddingResponse) GetCachedItems() []bool {
+	if x != nil {
+		return x.CachedItems
+	}
+	return nil
+}
+
+func (x *EmbeddingResponse) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *EmbeddingResponse) GetErrors() []*ItemError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *EmbeddingResponse) GetUsage() *Usage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *EmbeddingResponse) GetEmptyItems() []int32 {
+	if x != nil {
+		return x.EmptyItems
+	}
+	return nil
+}
+
+func (x *EmbeddingResponse) GetTruncatedItems() []int32 {
+	if x != nil {
+		return x.TruncatedItems
+	}
+	return nil
+}
+
+func (x *EmbeddingResponse) GetSummary() *BatchSummary {
+	if x != nil {
+		return x.Summary
+	}
+	return nil
+}
+
+var File_proto_embedding_proto protoreflect.FileDescriptor
+
+const file_proto_embedding_proto_rawDesc = "" +
+	"\n" +
+	"\x15proto/embedding.proto\x12\ameep.v1\"#\n" +
+	"\tEmbedding\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\x02R\x06values\"7\n" +
+	"\tItemError\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"O\n" +
+	"\x05Usage\x12#\n" +
+	"\rprompt_tokens\x18\x01 \x01(\x05R\fpromptTokens\x12!\n" +
+	"\ftotal_tokens\x18\x02 \x01(\x05R\vtotalTokens\"\x80\x01\n" +
+	"\fBatchSummary\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x05R\x05total\x12\x16\n" +
+	"\x06cached\x18\x02 \x01(\x05R\x06cached\x12\x14\n" +
+	"\x05fresh\x18\x03 \x01(\x05R\x05fresh\x12\x16\n" +
+	"\x06failed\x18\x04 \x01(\x05R\x06failed\x12\x14\n" +
+	"\x05empty\x18\x05 \x01(\x05R\x05empty\"\x8e\x03\n" +
+	"\x11EmbeddingResponse\x122\n" +
+	"\n" +
+	"embeddings\x18\x01 \x03(\v2\x12.meep.v1.EmbeddingR\n" +
+	"embeddings\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12%\n" +
+	"\x0eprovider_model\x18\x03 \x01(\tR\rproviderModel\x12!\n" +
+	"\fcached_items\x18\x04 \x03(\bR\vcachedItems\x12\x18\n" +
+	"\apartial\x18\x05 \x01(\bR\apartial\x12*\n" +
+	"\x06errors\x18\x06 \x03(\v2\x12.meep.v1.ItemErrorR\x06errors\x12$\n" +
+	"\x05usage\x18\a \x01(\v2\x0e.meep.v1.UsageR\x05usage\x12\x1f\n" +
+	"\vempty_items\x18\b \x03(\x05R\n" +
+	"emptyItems\x12'\n" +
+	"\x0ftruncated_items\x18\t \x03(\x05R\x0etruncatedItems\x12/\n" +
+	"\asummary\x18\n" +
+	" \x01(\v2\x15.meep.v1.BatchSummaryR\asummaryB?Z=github.com/zanmato/meilisearch-embedder-proxy/internal/meepv1b\x06proto3"
+
+var (
+	file_proto_embedding_proto_rawDescOnce sync.Once
+	file_proto_embedding_proto_rawDescData []byte
+)
+
+func file_proto_embedding_proto_rawDescGZIP() []byte {
+	file_proto_embedding_proto_rawDescOnce.Do(func() {
+		file_proto_embedding_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_embedding_proto_rawDesc), len(file_proto_embedding_proto_rawDesc)))
+	})
+	return file_proto_embedding_proto_rawDescData
+}
+
+var file_proto_embedding_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_embedding_proto_goTypes = []any{
+	(*Embedding)(nil),         // 0: meep.v1.Embedding
+	(*ItemError)(nil),         // 1: meep.v1.ItemError
+	(*Usage)(nil),             // 2: meep.v1.Usage
+	(*BatchSummary)(nil),      // 3: meep.v1.BatchSummary
+	(*EmbeddingResponse)(nil), // 4: meep.v1.EmbeddingResponse
+}
+var file_proto_embedding_proto_depIdxs = []int32{
+	0, // 0: meep.v1.EmbeddingResponse.embeddings:type_name -> meep.v1.Embedding
+	1, // 1: meep.v1.EmbeddingResponse.errors:type_name -> meep.v1.ItemError
+	2, // 2: meep.v1.EmbeddingResponse.usage:type_name -> meep.v1.Usage
+	3, // 3: meep.v1.EmbeddingResponse.summary:type_name -> meep.v1.BatchSummary
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_proto_embedding_proto_init() }
+func file_proto_embedding_proto_init() {
+	if File_proto_embedding_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_embedding_proto_rawDesc), len(file_proto_embedding_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_embedding_proto_goTypes,
+		DependencyIndexes: file_proto_embedding_proto_depIdxs,
+		MessageInfos:      file_proto_embedding_proto_msgTypes,
+	}.Build()
+	File_proto_embedding_proto = out.File
+	file_proto_embedding_proto_goTypes = nil
+	file_proto_embedding_proto_depIdxs = nil
+}
@@ -0,0 +1,154 @@
+package meilisearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultPageSize = 1000
+
+// Client reads documents from a Meilisearch instance. It only covers what
+// cache warmups need: paging through an index.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+type documentsPage struct {
+	Results []map[string]interface{} `json:"results"`
+	Offset  int                      `json:"offset"`
+	Limit   int                      `json:"limit"`
+	Total   int                      `json:"total"`
+}
+
+// New builds a client for host, which must be listed in allowedHosts
+// (server.meilisearch_allowed_hosts): the host comes from the request, so
+// without the list any admin caller could make the proxy fetch arbitrary
+// internal URLs. A pattern with a leading dot also allows its subdomains.
+func New(host, apiKey string, allowedHosts []string) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Meilisearch host %q: %w", host, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return nil, fmt.Errorf("meilisearch host %q must be an http or https URL", host)
+	}
+
+	if !hostAllowed(u.Hostname(), allowedHosts) {
+		return nil, fmt.Errorf("meilisearch host %q is not in server.meilisearch_allowed_hosts", u.Hostname())
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+
+	return &Client{
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		apiKey:  apiKey,
+		http: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: transport,
+			// A redirect could point anywhere; Meilisearch never sends one.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+func hostAllowed(host string, patterns []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "."); ok {
+			if host == suffix || strings.HasSuffix(host, pattern) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldValues pages through index and calls fn with the string values of
+// field on each page, skipping documents where it is missing, empty or not
+// a string. It stops after limit documents when limit > 0.
+func (c *Client) FieldValues(ctx context.Context, index, field string, pageSize, limit int, fn func([]string) error) error {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	for offset := 0; limit <= 0 || offset < limit; offset += pageSize {
+		size := pageSize
+		if limit > 0 {
+			size = min(size, limit-offset)
+		}
+
+		page, err := c.documents(ctx, index, field, offset, size)
+		if err != nil {
+			return err
+		}
+
+		values := make([]string, 0, len(page.Results))
+		for _, document := range page.Results {
+			if value, ok := document[field].(string); ok && value != "" {
+				values = append(values, value)
+			}
+		}
+
+		if err := fn(values); err != nil {
+			return err
+		}
+
+		if len(page.Results) < size || offset+len(page.Results) >= page.Total {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) documents(ctx context.Context, index, field string, offset, limit int) (*documentsPage, error) {
+	query := url.Values{}
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("fields", field)
+
+	endpoint := fmt.Sprintf("%s/indexes/%s/documents?%s", c.baseURL, url.PathEscape(index), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Meilisearch request: %w", err)
+	}
+
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch documents from Meilisearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("meilisearch returned %d for index %q", resp.StatusCode, index)
+	}
+
+	var page documentsPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode Meilisearch documents: %w", err)
+	}
+
+	return &page, nil
+}
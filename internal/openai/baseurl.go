@@ -0,0 +1,163 @@
+package openai
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+)
+
+// metadataHosts are cloud instance metadata endpoints. They are never a
+// valid provider, whatever allow_private_base_urls says.
+var metadataHosts = map[string]bool{
+	"metadata":                 true,
+	"metadata.google.internal": true,
+}
+
+var metadataIPs = []net.IP{
+	net.ParseIP("169.254.169.254"), // AWS, GCP, Azure, OpenStack
+	net.ParseIP("fd00:ec2::254"),   // AWS IPv6
+	net.ParseIP("100.100.100.200"), // Alibaba Cloud
+}
+
+// urlPolicy decides which provider base URLs may be called. It is checked
+// against the configured URLs when the client is built and again at dial
+// time, so a hostname that resolves to an internal address is refused too.
+type urlPolicy struct {
+	allowedHosts []string
+	deniedHosts  []string
+	allowPrivate bool
+}
+
+func newURLPolicy(cfg *config.OpenAIConfig) urlPolicy {
+	policy := urlPolicy{allowPrivate: cfg.AllowPrivateBaseURLs}
+	for _, host := range cfg.AllowedBaseHosts {
+		policy.allowedHosts = append(policy.allowedHosts, strings.ToLower(host))
+	}
+	for _, host := range cfg.DeniedBaseHosts {
+		policy.deniedHosts = append(policy.deniedHosts, strings.ToLower(host))
+	}
+	return policy
+}
+
+func (p urlPolicy) check(rawURL string) error {
+	if rawURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL %q: %w", rawURL, err)
+	}
+
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("base URL %q must use http or https", rawURL)
+	}
+
+	if u.User != nil {
+		return fmt.Errorf("base URL %q must not contain credentials", rawURL)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("base URL %q has no host", rawURL)
+	}
+
+	if matchesHost(host, p.deniedHosts) {
+		return fmt.Errorf("base URL host %q is denied", host)
+	}
+
+	if metadataHosts[host] {
+		return fmt.Errorf("base URL host %q is a metadata endpoint", host)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if err := p.checkIP(ip); err != nil {
+			return err
+		}
+	} else if !p.allowPrivate && (host == "localhost" || strings.HasSuffix(host, ".localhost")) {
+		return fmt.Errorf("base URL host %q is internal; set openai.allow_private_base_urls to allow it", host)
+	}
+
+	if len(p.allowedHosts) > 0 && !matchesHost(host, p.allowedHosts) {
+		return fmt.Errorf("base URL host %q is not in openai.allowed_base_hosts", host)
+	}
+
+	return nil
+}
+
+func (p urlPolicy) checkIP(ip net.IP) error {
+	for _, metadataIP := range metadataIPs {
+		if ip.Equal(metadataIP) {
+			return fmt.Errorf("address %s is a metadata endpoint", ip)
+		}
+	}
+
+	if ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return fmt.Errorf("address %s is link-local", ip)
+	}
+
+	if !p.allowPrivate && (ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified()) {
+		return fmt.Errorf("address %s is internal; set openai.allow_private_base_urls to allow it", ip)
+	}
+
+	return nil
+}
+
+// matchesHost reports whether host equals one of the patterns, or is a
+// subdomain of a pattern written with a leading dot (".openai.azure.com").
+func matchesHost(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if suffix, ok := strings.CutPrefix(pattern, "."); ok {
+			if host == suffix || strings.HasSuffix(host, pattern) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func (p urlPolicy) dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(ip)
+	}
+	return nil
+}
+
+// httpClient returns a client that enforces the policy on every connection
+// and redirect, not just on the configured URL.
+func (p urlPolicy) httpClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   p.dialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Through an HTTP proxy the dial goes to the proxy, not the provider,
+	// so dialControl would never see the provider's address.
+	transport.Proxy = nil
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return p.check(req.URL.String())
+		},
+	}
+}
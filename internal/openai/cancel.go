@@ -0,0 +1,28 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// callerCancelled reports whether ctx was cancelled by the caller, which for
+// HTTP requests means the client disconnected. Timeouts are not included:
+// a provider that times out is still failing.
+func callerCancelled(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
+
+// abortCall ends a provider call whose caller went away. The attempt is not
+// retried, not sent to a fallback and not counted against provider health.
+func (c *Client) abortCall(ctx context.Context, model string, batchSize int, elapsed time.Duration) error {
+	c.logger.Info("Provider call aborted, request cancelled",
+		zap.String("model", model),
+		zap.Int("batch_size", batchSize),
+		zap.Duration("latency", elapsed))
+
+	return fmt.Errorf("provider call aborted: %w", ctx.Err())
+}
@@ -0,0 +1,86 @@
+package openai_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
+)
+
+func TestCancelledRequestAbortsProviderCall(t *testing.T) {
+	var requests atomic.Int32
+	received := make(chan struct{}, 1)
+	aborted := make(chan struct{})
+
+	// The provider never answers; it only returns once the proxy hangs up.
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// The server only notices the hang-up once the body is read.
+		io.Copy(io.Discard, r.Body)
+		received <- struct{}{}
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer provider.Close()
+
+	client, err := openai.New(&config.OpenAIConfig{
+		APIKey:               "test",
+		Model:                "text-embedding-3-small",
+		BaseURL:              provider.URL,
+		AllowPrivateBaseURLs: true,
+		MaxRetries:           3,
+		RetryBudget:          3,
+		TimeoutSec:           30,
+		ChunkSize:            1000,
+		ResponseOrder:        "index",
+		HealthWindowSec:      60,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.CreateBatchEmbeddingsWithModel(ctx, []string{"hello", "world"}, "")
+		done <- err
+	}()
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider never received the request")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider call did not return after the request was cancelled")
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider connection was not closed")
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("provider saw %d requests, want 1: a cancelled call must not be retried", n)
+	}
+	if health := client.ProviderHealth(); health.Failures != 0 {
+		t.Errorf("provider health recorded %d failures for a cancelled call", health.Failures)
+	}
+}
@@ -0,0 +1,140 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// retryBudget is shared by all chunks of one call. Besides capping the total
+// number of retries, it holds a pause deadline: when any chunk is rate
+// limited, every chunk waits until the provider's Retry-After has passed
+// before its next attempt, so parallel chunks back off together.
+type retryBudget struct {
+	mu         sync.Mutex
+	remaining  int
+	pauseUntil time.Time
+}
+
+func newRetryBudget(retries int) *retryBudget {
+	return &retryBudget{remaining: retries}
+}
+
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+func (b *retryBudget) left() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining
+}
+
+// pauseOnRateLimit extends the shared pause when err is a 429, using the
+// provider's Retry-After header when present.
+func (b *retryBudget) pauseOnRateLimit(err error) {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+
+	delay := time.Second
+	if apiErr.Response != nil {
+		if seconds, err := strconv.Atoi(apiErr.Response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(b.pauseUntil) {
+		b.pauseUntil = until
+	}
+}
+
+// wait sleeps for backoff or until the shared pause ends, whichever is later.
+func (b *retryBudget) wait(ctx context.Context, backoff time.Duration) error {
+	b.mu.Lock()
+	if pause := time.Until(b.pauseUntil); pause > backoff {
+		backoff = pause
+	}
+	b.mu.Unlock()
+
+	if backoff <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// runChunks calls fn for each chunk index, one at a time or with up to
+// openai.chunk_concurrency in flight. The first error cancels the chunks
+// still running and is returned.
+func (c *Client) runChunks(ctx context.Context, count int, fn func(ctx context.Context, i int) error) error {
+	if c.chunkConcurrency <= 1 || count == 1 {
+		for i := 0; i < count; i++ {
+			if err := fn(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	slots := make(chan struct{}, c.chunkConcurrency)
+
+	for i := 0; i < count; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -2,20 +2,60 @@ package openai
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/tokenizer"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/vectorcheck"
 )
 
+const defaultMaxInputChars = 10000
+
+var ErrModelNotListed = errors.New("configured model not offered by provider")
+
 type Client struct {
-	client    *openai.Client
-	logger    *zap.Logger
-	model     string
-	maxRetries int
-	timeout   time.Duration
+	client              *openai.Client
+	apiKey              string
+	apiKeyMutex         sync.RWMutex
+	logger              *zap.Logger
+	model               string
+	strictModel         bool
+	allowed             map[string]bool
+	models              map[string]config.ModelConfig
+	maxRetries          int
+	retryBudget         int
+	chunkSize           int
+	chunkConcurrency    int
+	maxTokensPerRequest int
+	orderByPosition     bool
+	skipInvalidVectors  bool
+	timeout             time.Duration
+	perRequestTimeout   time.Duration
+
+	errorWindow       *errorWindow
+	healthErrorRate   float64
+	healthMinRequests int
+
+	providerModels      map[string]bool
+	providerModelsMutex sync.RWMutex
+
+	fallbacks          []*fallbackProvider
+	fallbackEquivalent bool
+
+	limiters map[string]*modelLimiter
+	prices   map[string]float64
+
+	modelMismatches sync.Map // "requested|returned" pairs already logged
 }
 
 type EmbeddingRequest struct {
@@ -24,16 +64,28 @@ type EmbeddingRequest struct {
 }
 
 type EmbeddingResponse struct {
-	Embedding []float64  `json:"embedding,omitempty"`
+	Embedding  []float64   `json:"embedding,omitempty"`
 	Embeddings [][]float64 `json:"embeddings,omitempty"`
-	Model     string    `json:"model"`
-	TokenUsage struct {
+	Model      string      `json:"model"`
+	Provider   string      `json:"provider,omitempty"` // fallback that served it, empty for the primary
+	// ProviderModel is the model name the provider reported when it differs
+	// from the requested one; Model is always the requested name.
+	ProviderModel string `json:"provider_model,omitempty"`
+	TokenUsage    struct {
 		PromptTokens int `json:"prompt_tokens"`
 		TotalTokens  int `json:"total_tokens"`
 	} `json:"usage"`
 }
 
-func New(apiKey, baseURL, model string, maxRetries int, timeoutSec int, logger *zap.Logger) (*Client, error) {
+func New(cfg *config.OpenAIConfig, logger *zap.Logger) (*Client, error) {
+	apiKey := cfg.APIKey
+	baseURL := cfg.BaseURL
+	model := cfg.Model
+
+	if cfg.Mock && apiKey == "" {
+		apiKey = "mock"
+	}
+
 	if apiKey == "" {
 		return nil, fmt.Errorf("OpenAI API key is required")
 	}
@@ -42,39 +94,128 @@ func New(apiKey, baseURL, model string, maxRetries int, timeoutSec int, logger *
 		model = "text-embedding-3-small"
 	}
 
+	policy := newURLPolicy(cfg)
+	if err := policy.check(baseURL); err != nil {
+		return nil, err
+	}
+
+	var mock *mockTransport
+	httpClient := policy.httpClient()
+	if cfg.Mock {
+		mock = &mockTransport{}
+		httpClient = &http.Client{Transport: mock}
+	}
+
 	opts := []option.RequestOption{
 		option.WithAPIKey(apiKey),
+		option.WithMaxRetries(0),
+		option.WithHTTPClient(httpClient),
 	}
+	opts = append(opts, headerOptions(cfg.ExtraHeaders)...)
 
 	if baseURL != "" {
 		opts = append(opts, option.WithBaseURL(baseURL))
 	}
 
+	if cfg.DebugHTTP {
+		opts = append(opts, option.WithMiddleware(debugHTTPMiddleware(logger, cfg.ExtraHeaders)))
+		logger.Warn("Provider HTTP debug logging enabled; request and response bodies are logged at debug level")
+	}
+
 	client := openai.NewClient(opts...)
 
 	openaiClient := &Client{
-		client:     &client,
-		logger:     logger,
-		model:      model,
-		maxRetries: maxRetries,
-		timeout:    time.Duration(timeoutSec) * time.Second,
+		client:              &client,
+		apiKey:              apiKey,
+		logger:              logger,
+		model:               model,
+		strictModel:         cfg.StrictModel,
+		allowed:             make(map[string]bool, len(cfg.AllowedModels)),
+		models:              make(map[string]config.ModelConfig, len(cfg.Models)),
+		maxRetries:          cfg.MaxRetries,
+		retryBudget:         cfg.RetryBudget,
+		chunkSize:           cfg.ChunkSize,
+		chunkConcurrency:    cfg.ChunkConcurrency,
+		maxTokensPerRequest: cfg.MaxTokensPerRequest,
+		orderByPosition:     cfg.ResponseOrder == "position",
+		skipInvalidVectors:  cfg.InvalidVectors == "skip",
+		timeout:             time.Duration(cfg.TimeoutSec) * time.Second,
+		perRequestTimeout:   time.Duration(cfg.PerRequestTimeoutSec) * time.Second,
+		errorWindow:         newErrorWindow(time.Duration(cfg.HealthWindowSec) * time.Second),
+		healthErrorRate:     cfg.HealthErrorRate,
+		healthMinRequests:   cfg.HealthMinRequests,
+		fallbackEquivalent:  cfg.FallbackEquivalent,
+		prices:              cfg.PricePer1KTokens,
+	}
+
+	if mock != nil {
+		mock.client = openaiClient
+		logger.Warn("Mock provider enabled; embeddings are deterministic pseudo-random vectors, not real ones")
+	}
+
+	for _, fallbackConfig := range cfg.Fallbacks {
+		if mock != nil {
+			break
+		}
+		if err := policy.check(fallbackConfig.BaseURL); err != nil {
+			return nil, fmt.Errorf("fallback %s: %w", fallbackConfig.Name, err)
+		}
+		openaiClient.fallbacks = append(openaiClient.fallbacks, newFallbackProvider(fallbackConfig, policy, cfg.DebugHTTP, logger))
+	}
+
+	// 0 disables retries; only a negative budget falls back to max_retries.
+	if openaiClient.retryBudget < 0 {
+		openaiClient.retryBudget = cfg.MaxRetries
+	}
+
+	if openaiClient.chunkSize <= 0 {
+		openaiClient.chunkSize = 1000
+	}
+
+	for _, name := range cfg.AllowedModels {
+		openaiClient.allowed[name] = true
+	}
+
+	for _, modelConfig := range cfg.Models {
+		openaiClient.models[modelConfig.Name] = modelConfig
+	}
+
+	openaiClient.limiters = newModelLimiters(cfg.Models)
+
+	if _, ok := openaiClient.models[model]; !ok && len(openaiClient.models) > 0 {
+		openaiClient.models[model] = config.ModelConfig{Name: model}
 	}
 
 	logger.Info("OpenAI client initialized",
 		zap.String("model", model),
 		zap.String("base_url", baseURL),
-		zap.Int("max_retries", maxRetries),
-		zap.Int("timeout_sec", timeoutSec))
+		zap.Int("max_retries", cfg.MaxRetries),
+		zap.Int("timeout_sec", cfg.TimeoutSec),
+		zap.Int("per_request_timeout_sec", cfg.PerRequestTimeoutSec),
+		zap.Int("retry_budget", openaiClient.retryBudget),
+		zap.Int("chunk_concurrency", openaiClient.chunkConcurrency),
+		zap.Int("chunk_size", openaiClient.chunkSize),
+		zap.Int("max_tokens_per_request", cfg.MaxTokensPerRequest),
+		zap.String("response_order", cfg.ResponseOrder),
+		zap.String("invalid_vectors", cfg.InvalidVectors),
+		zap.Bool("strict_model", cfg.StrictModel),
+		zap.Strings("allowed_models", cfg.AllowedModels),
+		zap.Int("configured_models", len(openaiClient.models)),
+		zap.Int("fallbacks", len(openaiClient.fallbacks)))
 
 	return openaiClient, nil
 }
 
 func (c *Client) CreateEmbedding(ctx context.Context, input string) (*EmbeddingResponse, error) {
+	return c.CreateEmbeddingWithModel(ctx, input, c.model)
+}
+
+func (c *Client) CreateEmbeddingWithModel(ctx context.Context, input, model string) (*EmbeddingResponse, error) {
 	if input == "" {
 		return nil, fmt.Errorf("input text cannot be empty")
 	}
 
-	responses, err := c.CreateBatchEmbeddings(ctx, []string{input})
+	responses, err := c.CreateBatchEmbeddingsWithModel(ctx, []string{input}, model)
 	if err != nil {
 		return nil, err
 	}
@@ -83,111 +224,440 @@ func (c *Client) CreateEmbedding(ctx context.Context, input string) (*EmbeddingR
 		return nil, fmt.Errorf("no embedding data returned from OpenAI")
 	}
 
+	if responses.Embeddings[0] == nil {
+		return nil, fmt.Errorf("provider returned an %w", vectorcheck.ErrInvalidVector)
+	}
+
 	return &EmbeddingResponse{
-		Embedding: responses.Embeddings[0],
-		Model:     responses.Model,
-		TokenUsage: responses.TokenUsage,
+		Embedding:     responses.Embeddings[0],
+		Model:         responses.Model,
+		ProviderModel: responses.ProviderModel,
+		Provider:      responses.Provider,
+		TokenUsage:    responses.TokenUsage,
 	}, nil
 }
 
 func (c *Client) CreateBatchEmbeddings(ctx context.Context, inputs []string) (*EmbeddingResponse, error) {
-	if len(inputs) == 0 {
-		return nil, fmt.Errorf("input array cannot be empty")
+	return c.CreateBatchEmbeddingsWithModel(ctx, inputs, c.model)
+}
+
+func (c *Client) CreateBatchEmbeddingsWithModel(ctx context.Context, inputs []string, model string) (*EmbeddingResponse, error) {
+	if model == "" {
+		model = c.model
 	}
 
-	if len(inputs) > 1000 {
-		return nil, fmt.Errorf("batch size too large (max 1000 items)")
+	if !c.IsModelAllowed(model) {
+		return nil, fmt.Errorf("model %q is not allowed", model)
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("input array cannot be empty")
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
+	budget := newRetryBudget(c.retryBudget)
+	result := &EmbeddingResponse{
+		Embeddings: make([][]float64, 0, len(inputs)),
+		Model:      model,
+	}
+
+	chunks := c.splitChunks(inputs, model)
+	chunkResults := make([]*EmbeddingResponse, len(chunks))
+
+	err := c.runChunks(ctx, len(chunks), func(ctx context.Context, i int) error {
+		chunk, err := c.embedChunk(ctx, inputs[chunks[i][0]:chunks[i][1]], model, budget)
+		chunkResults[i] = chunk
+		return err
+	})
+	if err != nil {
+		return nil, c.timeoutError(ctx, err)
+	}
+
+	for _, chunk := range chunkResults {
+		result.Embeddings = append(result.Embeddings, chunk.Embeddings...)
+		if chunk.Model != model {
+			result.ProviderModel = chunk.Model
+			c.noteModelMismatch(model, chunk.Model)
+		}
+		if chunk.Provider != "" {
+			result.Provider = chunk.Provider
+		}
+		result.TokenUsage.PromptTokens += chunk.TokenUsage.PromptTokens
+		result.TokenUsage.TotalTokens += chunk.TokenUsage.TotalTokens
+	}
+
+	c.logger.Info("Successfully created batch embeddings",
+		zap.String("model", result.Model),
+		zap.Int("batch_size", len(result.Embeddings)),
+		zap.Int("chunks", len(chunks)),
+		zap.Int("retries_used", c.retryBudget-budget.left()),
+		zap.Int("vector_length", len(result.Embeddings[0])),
+		zap.Int("prompt_tokens", result.TokenUsage.PromptTokens),
+		zap.Int("total_tokens", result.TokenUsage.TotalTokens))
+
+	return result, nil
+}
+
+func (c *Client) splitChunks(inputs []string, model string) [][2]int {
+	var chunks [][2]int
+
+	start := 0
+	tokens := 0
+	for i, input := range inputs {
+		inputTokens := 0
+		if c.maxTokensPerRequest > 0 {
+			inputTokens = tokenizer.CountTokens(input, model)
+		}
+
+		full := i-start >= c.chunkSize
+		overTokens := c.maxTokensPerRequest > 0 && i > start && tokens+inputTokens > c.maxTokensPerRequest
+		if full || overTokens {
+			chunks = append(chunks, [2]int{start, i})
+			start = i
+			tokens = 0
+		}
+
+		if c.maxTokensPerRequest > 0 && inputTokens > c.maxTokensPerRequest {
+			c.logger.Warn("Single input exceeds max tokens per request",
+				zap.Int("index", i),
+				zap.Int("estimated_tokens", inputTokens),
+				zap.Int("max_tokens_per_request", c.maxTokensPerRequest))
+		}
+
+		tokens += inputTokens
+	}
+
+	return append(chunks, [2]int{start, len(inputs)})
+}
+
+func (c *Client) embedChunk(ctx context.Context, inputs []string, model string, budget *retryBudget) (*EmbeddingResponse, error) {
+	response, err := c.embedChunkPrimary(ctx, inputs, model, budget)
+	if err == nil || len(c.fallbacks) == 0 || ctx.Err() != nil || !isRetryableError(err) {
+		return response, err
+	}
+
+	return c.embedChunkFallback(ctx, inputs, model, err)
+}
+
+func (c *Client) embedChunkPrimary(ctx context.Context, inputs []string, model string, budget *retryBudget) (*EmbeddingResponse, error) {
 	var lastErr error
 
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+	for attempt := 0; ; attempt++ {
 		if attempt > 0 {
+			if attempt > c.maxRetries || !budget.take() {
+				return nil, fmt.Errorf("failed to create batch embeddings after %d attempts: %w", attempt, lastErr)
+			}
+
 			c.logger.Warn("Retrying OpenAI batch API call",
 				zap.Int("attempt", attempt),
+				zap.Int("retry_budget_remaining", budget.left()),
 				zap.Error(lastErr))
+		}
 
-			backoff := time.Duration(attempt) * time.Second
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(backoff):
-			}
+		// Every attempt, the first included, honours a rate-limit pause
+		// another chunk has already hit.
+		if err := budget.wait(ctx, time.Duration(attempt)*time.Second); err != nil {
+			return nil, err
 		}
 
-		response, err := c.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
-		Input: openai.EmbeddingNewParamsInputUnion{
-			OfArrayOfStrings: inputs,
-		},
-		Model: openai.EmbeddingModel(c.model),
-	})
+		params := c.embeddingParams(ctx, inputs, model)
+
+		release, err := c.acquire(ctx, model)
+		if err != nil {
+			return nil, err
+		}
+
+		callStart := time.Now()
+		attemptCtx, cancel := c.attemptContext(ctx)
+		response, err := c.client.Embeddings.New(attemptCtx, params, c.requestOptions()...)
+		cancel()
+		release()
+		if err != nil && callerCancelled(ctx) {
+			return nil, c.abortCall(ctx, model, len(inputs), time.Since(callStart))
+		}
+		c.errorWindow.record(providerFailure(err))
 
 		if err != nil {
 			lastErr = err
+			budget.pauseOnRateLimit(err)
 			c.logger.Error("OpenAI batch API call failed",
 				zap.Int("attempt", attempt+1),
 				zap.Error(err))
 			continue
 		}
 
-		if len(response.Data) == 0 {
-			lastErr = fmt.Errorf("no embedding data returned from OpenAI")
+		embeddingResponse, err := c.toEmbeddingResponse(response, len(inputs))
+		if err != nil {
+			lastErr = err
 			continue
 		}
 
-		embeddings := make([][]float64, len(response.Data))
-		for i, data := range response.Data {
-			if len(data.Embedding) == 0 {
-				lastErr = fmt.Errorf("empty embedding vector returned from OpenAI at index %d", i)
-				continue
+		c.logProviderCall("primary", model, len(inputs), embeddingResponse.TokenUsage.PromptTokens, time.Since(callStart))
+
+		return embeddingResponse, nil
+	}
+}
+
+func (c *Client) embeddingParams(ctx context.Context, inputs []string, model string) openai.EmbeddingNewParams {
+	params := openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{
+			OfArrayOfStrings: inputs,
+		},
+		Model: openai.EmbeddingModel(model),
+	}
+
+	if dimensions := c.EffectiveDimensions(model, dimensionsFromContext(ctx)); dimensions > 0 {
+		params.Dimensions = openai.Int(int64(dimensions))
+	}
+
+	if user := userFromContext(ctx); user != "" {
+		params.User = openai.String(user)
+	}
+
+	return params
+}
+
+// toEmbeddingResponse converts a provider response for a chunk of count
+// inputs. A short or long response is an error: chunks are concatenated by
+// position, so one missing vector would shift every later one onto the
+// wrong input.
+func (c *Client) toEmbeddingResponse(response *openai.CreateEmbeddingResponse, count int) (*EmbeddingResponse, error) {
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("no embedding data returned from OpenAI")
+	}
+
+	if len(response.Data) != count {
+		return nil, fmt.Errorf("OpenAI returned %d embeddings for %d inputs", len(response.Data), count)
+	}
+
+	embeddings, err := extractEmbeddings(response, c.orderByPosition)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkVectors(embeddings); err != nil {
+		return nil, err
+	}
+
+	embeddingResponse := &EmbeddingResponse{
+		Embeddings: embeddings,
+		Model:      string(response.Model),
+	}
+
+	if response.Usage.PromptTokens > 0 {
+		embeddingResponse.TokenUsage.PromptTokens = int(response.Usage.PromptTokens)
+		embeddingResponse.TokenUsage.TotalTokens = int(response.Usage.TotalTokens)
+	}
+
+	return embeddingResponse, nil
+}
+
+// extractEmbeddings places each vector at the input position named by its
+// index, since providers and gateways may reorder them. byPosition trusts the
+// response order instead, for gateways that omit or zero the index.
+func extractEmbeddings(response *openai.CreateEmbeddingResponse, byPosition bool) ([][]float64, error) {
+	embeddings := make([][]float64, len(response.Data))
+	for i, data := range response.Data {
+		index := i
+		if !byPosition {
+			index = int(data.Index)
+			if index < 0 || index >= len(embeddings) || embeddings[index] != nil {
+				return nil, fmt.Errorf("invalid or duplicate embedding index %d returned from OpenAI at position %d", data.Index, i)
 			}
-			embeddings[i] = data.Embedding
 		}
+		if len(data.Embedding) == 0 {
+			return nil, fmt.Errorf("empty embedding vector returned from OpenAI at index %d", index)
+		}
+		embeddings[index] = data.Embedding
+	}
+	return embeddings, nil
+}
 
-		if lastErr != nil {
+// checkVectors fails the call on a zero, NaN or Inf vector, which then
+// counts as a failed attempt and is retried. With invalid_vectors = "skip"
+// only the bad vectors are dropped, leaving nil for the cache to report as
+// failed items.
+func (c *Client) checkVectors(embeddings [][]float64) error {
+	for i, embedding := range embeddings {
+		err := vectorcheck.Validate(embedding)
+		if err == nil {
 			continue
 		}
-
-		embeddingResponse := &EmbeddingResponse{
-			Embeddings: embeddings,
-			Model:     string(response.Model),
+		if !c.skipInvalidVectors {
+			return fmt.Errorf("input %d: provider returned %w", i, err)
 		}
 
-		if response.Usage.PromptTokens > 0 {
-			embeddingResponse.TokenUsage.PromptTokens = int(response.Usage.PromptTokens)
-			embeddingResponse.TokenUsage.TotalTokens = int(response.Usage.TotalTokens)
-		}
+		c.logger.Warn("Dropping invalid vector from provider response",
+			zap.Int("index", i),
+			zap.Error(err))
+		embeddings[i] = nil
+	}
+	return nil
+}
 
-		c.logger.Info("Successfully created batch embeddings",
-			zap.String("model", embeddingResponse.Model),
-			zap.Int("batch_size", len(embeddings)),
-			zap.Int("vector_length", len(embeddings[0])),
-			zap.Int("prompt_tokens", embeddingResponse.TokenUsage.PromptTokens),
-			zap.Int("total_tokens", embeddingResponse.TokenUsage.TotalTokens))
+func (c *Client) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.perRequestTimeout > 0 {
+		return context.WithTimeout(ctx, c.perRequestTimeout)
+	}
+	return context.WithCancel(ctx)
+}
 
-		return embeddingResponse, nil
+func (c *Client) SetAPIKey(apiKey string) error {
+	if apiKey == "" {
+		return fmt.Errorf("OpenAI API key is required")
 	}
 
-	return nil, fmt.Errorf("failed to create batch embeddings after %d attempts: %w", c.maxRetries+1, lastErr)
+	c.apiKeyMutex.Lock()
+	changed := c.apiKey != apiKey
+	c.apiKey = apiKey
+	c.apiKeyMutex.Unlock()
+
+	c.logger.Info("OpenAI API key reloaded", zap.Bool("changed", changed))
+	return nil
+}
+
+// headerOptions sets each configured extra header on every provider request.
+func headerOptions(headers map[string]string) []option.RequestOption {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	opts := make([]option.RequestOption, len(names))
+	for i, name := range names {
+		opts[i] = option.WithHeader(name, headers[name])
+	}
+	return opts
+}
+
+func (c *Client) requestOptions() []option.RequestOption {
+	c.apiKeyMutex.RLock()
+	defer c.apiKeyMutex.RUnlock()
+
+	return []option.RequestOption{option.WithAPIKey(c.apiKey)}
+}
+
+// noteModelMismatch warns, once per pair, when the provider reports a
+// different model than was requested. Vectors are still cached and returned
+// under the requested name, so a silent provider-side model change shows up
+// here rather than as a split cache.
+func (c *Client) noteModelMismatch(requested, returned string) {
+	if returned == "" {
+		return
+	}
+
+	if _, seen := c.modelMismatches.LoadOrStore(requested+"|"+returned, true); seen {
+		return
+	}
+
+	c.logger.Warn("Provider returned a different model than requested",
+		zap.String("requested_model", requested),
+		zap.String("provider_model", returned))
 }
 
 func (c *Client) GetModel() string {
 	return c.model
 }
 
+func (c *Client) ModelConfig(model string) config.ModelConfig {
+	if model == "" {
+		model = c.model
+	}
+
+	modelConfig, ok := c.models[model]
+	if !ok {
+		modelConfig = config.ModelConfig{Name: model}
+	}
+
+	if modelConfig.MaxInputChars == 0 {
+		modelConfig.MaxInputChars = defaultMaxInputChars
+	}
+
+	return modelConfig
+}
+
+func (c *Client) IsModelAllowed(model string) bool {
+	if len(c.models) > 0 {
+		_, ok := c.models[model]
+		return model == "" || ok
+	}
+
+	if model == "" || model == c.model || c.allowed[model] {
+		return true
+	}
+
+	return len(c.allowed) == 0 && !c.strictModel
+}
+
 func (c *Client) ValidateModel(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	_, err := c.client.Models.List(ctx)
+	available := make(map[string]bool)
+	pager := c.client.Models.ListAutoPaging(ctx, c.requestOptions()...)
+	for pager.Next() {
+		available[pager.Current().ID] = true
+	}
 
-	if err != nil {
+	if err := pager.Err(); err != nil {
 		return fmt.Errorf("model validation failed: %w", err)
 	}
 
-	c.logger.Info("Model validation successful", zap.String("model", c.model))
+	c.providerModelsMutex.Lock()
+	c.providerModels = available
+	c.providerModelsMutex.Unlock()
+
+	var missing []string
+	for _, model := range c.configuredModels() {
+		if !available[model] {
+			missing = append(missing, model)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrModelNotListed, strings.Join(missing, ", "))
+	}
+
+	c.logger.Info("Model validation successful",
+		zap.String("model", c.model),
+		zap.Int("provider_models", len(available)))
 	return nil
-}
\ No newline at end of file
+}
+
+func (c *Client) ProviderModels() []string {
+	c.providerModelsMutex.RLock()
+	defer c.providerModelsMutex.RUnlock()
+
+	models := make([]string, 0, len(c.providerModels))
+	for model := range c.providerModels {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	return models
+}
+
+func (c *Client) configuredModels() []string {
+	seen := map[string]bool{c.model: true}
+	models := []string{c.model}
+
+	add := func(model string) {
+		if !seen[model] {
+			seen[model] = true
+			models = append(models, model)
+		}
+	}
+
+	for model := range c.allowed {
+		add(model)
+	}
+	for model := range c.models {
+		add(model)
+	}
+
+	sort.Strings(models[1:])
+	return models
+}
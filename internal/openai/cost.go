@@ -0,0 +1,46 @@
+package openai
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// EstimateCost returns the USD cost of tokens for model from the configured
+// price table. ok is false when the model has no price.
+func (c *Client) EstimateCost(model string, tokens int) (cost float64, ok bool) {
+	if model == "" {
+		model = c.model
+	}
+
+	price, ok := c.prices[model]
+	if !ok {
+		return 0, false
+	}
+
+	return float64(tokens) / 1000 * price, true
+}
+
+// HasPrice reports whether a cost estimate is available for model.
+func (c *Client) HasPrice(model string) bool {
+	_, ok := c.EstimateCost(model, 0)
+	return ok
+}
+
+// logProviderCall writes one line per successful provider call, meant to be
+// aggregated into usage and cost dashboards.
+func (c *Client) logProviderCall(provider, model string, batchSize, promptTokens int, latency time.Duration) {
+	fields := []zap.Field{
+		zap.String("provider", provider),
+		zap.String("model", model),
+		zap.Int("batch_size", batchSize),
+		zap.Int("prompt_tokens", promptTokens),
+		zap.Duration("latency", latency),
+	}
+
+	if cost, ok := c.EstimateCost(model, promptTokens); ok {
+		fields = append(fields, zap.Float64("estimated_cost_usd", cost))
+	}
+
+	c.logger.Info("Provider call completed", fields...)
+}
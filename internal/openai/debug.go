@@ -0,0 +1,103 @@
+package openai
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+	"go.uber.org/zap"
+)
+
+const debugBodyLimit = 512
+
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Api-Key":       true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// debugHTTPMiddleware logs provider traffic. Configured extra headers are
+// redacted along with the standard credential headers, since they often
+// carry gateway tokens.
+func debugHTTPMiddleware(logger *zap.Logger, extraHeaders map[string]string) option.Middleware {
+	redacted := make(map[string]bool, len(redactedHeaders)+len(extraHeaders))
+	for name := range redactedHeaders {
+		redacted[name] = true
+	}
+	for name := range extraHeaders {
+		redacted[http.CanonicalHeaderKey(name)] = true
+	}
+
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if !logger.Core().Enabled(zap.DebugLevel) {
+			return next(req)
+		}
+
+		var requestBody []byte
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				requestBody, _ = io.ReadAll(io.LimitReader(body, debugBodyLimit+1))
+				body.Close()
+			}
+		}
+
+		logger.Debug("Provider HTTP request",
+			zap.String("method", req.Method),
+			zap.String("url", req.URL.Redacted()),
+			zap.Any("headers", redactHeaders(req.Header, redacted)),
+			zap.String("body", truncateBody(requestBody)))
+
+		start := time.Now()
+		resp, err := next(req)
+		if err != nil {
+			logger.Debug("Provider HTTP request failed",
+				zap.String("url", req.URL.Redacted()),
+				zap.Duration("latency", time.Since(start)),
+				zap.Error(err))
+			return resp, err
+		}
+
+		head, readErr := io.ReadAll(io.LimitReader(resp.Body, debugBodyLimit+1))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+
+		fields := []zap.Field{
+			zap.String("url", req.URL.Redacted()),
+			zap.Int("status_code", resp.StatusCode),
+			zap.String("content_type", resp.Header.Get("Content-Type")),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("body", truncateBody(head)),
+		}
+		if readErr != nil {
+			fields = append(fields, zap.NamedError("read_error", readErr))
+		}
+		logger.Debug("Provider HTTP response", fields...)
+
+		return resp, nil
+	}
+}
+
+func redactHeaders(header http.Header, redacted map[string]bool) map[string]string {
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		if redacted[http.CanonicalHeaderKey(name)] {
+			result[name] = "[REDACTED]"
+			continue
+		}
+		result[name] = strings.Join(values, ", ")
+	}
+	return result
+}
+
+func truncateBody(body []byte) string {
+	if len(body) > debugBodyLimit {
+		return string(body[:debugBodyLimit]) + "...(truncated)"
+	}
+	return string(body)
+}
@@ -0,0 +1,73 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+)
+
+// knownMaxDimensions lists the native sizes of models that accept a
+// shorter `dimensions` parameter. Other models need max_dimensions in
+// their [[openai.models]] entry before requests may override it.
+var knownMaxDimensions = map[string]int{
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+}
+
+type dimensionsContextKey struct{}
+
+// WithDimensions overrides the output size requested from the provider for
+// calls made with the returned context.
+func WithDimensions(ctx context.Context, dimensions int) context.Context {
+	if dimensions <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, dimensionsContextKey{}, dimensions)
+}
+
+func dimensionsFromContext(ctx context.Context) int {
+	dimensions, _ := ctx.Value(dimensionsContextKey{}).(int)
+	return dimensions
+}
+
+// MaxDimensions returns the largest dimensions override a model accepts, or
+// 0 when it does not support one.
+func (c *Client) MaxDimensions(model string) int {
+	modelConfig := c.ModelConfig(model)
+	if modelConfig.MaxDimensions > 0 {
+		return modelConfig.MaxDimensions
+	}
+	return knownMaxDimensions[modelConfig.Name]
+}
+
+// ValidateDimensions checks a per-request dimensions override for model.
+func (c *Client) ValidateDimensions(model string, dimensions int) error {
+	if dimensions < 0 {
+		return fmt.Errorf("dimensions must be positive")
+	}
+
+	modelConfig := c.ModelConfig(model)
+	if modelConfig.MultiVector {
+		return fmt.Errorf("model %s is multi-vector and does not support a dimensions override", modelConfig.Name)
+	}
+
+	limit := c.MaxDimensions(model)
+	if limit == 0 {
+		return fmt.Errorf("model %s does not support a dimensions override", modelConfig.Name)
+	}
+
+	if dimensions > limit {
+		return fmt.Errorf("dimensions %d not supported by model %s (must be 1-%d)", dimensions, modelConfig.Name, limit)
+	}
+
+	return nil
+}
+
+// EffectiveDimensions returns the output size sent to the provider for a
+// request: the override when given, otherwise the model's configured size.
+// 0 means the provider's native size.
+func (c *Client) EffectiveDimensions(model string, override int) int {
+	if override > 0 {
+		return override
+	}
+	return c.ModelConfig(model).Dimensions
+}
@@ -0,0 +1,116 @@
+package openai
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// errorWindow counts provider attempts in one-second buckets so the error
+// rate over the last window can be read without keeping every event.
+type errorWindow struct {
+	mu      sync.Mutex
+	window  time.Duration
+	buckets []errorBucket
+}
+
+type errorBucket struct {
+	second   int64
+	total    int
+	failures int
+}
+
+func newErrorWindow(window time.Duration) *errorWindow {
+	seconds := int(window / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return &errorWindow{
+		window:  window,
+		buckets: make([]errorBucket, seconds),
+	}
+}
+
+func (w *errorWindow) record(failed bool) {
+	now := time.Now().Unix()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	bucket := &w.buckets[now%int64(len(w.buckets))]
+	if bucket.second != now {
+		*bucket = errorBucket{second: now}
+	}
+
+	bucket.total++
+	if failed {
+		bucket.failures++
+	}
+}
+
+// providerFailure reports whether err counts against the provider's
+// health: transport errors, timeouts and 5xx. Other 4xx responses, rate
+// limits included, are the provider working as intended.
+func providerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+
+	return apiErr.StatusCode == http.StatusRequestTimeout || apiErr.StatusCode >= http.StatusInternalServerError
+}
+
+func (w *errorWindow) snapshot() (total, failures int) {
+	oldest := time.Now().Unix() - int64(len(w.buckets)) + 1
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, bucket := range w.buckets {
+		if bucket.second >= oldest {
+			total += bucket.total
+			failures += bucket.failures
+		}
+	}
+
+	return total, failures
+}
+
+type ProviderHealth struct {
+	Healthy   bool    `json:"healthy"`
+	ErrorRate float64 `json:"error_rate"`
+	Requests  int     `json:"requests"`
+	Failures  int     `json:"failures"`
+	WindowSec int     `json:"window_sec"`
+	Threshold float64 `json:"threshold"`
+}
+
+func (c *Client) ProviderHealth() ProviderHealth {
+	total, failures := c.errorWindow.snapshot()
+
+	health := ProviderHealth{
+		Healthy:   true,
+		Requests:  total,
+		Failures:  failures,
+		WindowSec: int(c.errorWindow.window / time.Second),
+		Threshold: c.healthErrorRate,
+	}
+
+	if total > 0 {
+		health.ErrorRate = float64(failures) / float64(total)
+	}
+
+	if c.healthErrorRate > 0 && total >= c.healthMinRequests && health.ErrorRate > c.healthErrorRate {
+		health.Healthy = false
+	}
+
+	return health
+}
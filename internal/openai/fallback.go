@@ -0,0 +1,113 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+)
+
+// fallbackProvider is a secondary OpenAI-compatible endpoint tried, in
+// configuration order, when the primary fails with a retryable error.
+type fallbackProvider struct {
+	name   string
+	client openai.Client
+}
+
+func newFallbackProvider(cfg config.FallbackConfig, policy urlPolicy, debugHTTP bool, logger *zap.Logger) *fallbackProvider {
+	opts := []option.RequestOption{
+		option.WithAPIKey(cfg.APIKey),
+		option.WithBaseURL(cfg.BaseURL),
+		option.WithMaxRetries(0),
+		option.WithHTTPClient(policy.httpClient()),
+	}
+	opts = append(opts, headerOptions(cfg.ExtraHeaders)...)
+
+	if debugHTTP {
+		opts = append(opts, option.WithMiddleware(debugHTTPMiddleware(logger, cfg.ExtraHeaders)))
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name = cfg.BaseURL
+	}
+
+	logger.Info("Provider fallback configured",
+		zap.String("fallback", name),
+		zap.String("base_url", cfg.BaseURL))
+
+	return &fallbackProvider{
+		name:   name,
+		client: openai.NewClient(opts...),
+	}
+}
+
+// isRetryableError reports whether another provider might succeed where
+// this one failed: transport errors, timeouts, rate limits and 5xx.
+func isRetryableError(err error) bool {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	default:
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	}
+}
+
+func (c *Client) embedChunkFallback(ctx context.Context, inputs []string, model string, primaryErr error) (*EmbeddingResponse, error) {
+	lastErr := primaryErr
+
+	for _, fallback := range c.fallbacks {
+		if ctx.Err() != nil {
+			break
+		}
+
+		c.logger.Warn("Primary provider failed, trying fallback",
+			zap.String("fallback", fallback.name),
+			zap.Int("chunk_size", len(inputs)),
+			zap.Error(lastErr))
+
+		callStart := time.Now()
+		attemptCtx, cancel := c.attemptContext(ctx)
+		response, err := fallback.client.Embeddings.New(attemptCtx, c.embeddingParams(ctx, inputs, model))
+		cancel()
+		if err != nil && callerCancelled(ctx) {
+			return nil, c.abortCall(ctx, model, len(inputs), time.Since(callStart))
+		}
+
+		if err == nil {
+			var embeddingResponse *EmbeddingResponse
+			embeddingResponse, err = c.toEmbeddingResponse(response, len(inputs))
+			if err == nil {
+				embeddingResponse.Provider = fallback.name
+				c.logProviderCall(fallback.name, model, len(inputs), embeddingResponse.TokenUsage.PromptTokens, time.Since(callStart))
+				return embeddingResponse, nil
+			}
+		}
+
+		c.logger.Error("Fallback provider failed",
+			zap.String("fallback", fallback.name),
+			zap.Error(err))
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("all providers failed: %w", lastErr)
+}
+
+// Cacheable reports whether a response may be stored under the shared
+// cache key. Fallback results are only cached when fallbacks are
+// configured as equivalent to the primary.
+func (c *Client) Cacheable(response *EmbeddingResponse) bool {
+	return response.Provider == "" || c.fallbackEquivalent
+}
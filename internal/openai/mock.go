@@ -0,0 +1,165 @@
+package openai
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/tokenizer"
+)
+
+// defaultMockDimensions is the vector size served for models whose native
+// size is unknown and that have no configured dimensions.
+const defaultMockDimensions = 1536
+
+// mockTransport stands in for the provider when openai.mock is enabled. It
+// answers the embeddings and models endpoints in the provider's wire format,
+// so everything above the HTTP layer (chunking, retries, limits, parsing)
+// runs exactly as it would against the real API.
+type mockTransport struct {
+	client *Client
+}
+
+type mockEmbeddingRequest struct {
+	Input      json.RawMessage `json:"input"`
+	Model      string          `json:"model"`
+	Dimensions int             `json:"dimensions"`
+}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/embeddings"):
+		return t.embeddings(req)
+	case req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/models"):
+		return t.models(req)
+	default:
+		return mockResponse(req, http.StatusNotFound, map[string]interface{}{
+			"error": map[string]string{"message": "not supported by mock provider: " + req.URL.Path},
+		})
+	}
+}
+
+func (t *mockTransport) embeddings(req *http.Request) (*http.Response, error) {
+	var body mockEmbeddingRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return mockResponse(req, http.StatusBadRequest, map[string]interface{}{
+			"error": map[string]string{"message": err.Error()},
+		})
+	}
+
+	var inputs []string
+	if err := json.Unmarshal(body.Input, &inputs); err != nil {
+		var input string
+		if err := json.Unmarshal(body.Input, &input); err != nil {
+			return mockResponse(req, http.StatusBadRequest, map[string]interface{}{
+				"error": map[string]string{"message": "input must be a string or an array of strings"},
+			})
+		}
+		inputs = []string{input}
+	}
+
+	dimensions := body.Dimensions
+	if dimensions <= 0 {
+		dimensions = knownMaxDimensions[body.Model]
+	}
+	if dimensions <= 0 {
+		dimensions = defaultMockDimensions
+	}
+
+	multiVector := t.client.ModelConfig(body.Model).MultiVector
+	data := make([]map[string]interface{}, len(inputs))
+	tokens := 0
+	for i, input := range inputs {
+		inputTokens := max(tokenizer.CountTokens(input, body.Model), 1)
+		tokens += inputTokens
+
+		var embedding interface{} = mockVector(input, body.Model, dimensions, 0)
+		if multiVector {
+			vectors := make([][]float64, inputTokens)
+			for j := range vectors {
+				vectors[j] = mockVector(input, body.Model, dimensions, j)
+			}
+			embedding = vectors
+		}
+
+		data[i] = map[string]interface{}{
+			"object":    "embedding",
+			"index":     i,
+			"embedding": embedding,
+		}
+	}
+
+	return mockResponse(req, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data":   data,
+		"model":  body.Model,
+		"usage": map[string]int{
+			"prompt_tokens": tokens,
+			"total_tokens":  tokens,
+		},
+	})
+}
+
+func (t *mockTransport) models(req *http.Request) (*http.Response, error) {
+	models := t.client.configuredModels()
+	data := make([]map[string]interface{}, len(models))
+	for i, model := range models {
+		data[i] = map[string]interface{}{
+			"id":       model,
+			"object":   "model",
+			"owned_by": "mock",
+		}
+	}
+
+	return mockResponse(req, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+// mockVector derives a unit-length pseudo-random vector from the input, so
+// the same input and model always embed identically.
+func mockVector(input, model string, dimensions, position int) []float64 {
+	seed := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d", model, input, position)))
+	rng := rand.New(rand.NewPCG(binary.LittleEndian.Uint64(seed[0:8]), binary.LittleEndian.Uint64(seed[8:16])))
+
+	vector := make([]float64, dimensions)
+	var sum float64
+	for i := range vector {
+		vector[i] = rng.Float64()*2 - 1
+		sum += vector[i] * vector[i]
+	}
+
+	norm := math.Sqrt(sum)
+	for i := range vector {
+		vector[i] = float64(float32(vector[i] / norm))
+	}
+
+	return vector
+}
+
+func mockResponse(req *http.Request, status int, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(payload)),
+		ContentLength: int64(len(payload)),
+		Request:       req,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package openai
+
+// Validation states reported by Models.
+const (
+	ModelListed    = "listed"     // the provider's model list includes it
+	ModelNotListed = "not_listed" // the provider's model list does not include it
+	ModelUnchecked = "unchecked"  // the model list could not be fetched
+)
+
+type ModelInfo struct {
+	Name          string `json:"name"`
+	Default       bool   `json:"default,omitempty"`
+	Dimensions    int    `json:"dimensions,omitempty"`
+	MaxDimensions int    `json:"max_dimensions,omitempty"`
+	MaxInputChars int    `json:"max_input_chars"`
+	MultiVector   bool   `json:"multi_vector,omitempty"`
+	Validation    string `json:"validation"`
+}
+
+// Models describes every model this proxy serves, default first, with the
+// outcome of the startup check against the provider's model list.
+func (c *Client) Models() []ModelInfo {
+	c.providerModelsMutex.RLock()
+	available := c.providerModels
+	c.providerModelsMutex.RUnlock()
+
+	names := c.configuredModels()
+	models := make([]ModelInfo, len(names))
+	for i, name := range names {
+		modelConfig := c.ModelConfig(name)
+
+		dimensions := modelConfig.Dimensions
+		if dimensions == 0 {
+			dimensions = knownMaxDimensions[name]
+		}
+
+		validation := ModelUnchecked
+		if available != nil {
+			validation = ModelNotListed
+			if available[name] {
+				validation = ModelListed
+			}
+		}
+
+		models[i] = ModelInfo{
+			Name:          name,
+			Default:       name == c.model,
+			Dimensions:    dimensions,
+			MaxDimensions: c.MaxDimensions(name),
+			MaxInputChars: modelConfig.MaxInputChars,
+			MultiVector:   modelConfig.MultiVector,
+			Validation:    validation,
+		}
+	}
+
+	return models
+}
@@ -0,0 +1,178 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/vectorcheck"
+)
+
+type MultiVectorResponse struct {
+	Embeddings    [][][]float64
+	Model         string
+	ProviderModel string
+	TokenUsage    struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	}
+}
+
+// multiVectorPayload mirrors the embeddings response of providers that
+// return one vector per token instead of a single pooled vector.
+type multiVectorPayload struct {
+	Data []struct {
+		Index     int         `json:"index"`
+		Embedding [][]float64 `json:"embedding"`
+	} `json:"data"`
+	Model string `json:"model"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func (c *Client) CreateMultiVectorEmbeddings(ctx context.Context, inputs []string, model string) (*MultiVectorResponse, error) {
+	if model == "" {
+		model = c.model
+	}
+
+	if !c.IsModelAllowed(model) {
+		return nil, fmt.Errorf("model %q is not allowed", model)
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("input array cannot be empty")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	budget := newRetryBudget(c.retryBudget)
+	result := &MultiVectorResponse{
+		Embeddings: make([][][]float64, 0, len(inputs)),
+		Model:      model,
+	}
+
+	for _, bounds := range c.splitChunks(inputs, model) {
+		chunk, err := c.embedMultiVectorChunk(ctx, inputs[bounds[0]:bounds[1]], model, budget)
+		if err != nil {
+			return nil, c.timeoutError(ctx, err)
+		}
+
+		result.Embeddings = append(result.Embeddings, chunk.Embeddings...)
+		if chunk.Model != model {
+			result.ProviderModel = chunk.Model
+			c.noteModelMismatch(model, chunk.Model)
+		}
+		result.TokenUsage.PromptTokens += chunk.TokenUsage.PromptTokens
+		result.TokenUsage.TotalTokens += chunk.TokenUsage.TotalTokens
+	}
+
+	c.logger.Info("Successfully created multi-vector embeddings",
+		zap.String("model", result.Model),
+		zap.Int("batch_size", len(result.Embeddings)),
+		zap.Int("retries_used", c.retryBudget-budget.left()),
+		zap.Int("prompt_tokens", result.TokenUsage.PromptTokens))
+
+	return result, nil
+}
+
+func (c *Client) embedMultiVectorChunk(ctx context.Context, inputs []string, model string, budget *retryBudget) (*MultiVectorResponse, error) {
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if attempt > c.maxRetries || !budget.take() {
+				return nil, fmt.Errorf("failed to create multi-vector embeddings after %d attempts: %w", attempt, lastErr)
+			}
+
+			c.logger.Warn("Retrying OpenAI multi-vector API call",
+				zap.Int("attempt", attempt),
+				zap.Int("retry_budget_remaining", budget.left()),
+				zap.Error(lastErr))
+		}
+
+		// Every attempt, the first included, honours a rate-limit pause
+		// another chunk has already hit.
+		if err := budget.wait(ctx, time.Duration(attempt)*time.Second); err != nil {
+			return nil, err
+		}
+
+		params := openai.EmbeddingNewParams{
+			Input: openai.EmbeddingNewParamsInputUnion{
+				OfArrayOfStrings: inputs,
+			},
+			Model: openai.EmbeddingModel(model),
+		}
+
+		if user := userFromContext(ctx); user != "" {
+			params.User = openai.String(user)
+		}
+
+		release, err := c.acquire(ctx, model)
+		if err != nil {
+			return nil, err
+		}
+
+		var payload multiVectorPayload
+		callStart := time.Now()
+		attemptCtx, cancel := c.attemptContext(ctx)
+		err = c.client.Post(attemptCtx, "embeddings", params, &payload, c.requestOptions()...)
+		cancel()
+		release()
+		if err != nil && callerCancelled(ctx) {
+			return nil, c.abortCall(ctx, model, len(inputs), time.Since(callStart))
+		}
+		c.errorWindow.record(providerFailure(err))
+		if err != nil {
+			lastErr = err
+			budget.pauseOnRateLimit(err)
+			c.logger.Error("OpenAI multi-vector API call failed",
+				zap.Int("attempt", attempt+1),
+				zap.Error(err))
+			continue
+		}
+
+		embeddings, err := orderMultiVectors(&payload, len(inputs))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		response := &MultiVectorResponse{
+			Embeddings: embeddings,
+			Model:      payload.Model,
+		}
+		response.TokenUsage.PromptTokens = payload.Usage.PromptTokens
+		response.TokenUsage.TotalTokens = payload.Usage.TotalTokens
+
+		c.logProviderCall("primary", model, len(inputs), response.TokenUsage.PromptTokens, time.Since(callStart))
+		return response, nil
+	}
+}
+
+func orderMultiVectors(payload *multiVectorPayload, count int) ([][][]float64, error) {
+	if len(payload.Data) != count {
+		return nil, fmt.Errorf("expected %d multi-vector embeddings, got %d", count, len(payload.Data))
+	}
+
+	embeddings := make([][][]float64, count)
+	for _, data := range payload.Data {
+		if data.Index < 0 || data.Index >= count || embeddings[data.Index] != nil {
+			return nil, fmt.Errorf("invalid multi-vector embedding index %d", data.Index)
+		}
+		if len(data.Embedding) == 0 {
+			return nil, fmt.Errorf("empty multi-vector embedding returned at index %d", data.Index)
+		}
+		if err := vectorcheck.ValidateMatrix(data.Embedding); err != nil {
+			return nil, fmt.Errorf("input %d: provider returned %w", data.Index, err)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+
+	return embeddings, nil
+}
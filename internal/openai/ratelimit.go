@@ -0,0 +1,97 @@
+package openai
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+)
+
+// modelLimiter caps in-flight provider calls and spaces their start times
+// for one model, so a burst against a slow model cannot use up capacity
+// that other models need.
+type modelLimiter struct {
+	slots    chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newModelLimiters(models []config.ModelConfig) map[string]*modelLimiter {
+	limiters := make(map[string]*modelLimiter)
+
+	for _, model := range models {
+		if model.MaxConcurrency == 0 && model.QPS == 0 {
+			continue
+		}
+
+		limiter := &modelLimiter{}
+		if model.MaxConcurrency > 0 {
+			limiter.slots = make(chan struct{}, model.MaxConcurrency)
+		}
+		if model.QPS > 0 {
+			limiter.interval = time.Duration(float64(time.Second) / model.QPS)
+		}
+		limiters[model.Name] = limiter
+	}
+
+	return limiters
+}
+
+// acquire blocks until a call to the provider for model may start. The
+// returned release must be called once the call has finished.
+func (c *Client) acquire(ctx context.Context, model string) (func(), error) {
+	limiter, ok := c.limiters[model]
+	if !ok {
+		return func() {}, nil
+	}
+
+	waitStart := time.Now()
+
+	if limiter.slots != nil {
+		select {
+		case limiter.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		if limiter.slots != nil {
+			<-limiter.slots
+		}
+	}
+
+	if limiter.interval > 0 {
+		limiter.mu.Lock()
+		start := time.Now()
+		if limiter.next.After(start) {
+			start = limiter.next
+		}
+		limiter.next = start.Add(limiter.interval)
+		limiter.mu.Unlock()
+
+		if delay := time.Until(start); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				release()
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	if waited := time.Since(waitStart); waited > 100*time.Millisecond {
+		c.logger.Debug("Provider call delayed by model rate limit",
+			zap.String("model", model),
+			zap.Duration("waited", waited))
+	}
+
+	return release, nil
+}
@@ -0,0 +1,38 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutError marks a provider call that ran out of time. Timeout is the
+// configured limit that was hit: openai.timeout_sec for the whole call, or
+// per_request_timeout_sec when every attempt timed out on its own.
+type TimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("provider call timed out after %s: %v", e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// timeoutError wraps err in a TimeoutError when it is a deadline, given
+// the call's context ctx. Other errors are returned unchanged.
+func (c *Client) timeoutError(ctx context.Context, err error) error {
+	var timeoutErr *TimeoutError
+	if !errors.Is(err, context.DeadlineExceeded) || errors.As(err, &timeoutErr) {
+		return err
+	}
+
+	timeout := c.timeout
+	if ctx.Err() == nil && c.perRequestTimeout > 0 {
+		timeout = c.perRequestTimeout
+	}
+
+	return &TimeoutError{Timeout: timeout, Err: err}
+}
@@ -0,0 +1,19 @@
+package openai
+
+import "context"
+
+type userContextKey struct{}
+
+// WithUser attaches an end-user identifier that is forwarded as the `user`
+// parameter on provider calls made with the returned context.
+func WithUser(ctx context.Context, user string) context.Context {
+	if user == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+func userFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userContextKey{}).(string)
+	return user
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var (
+	errQueueFull    = errors.New("request queue is full")
+	errQueueTimeout = errors.New("timed out waiting in the request queue")
+)
+
+// admission caps in-flight requests. When every slot is taken, up to
+// maxQueue requests wait in FIFO order for up to timeout before being shed.
+type admission struct {
+	mu       sync.Mutex
+	limit    int
+	inFlight int
+	waiting  *list.List // of chan struct{}, closed when a slot is handed over
+	maxQueue int
+	timeout  time.Duration
+	timedOut int64
+	rejected int64
+}
+
+func newAdmission(limit, maxQueue int, timeout time.Duration) *admission {
+	return &admission{
+		limit:    limit,
+		waiting:  list.New(),
+		maxQueue: maxQueue,
+		timeout:  timeout,
+	}
+}
+
+func (a *admission) acquire(ctx context.Context) error {
+	a.mu.Lock()
+	if a.inFlight < a.limit && a.waiting.Len() == 0 {
+		a.inFlight++
+		a.mu.Unlock()
+		return nil
+	}
+	if a.waiting.Len() >= a.maxQueue {
+		a.rejected++
+		a.mu.Unlock()
+		return errQueueFull
+	}
+	ready := make(chan struct{})
+	elem := a.waiting.PushBack(ready)
+	a.mu.Unlock()
+
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-timer.C:
+		err = errQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case <-ready:
+		// The slot was handed over while we were giving up; keep it.
+		return nil
+	default:
+	}
+	a.waiting.Remove(elem)
+	if errors.Is(err, errQueueTimeout) {
+		a.timedOut++
+	}
+	return err
+}
+
+// release frees a slot, handing it straight to the oldest waiter if any.
+func (a *admission) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if front := a.waiting.Front(); front != nil {
+		a.waiting.Remove(front)
+		close(front.Value.(chan struct{}))
+		return
+	}
+	a.inFlight--
+}
+
+func (a *admission) stats() map[string]interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return map[string]interface{}{
+		"in_flight":       a.inFlight,
+		"max_concurrent":  a.limit,
+		"queue_depth":     a.waiting.Len(),
+		"max_queue_depth": a.maxQueue,
+		"queue_timeouts":  a.timedOut,
+		"queue_rejected":  a.rejected,
+	}
+}
+
+func admissionMiddleware(a *admission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if strings.HasSuffix(path, "/healthz") || strings.HasSuffix(path, "/readyz") {
+			c.Next()
+			return
+		}
+
+		if err := a.acquire(c.Request.Context()); err != nil {
+			if errors.Is(err, context.Canceled) {
+				addLogFields(c, zap.String("error_category", "client_closed"))
+				c.AbortWithStatus(statusClientClosedRequest)
+				return
+			}
+
+			details := "Too many concurrent requests, retry later"
+			if errors.Is(err, errQueueTimeout) {
+				details = "Timed out waiting for a free request slot, retry later"
+			}
+			addLogFields(c, zap.String("error_category", "overloaded"))
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, ErrorResponse{
+				Error:   "Service overloaded",
+				Code:    http.StatusServiceUnavailable,
+				Details: details,
+			})
+			return
+		}
+		defer a.release()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/asyncjobs"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+)
+
+func (s *Server) handleEmbedAsync(c *gin.Context) {
+	if s.config.MaxBodyBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.config.MaxBodyBytes)
+	}
+
+	var req cache.AsyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response := invalidBodyResponse(err)
+		addLogFields(c, zap.String("error_category", response.Reason))
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	t := tenantFromContext(c)
+	if t != nil {
+		req.Tenant = t.ID
+	}
+
+	callbackURL, err := s.asyncJobs.CallbackURL(req.CallbackURL)
+	if err == nil {
+		err = s.cache.ValidateAsyncRequest(&req, s.asyncJobs.MaxInputs())
+	}
+	if err != nil {
+		addLogFields(c, zap.String("error_category", "validation"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	path := strings.TrimSuffix(c.Request.URL.Path, "/")
+	var budget func() error
+	if t != nil {
+		budget = func() error {
+			if s.tenants.Exhausted(t) {
+				return fmt.Errorf("tenant %s has used its daily budget of %d tokens", t.ID, t.TokenBudget)
+			}
+			return nil
+		}
+	}
+
+	job, err := s.asyncJobs.Start(s.cache, &req, callbackURL, path, budget, func(response *cache.EmbeddingResponse) {
+		if s.tenants != nil && req.Tenant != "" {
+			s.tenants.Record(req.Tenant, response.TokenUsage.TotalTokens, len(response.CachedItems), countCachedItems(response))
+		}
+	})
+	if errors.Is(err, asyncjobs.ErrTooManyJobs) {
+		addLogFields(c, zap.String("error_category", "too_many_jobs"))
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{
+			Error:   "Too many async jobs",
+			Code:    http.StatusTooManyRequests,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.Header("Location", path+"/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (s *Server) handleEmbedAsyncJob(c *gin.Context) {
+	job, _, ok := s.asyncJobs.Get(c.Param("id"), asyncTenant(c))
+	if !ok {
+		c.JSON(http.StatusNotFound, asyncJobNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (s *Server) handleEmbedAsyncResult(c *gin.Context) {
+	_, result, ok := s.asyncJobs.Get(c.Param("id"), asyncTenant(c))
+	if !ok {
+		c.JSON(http.StatusNotFound, asyncJobNotFound)
+		return
+	}
+
+	if result == nil {
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "Async job still running",
+			Code:    http.StatusConflict,
+			Details: "Poll the job until its status is no longer running",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Server) handleEmbedAsyncCancel(c *gin.Context) {
+	job, ok := s.asyncJobs.Cancel(c.Param("id"), asyncTenant(c))
+	if !ok {
+		c.JSON(http.StatusNotFound, asyncJobNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+var asyncJobNotFound = ErrorResponse{
+	Error:   "Async job not found",
+	Code:    http.StatusNotFound,
+	Details: "Unknown job id, or the job finished more than async.job_ttl_sec ago",
+}
+
+// asyncTenant scopes job lookups, so tenants only see their own jobs.
+func asyncTenant(c *gin.Context) string {
+	if t := tenantFromContext(c); t != nil {
+		return t.ID
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Reasons reported in ErrorResponse.Reason for bodies that cannot be
+// decoded, so clients can tell a broken payload from a wrong one.
+const (
+	reasonInvalidJSON  = "invalid_json"
+	reasonInvalidShape = "invalid_shape"
+	reasonMissingField = "missing_field"
+)
+
+// bodyError is a decode failure with a known reason.
+type bodyError struct {
+	reason string
+	err    error
+}
+
+func (e *bodyError) Error() string { return e.err.Error() }
+
+func (e *bodyError) Unwrap() error { return e.err }
+
+func shapeError(format string, args ...interface{}) error {
+	return &bodyError{reason: reasonInvalidShape, err: fmt.Errorf(format, args...)}
+}
+
+// invalidBodyResponse turns a JSON decode or binding error into a 400 that
+// says whether the body was not JSON at all, JSON of the wrong shape, or
+// missing a required field.
+func invalidBodyResponse(err error) ErrorResponse {
+	reason, details := describeBodyError(err)
+
+	title := "Invalid request body"
+	switch reason {
+	case reasonInvalidJSON:
+		title = "Request body is not valid JSON"
+	case reasonInvalidShape:
+		title = "Request body has the wrong shape"
+	case reasonMissingField:
+		title = "Missing required field"
+	}
+
+	return ErrorResponse{
+		Error:   title,
+		Code:    http.StatusBadRequest,
+		Reason:  reason,
+		Details: details,
+	}
+}
+
+func describeBodyError(err error) (string, string) {
+	var bodyErr *bodyError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var validationErrs validator.ValidationErrors
+
+	switch {
+	case errors.As(err, &bodyErr):
+		return bodyErr.reason, bodyErr.Error()
+	case errors.Is(err, io.EOF):
+		return reasonInvalidJSON, "request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return reasonInvalidJSON, "request body is truncated"
+	case errors.As(err, &syntaxErr):
+		return reasonInvalidJSON, fmt.Sprintf("%s at offset %d", syntaxErr.Error(), syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return reasonInvalidShape, fmt.Sprintf("request body must be a JSON object, got %s", typeErr.Value)
+		}
+		return reasonInvalidShape, fmt.Sprintf("field %q must be %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type.Kind().String()), typeErr.Value)
+	case errors.As(err, &validationErrs) && len(validationErrs) > 0:
+		fields := make([]string, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			fields[i] = fmt.Sprintf("%q", strings.ToLower(fieldErr.Field()))
+		}
+		return reasonMissingField, fmt.Sprintf("required field %s is missing or empty", strings.Join(fields, ", "))
+	default:
+		return reasonInvalidShape, err.Error()
+	}
+}
+
+// jsonTypeName maps a Go kind to the JSON type a client would send.
+func jsonTypeName(kind string) string {
+	switch {
+	case kind == "string":
+		return "a string"
+	case kind == "bool":
+		return "a boolean"
+	case kind == "slice" || kind == "array":
+		return "an array"
+	case kind == "struct" || kind == "map":
+		return "an object"
+	case strings.HasPrefix(kind, "int") || strings.HasPrefix(kind, "uint") || strings.HasPrefix(kind, "float"):
+		return "a number"
+	default:
+		return kind
+	}
+}
+
+// jsonTokenKind describes a token from json.Decoder.Token for messages.
+func jsonTokenKind(token json.Token) string {
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '[' {
+			return "an array"
+		}
+		return "an object"
+	case string:
+		return "a string"
+	case float64, json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case nil:
+		return "null"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", token)
+	}
+}
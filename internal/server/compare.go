@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+)
+
+// handleCompare serves POST /embed/compare, a diagnostic that embeds two
+// inputs and reports how far apart they are.
+func (s *Server) handleCompare(c *gin.Context) {
+	var req cache.CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		addLogFields(c, zap.String("error_category", "invalid_body"))
+		c.JSON(http.StatusBadRequest, invalidBodyResponse(err))
+		return
+	}
+
+	if t := tenantFromContext(c); t != nil {
+		req.Tenant = t.ID
+	}
+	if req.User == "" {
+		req.User = req.Tenant
+	}
+	if req.User == "" {
+		req.User = apiKeyID(c.GetHeader("Authorization"))
+	}
+
+	if err := s.cache.ValidateCompareRequest(&req); err != nil {
+		addLogFields(c, zap.String("error_category", "validation"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), embedRequestTimeout)
+	defer cancel()
+
+	result, err := s.cache.Compare(ctx, &req)
+	if errors.Is(err, database.ErrOverloaded) {
+		addLogFields(c, zap.String("error_category", "db_overloaded"))
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "Service overloaded",
+			Code:    http.StatusServiceUnavailable,
+			Details: "No database connection available, retry later",
+		})
+		return
+	}
+	if timeoutResponse, ok := providerTimeoutResponse(err); ok {
+		addLogFields(c, zap.String("error_category", "provider_timeout"))
+		c.JSON(http.StatusGatewayTimeout, timeoutResponse)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to compare embeddings",
+			zap.Error(err),
+			zap.String("client_ip", c.ClientIP()))
+
+		addLogFields(c, zap.String("error_category", "processing"))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to compare embeddings",
+			Code:    http.StatusInternalServerError,
+			Details: "Internal server error",
+		})
+		return
+	}
+
+	if s.tenants != nil && req.Tenant != "" {
+		cachedItems := 0
+		for _, cached := range []bool{result.CachedA, result.CachedB} {
+			if cached {
+				cachedItems++
+			}
+		}
+		s.tenants.Record(req.Tenant, result.TokenUsage.TotalTokens, 2, cachedItems)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
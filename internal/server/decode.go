@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+)
+
+// limitError is returned by decodeEmbedRequest when the body is valid JSON
+// but breaks a request limit, so it can be reported as a validation failure.
+type limitError struct {
+	err error
+}
+
+func (e *limitError) Error() string { return e.err.Error() }
+
+// decodeEmbedRequest reads an embed request with a token-streaming decoder.
+// Array inputs are decoded one item at a time, so an oversized batch is
+// rejected as soon as it crosses the item limit instead of after the whole
+// body has been buffered. Per-item length is checked while streaming when
+// "model" precedes "input"; otherwise ValidateRequest catches it afterwards.
+func decodeEmbedRequest(r io.Reader, maxInputChars func(model string) int) (*cache.EmbeddingRequest, error) {
+	decoder := json.NewDecoder(r)
+
+	req, err := decodeEmbedObject(decoder, maxInputChars)
+	if errors.Is(err, io.EOF) && decoder.InputOffset() > 0 {
+		// Token reports a body that ends mid-object as a plain EOF.
+		err = io.ErrUnexpectedEOF
+	}
+	return req, err
+}
+
+func decodeEmbedObject(decoder *json.Decoder, maxInputChars func(model string) int) (*cache.EmbeddingRequest, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, shapeError("request body must be a JSON object, got %s", jsonTokenKind(token))
+	}
+
+	var req cache.EmbeddingRequest
+	modelSeen := false
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid object key %v", token)
+		}
+
+		switch key {
+		case "input":
+			limit := 0
+			if modelSeen {
+				limit = maxInputChars(req.Model)
+			}
+			req.Input, err = decodeInput(decoder, limit)
+		case "model":
+			err = decoder.Decode(&req.Model)
+			modelSeen = true
+		case "normalize":
+			err = decoder.Decode(&req.Normalize)
+		case "user":
+			err = decoder.Decode(&req.User)
+		case "dimensions":
+			err = decoder.Decode(&req.Dimensions)
+		case "formats":
+			err = decoder.Decode(&req.Formats)
+		case "max_age":
+			err = decoder.Decode(&req.MaxAge)
+		default:
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if typeErr := (*json.UnmarshalTypeError)(nil); errors.As(err, &typeErr) && typeErr.Field == "" {
+			typeErr.Field = key
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := expectDelim(decoder, '}'); err != nil {
+		return nil, err
+	}
+
+	if req.Input == nil {
+		return nil, &bodyError{reason: reasonMissingField, err: fmt.Errorf("required field \"input\" is missing")}
+	}
+
+	return &req, nil
+}
+
+func decodeInput(decoder *json.Decoder, maxChars int) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('['):
+		return decodeInputArray(decoder, maxChars)
+	case json.Delim('{'):
+		object := map[string]interface{}{}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			object[fmt.Sprint(keyToken)] = value
+		}
+		return object, expectDelim(decoder, '}')
+	case nil:
+		return nil, nil
+	default:
+		if _, ok := token.(string); !ok {
+			return nil, shapeError("field \"input\" must be a string, an array or an object, got %s", jsonTokenKind(token))
+		}
+		return token, nil
+	}
+}
+
+func decodeInputArray(decoder *json.Decoder, maxChars int) (interface{}, error) {
+	items := make([]interface{}, 0, 64)
+	for decoder.More() {
+		if len(items) == cache.MaxBatchSize {
+			return nil, &limitError{fmt.Errorf("batch input too large (max %d items)", cache.MaxBatchSize)}
+		}
+
+		var item interface{}
+		if err := decoder.Decode(&item); err != nil {
+			return nil, err
+		}
+
+		switch item.(type) {
+		case string, map[string]interface{}:
+		default:
+			return nil, shapeError("batch input item at index %d must be a string or an object, got %s", len(items), jsonTokenKind(item))
+		}
+
+		if text, ok := item.(string); ok && maxChars > 0 && len(text) > maxChars {
+			return nil, &limitError{fmt.Errorf("batch input item at index %d too long (max %d characters)", len(items), maxChars)}
+		}
+
+		items = append(items, item)
+	}
+
+	if err := expectDelim(decoder, ']'); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func expectDelim(decoder *json.Decoder, want json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, token)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+)
+
+// handleHash serves POST /hash, a diagnostic that shows how inputs are
+// normalized and keyed without touching the cache or the provider.
+func (s *Server) handleHash(c *gin.Context) {
+	var req cache.EmbeddingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		addLogFields(c, zap.String("error_category", "invalid_body"))
+		c.JSON(http.StatusBadRequest, invalidBodyResponse(err))
+		return
+	}
+
+	if t := tenantFromContext(c); t != nil {
+		req.Tenant = t.ID
+	}
+
+	result, err := s.cache.HashInputs(&req)
+	if err != nil {
+		addLogFields(c, zap.String("error_category", "validation"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+)
+
+// handleEmbedLookup serves GET /embed: a read-only lookup that never calls
+// the provider. Responses carry an ETag derived from the cache key and the
+// entry's last update, so clients can revalidate with If-None-Match.
+func (s *Server) handleEmbedLookup(c *gin.Context) {
+	input := c.Query("input")
+	if input == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: "query parameter input is required",
+		})
+		return
+	}
+
+	req := &cache.EmbeddingRequest{
+		Input: input,
+		Model: c.Query("model"),
+	}
+	if value := c.Query("normalize"); value != "" {
+		normalize, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Validation failed",
+				Code:    http.StatusBadRequest,
+				Details: fmt.Sprintf("invalid normalize value %q", value),
+			})
+			return
+		}
+		req.Normalize = &normalize
+	}
+	if value := c.Query("dimensions"); value != "" {
+		dimensions, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Validation failed",
+				Code:    http.StatusBadRequest,
+				Details: fmt.Sprintf("invalid dimensions value %q", value),
+			})
+			return
+		}
+		req.Dimensions = dimensions
+	}
+	if value := c.Query("max_age"); value != "" {
+		maxAge, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Validation failed",
+				Code:    http.StatusBadRequest,
+				Details: fmt.Sprintf("invalid max_age value %q", value),
+			})
+			return
+		}
+		req.MaxAge = maxAge
+	}
+	if t := tenantFromContext(c); t != nil {
+		req.Tenant = t.ID
+	}
+
+	if err := s.cache.ValidateRequest(req); err != nil {
+		addLogFields(c, zap.String("error_category", "validation"))
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	result, err := s.cache.Lookup(ctx, req)
+	if errors.Is(err, database.ErrOverloaded) {
+		addLogFields(c, zap.String("error_category", "db_overloaded"))
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "Service overloaded",
+			Code:    http.StatusServiceUnavailable,
+			Details: "No database connection available, retry later",
+		})
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to look up embedding",
+			zap.Error(err),
+			zap.String("client_ip", c.ClientIP()))
+
+		addLogFields(c, zap.String("error_category", "processing"))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to look up embedding",
+			Code:    http.StatusInternalServerError,
+			Details: "Internal server error",
+		})
+		return
+	}
+
+	if s.tenants != nil {
+		c.Header("Vary", "Authorization")
+	}
+
+	if result == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Embedding not cached",
+			Code:    http.StatusNotFound,
+			Details: "POST /embed to compute it",
+		})
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%s"`, result.InputHash, result.Digest)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", s.lookupCacheControl())
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	s.cache.RoundForResponse(result.Response)
+	writeEmbedResponse(c, http.StatusOK, result.Response)
+}
+
+func (s *Server) lookupCacheControl() string {
+	if s.config.LookupMaxAgeSec <= 0 {
+		return "no-cache"
+	}
+
+	scope := "public"
+	if s.tenants != nil {
+		scope = "private"
+	}
+	return fmt.Sprintf("%s, max-age=%d", scope, s.config.LookupMaxAgeSec)
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type vacuumRequest struct {
+	Reindex *bool `json:"reindex,omitempty"`
+}
+
+// handleVacuum serves POST /maintenance/vacuum. Only one maintenance run is
+// allowed at a time; a second request gets 409 while the first is running.
+func (s *Server) handleVacuum(c *gin.Context) {
+	var req vacuumRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, invalidBodyResponse(err))
+			return
+		}
+	}
+	reindex := req.Reindex == nil || *req.Reindex
+
+	if !s.maintenance.TryLock() {
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "Maintenance already running",
+			Code:    http.StatusConflict,
+			Details: "Wait for the current run to finish",
+		})
+		return
+	}
+	defer s.maintenance.Unlock()
+
+	s.logger.Info("Starting cache maintenance",
+		zap.Bool("reindex", reindex),
+		zap.String("client_ip", c.ClientIP()))
+
+	start := time.Now()
+	results, err := s.cache.Vacuum(c.Request.Context(), reindex)
+	if err != nil {
+		s.logger.Error("Cache maintenance failed",
+			zap.Error(err),
+			zap.Duration("duration", time.Since(start)))
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":       "Cache maintenance failed",
+			"code":        http.StatusInternalServerError,
+			"details":     err.Error(),
+			"tables":      results,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tables":      results,
+		"reindex":     reindex,
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/meilisearch"
+)
+
+// maxMeilisearchWarmupDocuments caps how many documents one warmup reads,
+// since their texts are held in memory until the warmup runs.
+const maxMeilisearchWarmupDocuments = 100000
+
+type meilisearchWarmupRequest struct {
+	Host      string `json:"host" binding:"required"`
+	APIKey    string `json:"api_key,omitempty"`
+	Index     string `json:"index" binding:"required"`
+	Field     string `json:"field" binding:"required"`
+	Model     string `json:"model,omitempty"`
+	BatchSize int    `json:"batch_size,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+}
+
+// handleMeilisearchWarmup serves POST /warmup/meilisearch: it reads field
+// from every document of a Meilisearch index and warms the cache with the
+// distinct values, like POST /warmup with those values as inputs.
+func (s *Server) handleMeilisearchWarmup(c *gin.Context) {
+	var req meilisearchWarmupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, invalidBodyResponse(err))
+		return
+	}
+
+	if req.Limit < 0 || req.Limit > maxMeilisearchWarmupDocuments {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: fmt.Sprintf("limit must be between 1 and %d, or 0 for the maximum", maxMeilisearchWarmupDocuments),
+		})
+		return
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = maxMeilisearchWarmupDocuments
+	}
+
+	client, err := meilisearch.New(req.Host, req.APIKey, s.config.MeilisearchAllowedHosts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	warmup := cache.WarmupRequest{
+		Model:     req.Model,
+		BatchSize: req.BatchSize,
+	}
+	seen := make(map[string]bool)
+	values := 0
+
+	err = client.FieldValues(c.Request.Context(), req.Index, req.Field, meilisearch.DefaultPageSize, limit, func(batch []string) error {
+		values += len(batch)
+		for _, value := range batch {
+			if !seen[value] {
+				seen[value] = true
+				warmup.Inputs = append(warmup.Inputs, cache.WarmupInput{Text: value})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to read Meilisearch documents for warmup",
+			zap.String("index", req.Index),
+			zap.Error(err))
+
+		c.JSON(http.StatusBadGateway, ErrorResponse{
+			Error:   "Failed to read Meilisearch documents",
+			Code:    http.StatusBadGateway,
+			Details: "Meilisearch request failed; see the server logs",
+		})
+		return
+	}
+
+	s.logger.Info("Read Meilisearch documents for warmup",
+		zap.String("index", req.Index),
+		zap.String("field", req.Field),
+		zap.Int("values", values),
+		zap.Int("distinct_inputs", len(warmup.Inputs)))
+
+	if err := s.cache.ValidateWarmupRequest(&warmup); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if c.Query("async") == "true" {
+		job := s.warmupJobs.Start(s.cache, &warmup)
+		c.Header("Location", strings.TrimSuffix(strings.TrimSuffix(c.Request.URL.Path, "/"), "/meilisearch")+"/"+job.ID)
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	result, err := s.cache.Warmup(c.Request.Context(), &warmup)
+	if err != nil {
+		s.logger.Error("Cache warmup failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to warm up cache",
+			Code:    http.StatusInternalServerError,
+			Details: "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
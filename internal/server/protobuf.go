@@ -0,0 +1,66 @@
+package server
+
+import (
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/meepv1"
+)
+
+const protobufContentType = "application/x-protobuf"
+
+// protobufResponse converts a response to the meep.v1.EmbeddingResponse
+// message in proto/embedding.proto.
+func protobufResponse(response *cache.EmbeddingResponse) *meepv1.EmbeddingResponse {
+	embeddings := response.Embeddings
+	cachedItems := response.CachedItems
+	if embeddings == nil {
+		embeddings = [][]float64{response.Embedding}
+		cachedItems = []bool{response.Cached}
+	}
+
+	message := &meepv1.EmbeddingResponse{
+		Embeddings:     make([]*meepv1.Embedding, len(embeddings)),
+		Model:          response.Model,
+		ProviderModel:  response.ProviderModel,
+		CachedItems:    cachedItems,
+		Partial:        response.Partial,
+		Usage:          &meepv1.Usage{PromptTokens: int32(response.TokenUsage.PromptTokens), TotalTokens: int32(response.TokenUsage.TotalTokens)},
+		EmptyItems:     int32s(response.EmptyItems),
+		TruncatedItems: int32s(response.TruncatedItems),
+	}
+
+	for i, embedding := range embeddings {
+		values := make([]float32, len(embedding))
+		for j, value := range embedding {
+			values[j] = float32(value)
+		}
+		message.Embeddings[i] = &meepv1.Embedding{Values: values}
+	}
+
+	for _, itemError := range response.Errors {
+		message.Errors = append(message.Errors, &meepv1.ItemError{Index: int32(itemError.Index), Error: itemError.Error})
+	}
+
+	if summary := response.Summary; summary != nil {
+		message.Summary = &meepv1.BatchSummary{
+			Total:  int32(summary.Total),
+			Cached: int32(summary.Cached),
+			Fresh:  int32(summary.Fresh),
+			Failed: int32(summary.Failed),
+			Empty:  int32(summary.Empty),
+		}
+	}
+
+	return message
+}
+
+func int32s(values []int) []int32 {
+	if len(values) == 0 {
+		return nil
+	}
+
+	converted := make([]int32, len(values))
+	for i, value := range values {
+		converted[i] = int32(value)
+	}
+	return converted
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/meepv1"
+)
+
+func writeProtobuf(t *testing.T, response *cache.EmbeddingResponse) *meepv1.EmbeddingResponse {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/embed", nil)
+	c.Request.Header.Set("Accept", protobufContentType)
+
+	writeEmbedResponse(c, http.StatusOK, response)
+
+	if got := recorder.Header().Get("Content-Type"); got != protobufContentType {
+		t.Fatalf("Content-Type = %q, want %q", got, protobufContentType)
+	}
+
+	var message meepv1.EmbeddingResponse
+	if err := proto.Unmarshal(recorder.Body.Bytes(), &message); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	return &message
+}
+
+func TestProtobufResponseRoundTrip(t *testing.T) {
+	response := &cache.EmbeddingResponse{
+		Embeddings:     [][]float64{{0.25, -0.5, 1}, nil, {0.125, 0, -1}},
+		Model:          "text-embedding-3-small",
+		ProviderModel:  "text-embedding-3-small-2024",
+		CachedItems:    []bool{true, false, false},
+		Summary:        &cache.BatchSummary{Total: 3, Cached: 1, Fresh: 1, Failed: 1},
+		Partial:        true,
+		Errors:         []cache.ItemError{{Index: 1, Error: "provider returned no embedding"}},
+		TruncatedItems: []int{2},
+	}
+	response.TokenUsage.PromptTokens = 7
+	response.TokenUsage.TotalTokens = 7
+
+	want := &meepv1.EmbeddingResponse{
+		Embeddings: []*meepv1.Embedding{
+			{Values: []float32{0.25, -0.5, 1}},
+			{},
+			{Values: []float32{0.125, 0, -1}},
+		},
+		Model:          "text-embedding-3-small",
+		ProviderModel:  "text-embedding-3-small-2024",
+		CachedItems:    []bool{true, false, false},
+		Partial:        true,
+		Errors:         []*meepv1.ItemError{{Index: 1, Error: "provider returned no embedding"}},
+		Usage:          &meepv1.Usage{PromptTokens: 7, TotalTokens: 7},
+		TruncatedItems: []int32{2},
+		Summary:        &meepv1.BatchSummary{Total: 3, Cached: 1, Fresh: 1, Failed: 1},
+	}
+
+	if got := writeProtobuf(t, response); !proto.Equal(got, want) {
+		t.Errorf("decoded response = %v, want %v", got, want)
+	}
+}
+
+func TestProtobufSingleResponse(t *testing.T) {
+	response := &cache.EmbeddingResponse{
+		Embedding: []float64{0.5, 0.75},
+		Model:     "text-embedding-3-small",
+		Cached:    true,
+	}
+
+	want := &meepv1.EmbeddingResponse{
+		Embeddings:  []*meepv1.Embedding{{Values: []float32{0.5, 0.75}}},
+		Model:       "text-embedding-3-small",
+		CachedItems: []bool{true},
+		Usage:       &meepv1.Usage{},
+	}
+
+	if got := writeProtobuf(t, response); !proto.Equal(got, want) {
+		t.Errorf("decoded response = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// handleCachePurge serves DELETE /cache?used_before=... or ?created_before=...,
+// deleting every entry last used (or created) before the given time.
+func (s *Server) handleCachePurge(c *gin.Context) {
+	usedBefore, usedErr := parseCutoff(c.Query("used_before"))
+	createdBefore, createdErr := parseCutoff(c.Query("created_before"))
+
+	var err error
+	switch {
+	case usedErr != nil:
+		err = fmt.Errorf("used_before: %w", usedErr)
+	case createdErr != nil:
+		err = fmt.Errorf("created_before: %w", createdErr)
+	case usedBefore.IsZero() == createdBefore.IsZero():
+		err = fmt.Errorf("exactly one of used_before or created_before is required")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	startTime := time.Now()
+	var deleted int64
+	filter, cutoff := "used_before", usedBefore
+	if usedBefore.IsZero() {
+		filter, cutoff = "created_before", createdBefore
+		deleted, err = s.cache.DeleteCreatedBefore(c.Request.Context(), cutoff)
+	} else {
+		deleted, err = s.cache.DeleteUsedBefore(c.Request.Context(), cutoff)
+	}
+
+	if err != nil {
+		s.logger.Error("Failed to purge cache entries",
+			zap.String("filter", filter),
+			zap.Time("cutoff", cutoff),
+			zap.Int64("deleted", deleted),
+			zap.Error(err))
+
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to purge cache entries",
+			Code:    http.StatusInternalServerError,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info("Purged cache entries",
+		zap.String("filter", filter),
+		zap.Time("cutoff", cutoff),
+		zap.Int64("deleted", deleted),
+		zap.String("client_ip", c.ClientIP()),
+		zap.Duration("duration", time.Since(startTime)))
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": deleted,
+		filter:    cutoff,
+	})
+}
+
+// parseCutoff accepts an RFC 3339 timestamp or a date, which means midnight
+// UTC. An empty value returns the zero time.
+func parseCutoff(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date (2006-01-02) or RFC 3339 timestamp, got %q", value)
+	}
+	return t, nil
+}
@@ -2,63 +2,234 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/asyncjobs"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/audit"
 	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/idempotency"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/template"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/tenant"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/version"
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/warmupjobs"
 )
 
 type Server struct {
-	engine *gin.Engine
-	logger *zap.Logger
-	cache  *cache.Cache
-	server *http.Server
+	engine      *gin.Engine
+	adminEngine *gin.Engine
+	adminServer *http.Server
+	logger      *zap.Logger
+	cache       *cache.Cache
+	config      *config.ServerConfig
+	template    *template.Template
+	idempotency *idempotency.Store
+	audit       *audit.Recorder
+	tenants     *tenant.Registry
+	warmupJobs  *warmupjobs.Registry
+	asyncJobs   *asyncjobs.Registry // nil unless async callbacks are configured
+	admission   *admission
+	maintenance sync.Mutex
+	ready       atomic.Bool
+	server      *http.Server
+
+	// serversMu guards server and adminServer, which Start and StartAdmin
+	// set from their own goroutines while Shutdown may already be running.
+	serversMu sync.Mutex
+	closed    bool
 }
 
 type HealthResponse struct {
-	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	Version   string    `json:"version"`
+	Status    string                 `json:"status"`
+	Timestamp time.Time              `json:"timestamp"`
+	Version   string                 `json:"version"`
+	Checks    map[string]interface{} `json:"checks,omitempty"`
 }
 
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Code    int    `json:"code"`
+	Reason  string `json:"reason,omitempty"` // machine-readable cause
 	Details string `json:"details,omitempty"`
+
+	TimeoutMs int64 `json:"timeout_ms,omitempty"` // the limit hit, for provider_timeout
 }
 
-func New(cache *cache.Cache, logger *zap.Logger) *Server {
+func New(cache *cache.Cache, auditRecorder *audit.Recorder, tenants *tenant.Registry, asyncJobs *asyncjobs.Registry, cfg *config.ServerConfig, logCfg *config.LoggingConfig, logger *zap.Logger) (*Server, error) {
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
 
+	slowRequest := time.Duration(logCfg.SlowRequestMs) * time.Millisecond
+
 	engine.Use(gin.Recovery())
-	engine.Use(loggingMiddleware(logger))
+	engine.Use(loggingMiddleware(logger, slowRequest))
+
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		engine.Use(corsMiddleware(cfg))
+		logger.Info("CORS enabled",
+			zap.Strings("allowed_origins", cfg.CORSAllowedOrigins))
+	}
+
+	var limiter *admission
+	if cfg.MaxConcurrentRequests > 0 {
+		limiter = newAdmission(cfg.MaxConcurrentRequests, cfg.MaxQueuedRequests, time.Duration(cfg.QueueTimeoutMs)*time.Millisecond)
+		engine.Use(admissionMiddleware(limiter))
+		logger.Info("Concurrent request limit enabled",
+			zap.Int("max_concurrent_requests", cfg.MaxConcurrentRequests),
+			zap.Int("max_queued_requests", cfg.MaxQueuedRequests),
+			zap.Int("queue_timeout_ms", cfg.QueueTimeoutMs))
+	}
 
 	server := &Server{
-		engine: engine,
-		logger: logger,
-		cache:  cache,
+		engine:  engine,
+		logger:  logger,
+		cache:   cache,
+		config:  cfg,
+		audit:   auditRecorder,
+		tenants: tenants,
+
+		admission: limiter,
+		asyncJobs: asyncJobs,
+
+		warmupJobs: warmupjobs.New(time.Duration(cfg.WarmupJobTTLSec)*time.Second, logger),
+	}
+
+	if cfg.ResponseTemplate != "" {
+		tmpl, err := template.Parse(cfg.ResponseTemplate)
+		if err != nil {
+			return nil, err
+		}
+		server.template = tmpl
+		logger.Info("Using custom response template")
+	}
+
+	if cfg.IdempotencyTTLSec > 0 {
+		server.idempotency = idempotency.New(time.Duration(cfg.IdempotencyTTLSec)*time.Second, logger)
+	}
+
+	if cfg.AdminPort > 0 {
+		server.adminEngine = gin.New()
+		server.adminEngine.Use(gin.Recovery())
+		server.adminEngine.Use(loggingMiddleware(logger, slowRequest))
 	}
 
 	server.setupRoutes()
 
-	return server
+	return server, nil
 }
 
 func (s *Server) setupRoutes() {
 	s.engine.GET("/healthz", s.handleHealth)
+	s.engine.GET("/readyz", s.handleReady)
 	s.engine.GET("/", s.handleRoot)
-	s.engine.POST("/embed", s.handleEmbed)
-	s.engine.GET("/stats", s.handleStats)
+	s.engine.GET("/version", s.handleVersion)
+	s.engine.GET("/models", s.handleModels)
+	s.engine.POST("/embed", s.requireTenant, s.handleEmbed)
+	s.engine.GET("/embed", s.requireTenant, s.handleEmbedLookup)
+	s.engine.POST("/embed/compare", s.requireTenant, s.handleCompare)
+	s.engine.POST("/embed/pooled", s.requireTenant, s.handlePooled)
+	s.engine.POST("/hash", s.requireTenant, s.handleHash)
+	if s.asyncJobs != nil {
+		s.engine.POST("/embed/async", s.requireTenant, s.handleEmbedAsync)
+		s.engine.GET("/embed/async/:id", s.requireTenant, s.handleEmbedAsyncJob)
+		s.engine.GET("/embed/async/:id/result", s.requireTenant, s.handleEmbedAsyncResult)
+		s.engine.DELETE("/embed/async/:id", s.requireTenant, s.handleEmbedAsyncCancel)
+	}
 
 	api := s.engine.Group("/api/v1")
 	{
-		api.POST("/embeddings", s.handleEmbed)
-		api.GET("/stats", s.handleStats)
+		api.POST("/embeddings", s.requireTenant, s.handleEmbed)
+		api.GET("/embeddings", s.requireTenant, s.handleEmbedLookup)
+		api.POST("/embeddings/compare", s.requireTenant, s.handleCompare)
+		api.POST("/embeddings/pooled", s.requireTenant, s.handlePooled)
+		api.POST("/hash", s.requireTenant, s.handleHash)
+		if s.asyncJobs != nil {
+			api.POST("/embeddings/async", s.requireTenant, s.handleEmbedAsync)
+			api.GET("/embeddings/async/:id", s.requireTenant, s.handleEmbedAsyncJob)
+			api.GET("/embeddings/async/:id/result", s.requireTenant, s.handleEmbedAsyncResult)
+			api.DELETE("/embeddings/async/:id", s.requireTenant, s.handleEmbedAsyncCancel)
+		}
 		api.GET("/healthz", s.handleHealth)
+		api.GET("/readyz", s.handleReady)
+		api.GET("/version", s.handleVersion)
+		api.GET("/models", s.handleModels)
+	}
+
+	admin := s.engine
+	if s.adminEngine != nil {
+		admin = s.adminEngine
+		admin.GET("/healthz", s.handleHealth)
+	}
+
+	statsHandlers := []gin.HandlerFunc{s.handleStats}
+	if s.config.StatsAccess == "admin" {
+		statsHandlers = []gin.HandlerFunc{s.requireAdminToken, s.handleStats}
+	}
+
+	if s.config.StatsAccess != "disabled" {
+		admin.GET("/stats", statsHandlers...)
+	}
+	admin.POST("/stats/reset", s.requireAdminToken, s.handleStatsReset)
+	admin.POST("/refresh", s.requireAdminToken, s.handleRefresh)
+	admin.POST("/warmup", s.requireAdminToken, s.handleWarmup)
+	admin.POST("/warmup/meilisearch", s.requireAdminToken, s.handleMeilisearchWarmup)
+	admin.GET("/warmup/:id", s.requireAdminToken, s.handleWarmupJob)
+	admin.DELETE("/warmup/:id", s.requireAdminToken, s.handleWarmupCancel)
+	admin.POST("/maintenance/vacuum", s.requireAdminToken, s.handleVacuum)
+	admin.DELETE("/cache", s.requireAdminToken, s.handleCachePurge)
+
+	adminAPI := admin.Group("/api/v1")
+	{
+		if s.config.StatsAccess != "disabled" {
+			adminAPI.GET("/stats", statsHandlers...)
+		}
+		adminAPI.POST("/stats/reset", s.requireAdminToken, s.handleStatsReset)
+		adminAPI.POST("/refresh", s.requireAdminToken, s.handleRefresh)
+		adminAPI.POST("/warmup", s.requireAdminToken, s.handleWarmup)
+		adminAPI.POST("/warmup/meilisearch", s.requireAdminToken, s.handleMeilisearchWarmup)
+		adminAPI.GET("/warmup/:id", s.requireAdminToken, s.handleWarmupJob)
+		adminAPI.DELETE("/warmup/:id", s.requireAdminToken, s.handleWarmupCancel)
+		adminAPI.POST("/maintenance/vacuum", s.requireAdminToken, s.handleVacuum)
+		adminAPI.DELETE("/cache", s.requireAdminToken, s.handleCachePurge)
+	}
+
+	if s.config.EnablePprof {
+		registerPprof(admin.Group("/debug/pprof"))
+		s.logger.Warn("pprof endpoints enabled under /debug/pprof",
+			zap.Bool("admin_port", s.adminEngine != nil))
+	}
+}
+
+func registerPprof(group *gin.RouterGroup) {
+	group.GET("/", gin.WrapF(pprof.Index))
+	group.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/profile", gin.WrapF(pprof.Profile))
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/trace", gin.WrapF(pprof.Trace))
+
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		group.GET("/"+name, gin.WrapH(pprof.Handler(name)))
 	}
 }
 
@@ -66,21 +237,84 @@ func (s *Server) handleHealth(c *gin.Context) {
 	response := HealthResponse{
 		Status:    "healthy",
 		Timestamp: time.Now(),
-		Version:   "1.0.0",
+		Version:   version.Version,
+	}
+
+	if c.Query("deep") == "true" {
+		provider := s.cache.ProviderHealth()
+		response.Checks = map[string]interface{}{
+			"provider": provider,
+		}
+
+		if !provider.Healthy {
+			response.Status = "unhealthy"
+			addLogFields(c, zap.Float64("provider_error_rate", provider.ErrorRate))
+			c.JSON(http.StatusServiceUnavailable, response)
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+func (s *Server) handleReady(c *gin.Context) {
+	if !s.ready.Load() {
+		c.JSON(http.StatusServiceUnavailable, HealthResponse{
+			Status:    "not ready",
+			Timestamp: time.Now(),
+			Version:   version.Version,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, HealthResponse{
+		Status:    "ready",
+		Timestamp: time.Now(),
+		Version:   version.Version,
+	})
+}
+
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+	s.logger.Info("Readiness changed", zap.Bool("ready", ready))
+}
+
+func (s *Server) handleVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
+
+func (s *Server) handleModels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"models": s.cache.Models(),
+	})
+}
+
 func (s *Server) handleRoot(c *gin.Context) {
+	endpoints := map[string]string{
+		"embeddings": "POST /embed or /api/v1/embeddings",
+		"lookup":     "GET /embed?input=... or /api/v1/embeddings?input=...",
+		"compare":    "POST /embed/compare or /api/v1/embeddings/compare",
+		"pooled":     "POST /embed/pooled or /api/v1/embeddings/pooled",
+		"hash":       "POST /hash or /api/v1/hash",
+		"models":     "GET /models or /api/v1/models",
+		"stats":      "GET /stats or /api/v1/stats",
+		"refresh":    "POST /refresh or /api/v1/refresh",
+		"warmup":     "POST /warmup or /api/v1/warmup",
+		"health":     "GET /healthz or /api/v1/healthz",
+		"readiness":  "GET /readyz or /api/v1/readyz",
+		"version":    "GET /version or /api/v1/version",
+	}
+	if s.config.StatsAccess == "disabled" {
+		delete(endpoints, "stats")
+	}
+	if s.asyncJobs != nil {
+		endpoints["async"] = "POST /embed/async or /api/v1/embeddings/async"
+	}
+
 	response := map[string]interface{}{
-		"service": "Meep - Meilisearch Embedder Proxy",
-		"version": "1.0.0",
-		"endpoints": map[string]string{
-			"embeddings": "POST /embed or /api/v1/embeddings",
-			"stats":      "GET /stats or /api/v1/stats",
-			"health":     "GET /healthz or /api/v1/healthz",
-		},
+		"service":   "Meep - Meilisearch Embedder Proxy",
+		"version":   version.Version,
+		"endpoints": endpoints,
 		"timestamp": time.Now(),
 	}
 
@@ -90,25 +324,52 @@ func (s *Server) handleRoot(c *gin.Context) {
 func (s *Server) handleEmbed(c *gin.Context) {
 	startTime := time.Now()
 
-	var req cache.EmbeddingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if s.config.MaxBodyBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.config.MaxBodyBytes)
+	}
+
+	decoded, err := decodeEmbedRequest(c.Request.Body, s.streamingInputLimit)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		var limitErr *limitError
+		if errors.As(err, &limitErr) {
+			addLogFields(c, zap.String("error_category", "validation"))
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Validation failed",
+				Code:    http.StatusBadRequest,
+				Details: err.Error(),
+			})
+			return
+		}
+
+		if errors.As(err, &maxBytesErr) {
+			addLogFields(c, zap.String("error_category", "body_too_large"))
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+				Error:   "Request body too large",
+				Code:    http.StatusRequestEntityTooLarge,
+				Details: fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit),
+			})
+			return
+		}
+
+		response := invalidBodyResponse(err)
 		s.logger.Error("Invalid request body",
 			zap.Error(err),
+			zap.String("reason", response.Reason),
 			zap.String("client_ip", c.ClientIP()))
 
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Code:    http.StatusBadRequest,
-			Details: err.Error(),
-		})
+		addLogFields(c, zap.String("error_category", response.Reason))
+		c.JSON(http.StatusBadRequest, response)
 		return
 	}
+	req := *decoded
 
 	if err := s.cache.ValidateRequest(&req); err != nil {
 		s.logger.Error("Request validation failed",
 			zap.Error(err),
 			zap.String("client_ip", c.ClientIP()))
 
+		addLogFields(c, zap.String("error_category", "validation"))
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error:   "Validation failed",
 			Code:    http.StatusBadRequest,
@@ -117,35 +378,473 @@ func (s *Server) handleEmbed(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	if t := tenantFromContext(c); t != nil {
+		req.Tenant = t.ID
+	}
+
+	key := c.GetHeader("Idempotency-Key")
+	if key != "" && req.Tenant != "" {
+		key = req.Tenant + ":" + key
+	}
+	if key == "" || s.idempotency == nil {
+		status, body := s.processEmbed(c, &req, startTime)
+		writeEmbedResponse(c, status, body)
+		return
+	}
+
+	fingerprint, err := requestFingerprint(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request body",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result, err := s.idempotency.Do(c.Request.Context(), key, fingerprint, func() (int, interface{}) {
+		return s.processEmbed(c, &req, startTime)
+	})
+	if err != nil {
+		if errors.Is(err, idempotency.ErrKeyReused) {
+			addLogFields(c, zap.String("error_category", "idempotency_conflict"))
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+				Error:   "Idempotency key conflict",
+				Code:    http.StatusUnprocessableEntity,
+				Details: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "Request cancelled while waiting for original request",
+			Code:    http.StatusServiceUnavailable,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if result.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+		addLogFields(c, zap.Bool("idempotent_replay", true))
+	}
+
+	writeEmbedResponse(c, result.Status, result.Body)
+}
+
+const binaryContentType = "application/octet-stream"
+
+func isMultiVector(response *cache.EmbeddingResponse) bool {
+	return response.MultiEmbedding != nil || response.MultiEmbeddings != nil
+}
+
+func writeEmbedResponse(c *gin.Context, status int, body interface{}) {
+	response, ok := body.(*cache.EmbeddingResponse)
+	format := c.NegotiateFormat(gin.MIMEJSON, binaryContentType, protobufContentType)
+	if !ok || isMultiVector(response) || response.EmbeddingFormats != nil || (format != binaryContentType && format != protobufContentType) {
+		c.JSON(status, body)
+		return
+	}
+
+	if format == protobufContentType {
+		c.ProtoBuf(status, protobufResponse(response))
+		return
+	}
+
+	embeddings := response.Embeddings
+	if embeddings == nil {
+		embeddings = [][]float64{response.Embedding}
+	}
+
+	c.Header("X-Embedding-Model", response.Model)
+	if len(response.Errors) > 0 {
+		indexes := make([]string, len(response.Errors))
+		for i, itemError := range response.Errors {
+			indexes[i] = strconv.Itoa(itemError.Index)
+		}
+		c.Header("X-Unavailable-Items", strings.Join(indexes, ","))
+	}
+
+	c.Data(status, binaryContentType, encodeBinaryEmbeddings(embeddings))
+}
+
+// encodeBinaryEmbeddings packs embeddings as a little-endian uint32 count and
+// uint32 dimension followed by count*dimension float32 values. Missing
+// embeddings are written as zero vectors.
+func encodeBinaryEmbeddings(embeddings [][]float64) []byte {
+	dim := 0
+	for _, embedding := range embeddings {
+		dim = max(dim, len(embedding))
+	}
+
+	buf := make([]byte, 8+4*len(embeddings)*dim)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(embeddings)))
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(dim))
+
+	offset := 8
+	for _, embedding := range embeddings {
+		for i := 0; i < dim; i++ {
+			if i < len(embedding) {
+				binary.LittleEndian.PutUint32(buf[offset:], math.Float32bits(float32(embedding[i])))
+			}
+			offset += 4
+		}
+	}
+
+	return buf
+}
+
+// streamingInputLimit is the per-item length checked while decoding; 0 when
+// oversized inputs are truncated instead of rejected.
+func (s *Server) streamingInputLimit(model string) int {
+	if !s.cache.RejectsOverLimit() {
+		return 0
+	}
+	return s.cache.MaxInputChars(model)
+}
+
+// statusClientClosedRequest is nginx's non-standard status for a client that
+// disconnected before the response was ready. Nobody receives it; it only
+// shows up in the request log and the audit trail.
+const statusClientClosedRequest = 499
+
+func (s *Server) processEmbed(c *gin.Context, req *cache.EmbeddingRequest, startTime time.Time) (int, interface{}) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), embedRequestTimeout)
 	defer cancel()
 
-	response, err := s.cache.GetEmbedding(ctx, &req)
+	if req.User == "" {
+		req.User = req.Tenant
+	}
+	if req.User == "" {
+		req.User = apiKeyID(c.GetHeader("Authorization"))
+	}
+
+	response, err := s.cache.GetEmbedding(ctx, req)
+	if errors.Is(err, database.ErrOverloaded) {
+		s.logger.Warn("Rejecting embedding request, database overloaded",
+			zap.String("client_ip", c.ClientIP()),
+			zap.Duration("processing_time", time.Since(startTime)))
+
+		addLogFields(c, zap.String("error_category", "db_overloaded"))
+		s.recordAudit(c, req, http.StatusServiceUnavailable, nil, startTime)
+		c.Header("Retry-After", "1")
+		return http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "Service overloaded",
+			Code:    http.StatusServiceUnavailable,
+			Details: "No database connection available, retry later",
+		}
+	}
+
+	if err != nil && errors.Is(c.Request.Context().Err(), context.Canceled) {
+		s.logger.Info("Client disconnected before embedding completed",
+			zap.String("client_ip", c.ClientIP()),
+			zap.Duration("processing_time", time.Since(startTime)))
+
+		addLogFields(c, zap.String("error_category", "client_closed"))
+		s.recordAudit(c, req, statusClientClosedRequest, nil, startTime)
+		return statusClientClosedRequest, ErrorResponse{
+			Error:   "Client closed request",
+			Code:    statusClientClosedRequest,
+			Details: "Request cancelled by client",
+		}
+	}
+
+	if timeoutResponse, ok := providerTimeoutResponse(err); ok {
+		s.logger.Warn("Embedding provider timed out",
+			zap.Error(err),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Int64("timeout_ms", timeoutResponse.TimeoutMs),
+			zap.Duration("processing_time", time.Since(startTime)))
+
+		addLogFields(c, zap.String("error_category", "provider_timeout"))
+		s.recordAudit(c, req, http.StatusGatewayTimeout, nil, startTime)
+		return http.StatusGatewayTimeout, timeoutResponse
+	}
+
 	if err != nil {
 		s.logger.Error("Failed to get embedding",
 			zap.Error(err),
 			zap.String("client_ip", c.ClientIP()),
 			zap.Duration("processing_time", time.Since(startTime)))
 
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
+		addLogFields(c, zap.String("error_category", "processing"))
+		s.recordAudit(c, req, http.StatusInternalServerError, nil, startTime)
+		return http.StatusInternalServerError, ErrorResponse{
 			Error:   "Failed to process embedding request",
 			Code:    http.StatusInternalServerError,
 			Details: "Internal server error",
-		})
-		return
+		}
 	}
 
 	s.logger.Info("Embedding request completed successfully",
 		zap.String("client_ip", c.ClientIP()),
 		zap.String("model", response.Model),
 		zap.Bool("cached", response.Cached),
+		zap.Bool("partial", response.Partial),
 		zap.Duration("processing_time", time.Since(startTime)),
 		zap.Int("vector_length", len(response.Embedding)))
 
-	c.JSON(http.StatusOK, response)
+	status := http.StatusOK
+	if response.Partial {
+		status = http.StatusMultiStatus
+	}
+
+	if c.Query("meta") == "true" {
+		response.Meta = &cache.Meta{
+			EstimatedTokens: s.cache.EstimateTokens(req),
+		}
+	}
+
+	if c.Query("echo") == "true" {
+		response.Inputs = s.cache.EchoInputs(req)
+	}
+
+	s.cache.RoundForResponse(response)
+	addEmbedLogFields(c, response)
+	s.recordAudit(c, req, status, response, startTime)
+
+	if s.tenants != nil && req.Tenant != "" {
+		items := max(len(response.CachedItems), 1)
+		s.tenants.Record(req.Tenant, response.TokenUsage.TotalTokens, items, countCachedItems(response))
+	}
+
+	if s.template != nil && !isMultiVector(response) {
+		embeddings := response.Embeddings
+		if embeddings == nil {
+			embeddings = [][]float64{response.Embedding}
+		}
+		return status, s.template.Render(embeddings, response.Model)
+	}
+
+	cache.ApplyFormats(response, req.Formats)
+	return status, response
+}
+
+func (s *Server) recordAudit(c *gin.Context, req *cache.EmbeddingRequest, status int, response *cache.EmbeddingResponse, startTime time.Time) {
+	if s.audit == nil {
+		return
+	}
+
+	hashes, modelName := s.cache.InputHashes(req)
+
+	cachedItems := 0
+	if response != nil {
+		cachedItems = countCachedItems(response)
+	}
+
+	s.audit.Record(&database.AuditEntry{
+		ClientIP:    c.ClientIP(),
+		APIKeyID:    apiKeyID(c.GetHeader("Authorization")),
+		ModelName:   modelName,
+		InputHashes: hashes,
+		BatchSize:   len(hashes),
+		CachedItems: cachedItems,
+		StatusCode:  status,
+		Latency:     time.Since(startTime),
+		CreatedAt:   startTime,
+	})
+}
+
+func countCachedItems(response *cache.EmbeddingResponse) int {
+	cachedItems := 0
+	if response.Cached {
+		cachedItems = 1
+	}
+	for _, cached := range response.CachedItems {
+		if cached {
+			cachedItems++
+		}
+	}
+	return cachedItems
+}
+
+func apiKeyID(authorization string) string {
+	token := strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	if token == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+func requestFingerprint(req *cache.EmbeddingRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *Server) handleRefresh(c *gin.Context) {
+	startTime := time.Now()
+
+	var req cache.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.logger.Error("Invalid refresh request body",
+			zap.Error(err),
+			zap.String("client_ip", c.ClientIP()))
+
+		c.JSON(http.StatusBadRequest, invalidBodyResponse(err))
+		return
+	}
+
+	if err := s.cache.ValidateRefreshRequest(&req); err != nil {
+		s.logger.Error("Refresh request validation failed",
+			zap.Error(err),
+			zap.String("client_ip", c.ClientIP()))
+
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	results, err := s.cache.Refresh(ctx, &req)
+	if err != nil {
+		s.logger.Error("Failed to refresh embeddings",
+			zap.Error(err),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Duration("processing_time", time.Since(startTime)))
+
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to refresh embeddings",
+			Code:    http.StatusInternalServerError,
+			Details: "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"results":   results,
+		"timestamp": time.Now(),
+	})
+}
+
+func (s *Server) handleWarmup(c *gin.Context) {
+	if s.config.MaxBodyBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.config.MaxBodyBytes)
+	}
+
+	var req cache.WarmupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+				Error:   "Request body too large",
+				Code:    http.StatusRequestEntityTooLarge,
+				Details: fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit),
+			})
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, invalidBodyResponse(err))
+		return
+	}
+
+	if err := s.cache.ValidateWarmupRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    http.StatusBadRequest,
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if c.Query("async") == "true" {
+		job := s.warmupJobs.Start(s.cache, &req)
+		c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+job.ID)
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	result, err := s.cache.Warmup(c.Request.Context(), &req)
+	if err != nil {
+		s.logger.Error("Cache warmup failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to warm up cache",
+			Code:    http.StatusInternalServerError,
+			Details: "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Server) handleWarmupJob(c *gin.Context) {
+	job, ok := s.warmupJobs.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Warmup job not found",
+			Code:    http.StatusNotFound,
+			Details: "Unknown job id, or the job finished more than server.warmup_job_ttl_sec ago",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (s *Server) handleWarmupCancel(c *gin.Context) {
+	job, ok := s.warmupJobs.Cancel(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Warmup job not found",
+			Code:    http.StatusNotFound,
+			Details: "Unknown job id, or the job finished more than server.warmup_job_ttl_sec ago",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (s *Server) handleStatsReset(c *gin.Context) {
+	s.cache.ResetCounters()
+
+	c.JSON(http.StatusOK, gin.H{
+		"reset":     true,
+		"timestamp": time.Now(),
+	})
+}
+
+func (s *Server) requireAdminToken(c *gin.Context) {
+	if s.config.AdminToken == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
+			Error:   "Admin endpoint disabled",
+			Code:    http.StatusForbidden,
+			Details: "Set server.admin_token to enable this endpoint",
+		})
+		return
+	}
+
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+		addLogFields(c, zap.String("error_category", "unauthorized"))
+		c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "Unauthorized",
+			Code:    http.StatusUnauthorized,
+			Details: "Missing or invalid admin token",
+		})
+		return
+	}
+
+	c.Next()
 }
 
 func (s *Server) handleStats(c *gin.Context) {
+	if s.tenants != nil && !s.handleTenantStats(c) {
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
@@ -167,38 +866,149 @@ func (s *Server) handleStats(c *gin.Context) {
 		"stats": stats,
 		"service_info": map[string]interface{}{
 			"service": "Meep - Meilisearch Embedder Proxy",
-			"version": "1.0.0",
+			"version": version.Version,
 			"uptime":  time.Since(time.Now()).String(), // This would need to be tracked from start time
 		},
 		"timestamp": time.Now(),
 	}
 
+	if s.tenants != nil {
+		response["tenants"] = s.tenants.AllStats()
+	}
+
+	if s.admission != nil {
+		response["admission"] = s.admission.stats()
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
 func (s *Server) Start(addr string) error {
-	s.server = &http.Server{
+	server := &http.Server{
 		Addr:         addr,
 		Handler:      s.engine,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 60 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
+	if err := s.register(&s.server, server); err != nil {
+		return err
+	}
+
+	if s.config.UnixSocket != "" {
+		listener, err := s.listenUnix(s.config.UnixSocket)
+		if err != nil {
+			return err
+		}
+
+		s.logger.Info("Starting HTTP server",
+			zap.String("unix_socket", s.config.UnixSocket),
+			zap.String("service", "Meep - Meilisearch Embedder Proxy"))
+
+		return server.Serve(listener)
+	}
 
 	s.logger.Info("Starting HTTP server",
 		zap.String("address", addr),
 		zap.String("service", "Meep - Meilisearch Embedder Proxy"))
 
-	return s.server.ListenAndServe()
+	return server.ListenAndServe()
+}
+
+// register stores a server Start or StartAdmin is about to run, unless
+// Shutdown has already run, in which case it must not start serving.
+func (s *Server) register(target **http.Server, server *http.Server) error {
+	s.serversMu.Lock()
+	defer s.serversMu.Unlock()
+
+	if s.closed {
+		return http.ErrServerClosed
+	}
+	*target = server
+	return nil
+}
+
+func (s *Server) listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("unix socket path %s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale unix socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on unix socket: %w", err)
+	}
+
+	mode, err := strconv.ParseUint(s.config.UnixSocketMode, 8, 32)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("invalid unix socket mode %q: %w", s.config.UnixSocketMode, err)
+	}
+
+	if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set unix socket permissions: %w", err)
+	}
+
+	return listener, nil
+}
+
+func (s *Server) StartAdmin(addr string) error {
+	if s.adminEngine == nil {
+		return fmt.Errorf("admin server is not configured")
+	}
+
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      s.adminEngine,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 120 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+	if err := s.register(&s.adminServer, server); err != nil {
+		return err
+	}
+
+	s.logger.Info("Starting admin HTTP server",
+		zap.String("address", addr))
+
+	return server.ListenAndServe()
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down HTTP server")
+	s.SetReady(false)
+
+	s.warmupJobs.Stop()
+	if s.asyncJobs != nil {
+		s.asyncJobs.Stop()
+	}
+
+	s.serversMu.Lock()
+	s.closed = true
+	server, adminServer := s.server, s.adminServer
+	s.serversMu.Unlock()
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			s.logger.Error("Admin HTTP server shutdown error", zap.Error(err))
+		}
+	}
 
-	return s.server.Shutdown(ctx)
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
 }
 
-func loggingMiddleware(logger *zap.Logger) gin.HandlerFunc {
+// loggingMiddleware logs every request. With a slowRequest threshold set,
+// successful requests are logged at debug unless they take longer than the
+// threshold, which logs them at warn.
+func loggingMiddleware(logger *zap.Logger, slowRequest time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -215,13 +1025,108 @@ func loggingMiddleware(logger *zap.Logger) gin.HandlerFunc {
 			path = path + "?" + raw
 		}
 
-		logger.Info("HTTP request",
+		fields := []zap.Field{
 			zap.String("method", method),
 			zap.String("path", path),
 			zap.String("client_ip", clientIP),
 			zap.Int("status_code", statusCode),
 			zap.Duration("latency", latency),
 			zap.Int("response_size", c.Writer.Size()),
-		)
+		}
+
+		if extra, ok := c.Get(logFieldsKey); ok {
+			fields = append(fields, extra.([]zap.Field)...)
+		}
+
+		switch {
+		case statusCode >= http.StatusInternalServerError:
+			logger.Error("HTTP request", fields...)
+		case statusCode >= http.StatusBadRequest:
+			logger.Warn("HTTP request", fields...)
+		case slowRequest > 0 && latency > slowRequest:
+			logger.Warn("Slow HTTP request", append(fields, zap.Duration("slow_request_threshold", slowRequest))...)
+		case slowRequest > 0:
+			logger.Debug("HTTP request", fields...)
+		default:
+			logger.Info("HTTP request", fields...)
+		}
+	}
+}
+
+const logFieldsKey = "meep.log_fields"
+
+func addLogFields(c *gin.Context, fields ...zap.Field) {
+	if existing, ok := c.Get(logFieldsKey); ok {
+		fields = append(existing.([]zap.Field), fields...)
+	}
+	c.Set(logFieldsKey, fields)
+}
+
+func addEmbedLogFields(c *gin.Context, response *cache.EmbeddingResponse) {
+	if response.Embeddings == nil {
+		addLogFields(c,
+			zap.String("request_type", "single"),
+			zap.Int("item_count", 1),
+			zap.Bool("cache_hit", response.Cached),
+			zap.String("model", response.Model))
+		return
+	}
+
+	cacheHits := 0
+	for _, cached := range response.CachedItems {
+		if cached {
+			cacheHits++
+		}
+	}
+
+	addLogFields(c,
+		zap.String("request_type", "batch"),
+		zap.Int("item_count", len(response.Embeddings)),
+		zap.Int("cache_hits", cacheHits),
+		zap.Bool("cache_hit", cacheHits == len(response.Embeddings)),
+		zap.Int("failed_items", len(response.Errors)),
+		zap.String("model", response.Model))
+}
+
+func corsMiddleware(cfg *config.ServerConfig) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(cfg.CORSAllowedOrigins))
+	for _, origin := range cfg.CORSAllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+
+	methods := strings.Join(cfg.CORSAllowedMethods, ", ")
+	headers := strings.Join(cfg.CORSAllowedHeaders, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || (!allowAll && !allowed[origin]) {
+			if c.Request.Method == http.MethodOptions && origin != "" {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
 	}
 }
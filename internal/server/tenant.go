@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/tenant"
+)
+
+const tenantContextKey = "tenant"
+
+func tenantFromContext(c *gin.Context) *tenant.Tenant {
+	if value, ok := c.Get(tenantContextKey); ok {
+		return value.(*tenant.Tenant)
+	}
+	return nil
+}
+
+func bearerToken(c *gin.Context) string {
+	return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
+
+// requireTenant resolves the caller's tenant from its API key when tenants
+// are configured, and rejects callers that have spent today's budget.
+func (s *Server) requireTenant(c *gin.Context) {
+	if s.tenants == nil {
+		c.Next()
+		return
+	}
+
+	t := s.tenants.Authenticate(bearerToken(c))
+	if t == nil {
+		addLogFields(c, zap.String("error_category", "unauthorized"))
+		c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "Unauthorized",
+			Code:    http.StatusUnauthorized,
+			Details: "Missing or invalid API key",
+		})
+		return
+	}
+
+	addLogFields(c, zap.String("tenant", t.ID))
+
+	if s.tenants.Exhausted(t) {
+		addLogFields(c, zap.String("error_category", "budget_exhausted"))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{
+			Error:   "Token budget exhausted",
+			Code:    http.StatusTooManyRequests,
+			Details: fmt.Sprintf("tenant %s has used its daily budget of %d tokens", t.ID, t.TokenBudget),
+		})
+		return
+	}
+
+	c.Set(tenantContextKey, t)
+	c.Next()
+}
+
+func (s *Server) isAdmin(c *gin.Context) bool {
+	return s.config.AdminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(bearerToken(c)), []byte(s.config.AdminToken)) == 1
+}
+
+// handleTenantStats answers /stats for tenant callers and for admins
+// filtering by ?tenant=. It returns true when the full stats should follow.
+func (s *Server) handleTenantStats(c *gin.Context) bool {
+	if s.isAdmin(c) {
+		id := c.Query("tenant")
+		if id == "" {
+			return true
+		}
+
+		if !s.tenants.Exists(id) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "Unknown tenant",
+				Code:    http.StatusNotFound,
+				Details: fmt.Sprintf("no tenant with id %q", id),
+			})
+			return false
+		}
+
+		s.writeTenantStats(c, id)
+		return false
+	}
+
+	t := s.tenants.Authenticate(bearerToken(c))
+	if t == nil {
+		addLogFields(c, zap.String("error_category", "unauthorized"))
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "Unauthorized",
+			Code:    http.StatusUnauthorized,
+			Details: "Missing or invalid API key",
+		})
+		return false
+	}
+
+	s.writeTenantStats(c, t.ID)
+	return false
+}
+
+func (s *Server) writeTenantStats(c *gin.Context, id string) {
+	c.JSON(http.StatusOK, gin.H{
+		"tenant":    id,
+		"stats":     s.tenants.GetStats(id),
+		"timestamp": time.Now(),
+	})
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/openai"
+)
+
+// embedRequestTimeout bounds a request that may call the provider.
+const embedRequestTimeout = 60 * time.Second
+
+// providerTimeoutResponse reports err as a 504 when it is a timeout rather
+// than a provider failure, naming the limit that was hit so clients can tell
+// a slow provider from a broken one.
+func providerTimeoutResponse(err error) (ErrorResponse, bool) {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return ErrorResponse{}, false
+	}
+
+	timeout := embedRequestTimeout
+	var timeoutErr *openai.TimeoutError
+	if errors.As(err, &timeoutErr) && timeoutErr.Timeout > 0 {
+		timeout = timeoutErr.Timeout
+	}
+
+	return ErrorResponse{
+		Error:     "Provider timeout",
+		Code:      http.StatusGatewayTimeout,
+		Reason:    "provider_timeout",
+		Details:   fmt.Sprintf("Embedding provider did not respond within %s, retry later or send smaller batches", timeout),
+		TimeoutMs: timeout.Milliseconds(),
+	}, true
+}
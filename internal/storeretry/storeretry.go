@@ -0,0 +1,242 @@
+package storeretry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/database"
+)
+
+const deadLetterCapacity = 100
+
+type Item struct {
+	InputHash string
+	InputText string
+	ModelName string
+	Embedding []float64
+	Matrix    [][]float64 // set instead of Embedding for multi-vector models
+	Replace   bool        // overwrite the row rather than apply the conflict policy; matrices only
+	attempts  int
+}
+
+type Queue struct {
+	db          *database.Database
+	logger      *zap.Logger
+	itemChan    chan *Item
+	maxAttempts int
+	backoff     time.Duration
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+
+	// interrupted is the item the worker was retrying when it stopped;
+	// Stop gives it a last attempt with the rest.
+	interrupted *Item
+
+	deadLetters      []*Item
+	deadLettersMutex sync.Mutex
+
+	enqueued  atomic.Int64
+	stored    atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+	inFlight  atomic.Int64
+	lastError atomic.Value
+}
+
+func New(db *database.Database, logger *zap.Logger, size, maxAttempts int, backoff time.Duration) *Queue {
+	return &Queue{
+		db:          db,
+		logger:      logger,
+		itemChan:    make(chan *Item, size),
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		stopChan:    make(chan struct{}),
+	}
+}
+
+func (q *Queue) Start(ctx context.Context) {
+	q.logger.Info("Starting store retry queue",
+		zap.Int("queue_size", cap(q.itemChan)),
+		zap.Int("max_attempts", q.maxAttempts),
+		zap.Duration("backoff", q.backoff))
+
+	q.wg.Add(1)
+	go q.process(ctx)
+}
+
+// Stop ends the worker and makes one last attempt at every pending item.
+// The attempts are bounded by ctx, so they fit the shutdown deadline.
+func (q *Queue) Stop(ctx context.Context) {
+	q.logger.Info("Stopping store retry queue")
+
+	close(q.stopChan)
+	q.wg.Wait()
+
+	var pending []*Item
+	if q.interrupted != nil {
+		pending = append(pending, q.interrupted)
+	}
+	for drained := false; !drained; {
+		select {
+		case item := <-q.itemChan:
+			pending = append(pending, item)
+		default:
+			drained = true
+		}
+	}
+
+	lost := 0
+	for _, item := range pending {
+		if ctx.Err() != nil {
+			lost++
+			continue
+		}
+
+		item.attempts++
+		if err := q.store(ctx, item); err != nil {
+			q.lastError.Store(err.Error())
+			lost++
+			continue
+		}
+		q.stored.Add(1)
+	}
+
+	if lost > 0 {
+		q.failed.Add(int64(lost))
+		q.logger.Warn("Store retry queue stopped with unstored items",
+			zap.Int("pending", len(pending)),
+			zap.Int("unstored", lost))
+	}
+
+	q.logger.Info("Store retry queue stopped",
+		zap.Int("stored_on_stop", len(pending)-lost))
+}
+
+func (q *Queue) Enqueue(item *Item) {
+	select {
+	case q.itemChan <- item:
+		q.enqueued.Add(1)
+	default:
+		q.dropped.Add(1)
+		q.logger.Warn("Store retry queue full, dropping embedding",
+			zap.String("input_hash", item.InputHash))
+	}
+}
+
+func (q *Queue) process(ctx context.Context) {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case item := <-q.itemChan:
+			q.inFlight.Add(1)
+			done := q.retry(ctx, item)
+			q.inFlight.Add(-1)
+
+			if !done {
+				q.interrupted = item
+				return
+			}
+
+		case <-q.stopChan:
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// retry stores item, backing off between attempts, and moves it to the
+// dead-letter list when they run out. It reports false when it was stopped
+// before the item was either stored or given up on.
+func (q *Queue) retry(ctx context.Context, item *Item) bool {
+	for item.attempts < q.maxAttempts {
+		item.attempts++
+
+		err := q.store(ctx, item)
+		if err == nil {
+			q.stored.Add(1)
+			q.logger.Info("Stored embedding on retry",
+				zap.String("input_hash", item.InputHash),
+				zap.Int("attempt", item.attempts))
+			return true
+		}
+
+		q.lastError.Store(err.Error())
+		q.logger.Warn("Retrying embedding store failed",
+			zap.String("input_hash", item.InputHash),
+			zap.Int("attempt", item.attempts),
+			zap.Error(err))
+
+		if item.attempts >= q.maxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(q.backoff * time.Duration(item.attempts)):
+		case <-q.stopChan:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	q.failed.Add(1)
+	q.logger.Error("Giving up storing embedding, moved to dead-letter list",
+		zap.String("input_hash", item.InputHash),
+		zap.Int("attempts", item.attempts))
+
+	q.deadLettersMutex.Lock()
+	if len(q.deadLetters) == deadLetterCapacity {
+		q.deadLetters = q.deadLetters[1:]
+	}
+	q.deadLetters = append(q.deadLetters, item)
+	q.deadLettersMutex.Unlock()
+
+	return true
+}
+
+func (q *Queue) store(ctx context.Context, item *Item) error {
+	storeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if item.Matrix != nil {
+		store := q.db.StoreEmbeddingMatrix
+		if item.Replace {
+			store = q.db.ReplaceEmbeddingMatrix
+		}
+		return store(storeCtx, item.InputHash, item.InputText, item.ModelName, item.Matrix)
+	}
+
+	return q.db.StoreEmbedding(storeCtx, item.InputHash, item.InputText, item.ModelName, item.Embedding)
+}
+
+func (q *Queue) GetStats() map[string]interface{} {
+	q.deadLettersMutex.Lock()
+	deadLetterHashes := make([]string, len(q.deadLetters))
+	for i, item := range q.deadLetters {
+		deadLetterHashes[i] = item.InputHash
+	}
+	q.deadLettersMutex.Unlock()
+
+	stats := map[string]interface{}{
+		"queue_depth":    len(q.itemChan) + int(q.inFlight.Load()),
+		"queue_capacity": cap(q.itemChan),
+		"enqueued":       q.enqueued.Load(),
+		"stored":         q.stored.Load(),
+		"failed":         q.failed.Load(),
+		"dropped":        q.dropped.Load(),
+		"dead_letters":   deadLetterHashes,
+	}
+
+	if lastError, ok := q.lastError.Load().(string); ok {
+		stats["last_error"] = lastError
+	}
+
+	return stats
+}
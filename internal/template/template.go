@@ -0,0 +1,116 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	embeddingPlaceholder = "{{embedding}}"
+	modelPlaceholder     = "{{model}}"
+	indexPlaceholder     = "{{index}}"
+	repeatPlaceholder    = "{{..}}"
+)
+
+// Template reshapes embedding responses following the placeholder syntax of
+// Meilisearch's REST embedder: "{{embedding}}" marks where a vector goes and an
+// array of the form [<item>, "{{..}}"] repeats <item> once per embedding.
+type Template struct {
+	root interface{}
+}
+
+func Parse(raw string) (*Template, error) {
+	var root interface{}
+	if err := json.Unmarshal([]byte(raw), &root); err != nil {
+		return nil, fmt.Errorf("failed to parse response template: %w", err)
+	}
+
+	if err := validate(root, false); err != nil {
+		return nil, fmt.Errorf("invalid response template: %w", err)
+	}
+
+	return &Template{root: root}, nil
+}
+
+func validate(node interface{}, inRepeat bool) error {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			if err := validate(child, inRepeat); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if isRepeat(v) {
+			if inRepeat {
+				return fmt.Errorf("nested %q repetitions are not supported", repeatPlaceholder)
+			}
+			return validate(v[0], true)
+		}
+		for _, child := range v {
+			if s, ok := child.(string); ok && s == repeatPlaceholder {
+				return fmt.Errorf("%q must be the second and last element of an array", repeatPlaceholder)
+			}
+			if err := validate(child, inRepeat); err != nil {
+				return err
+			}
+		}
+	case string:
+		if v == indexPlaceholder && !inRepeat {
+			return fmt.Errorf("%q is only valid inside a repeated item", indexPlaceholder)
+		}
+	}
+
+	return nil
+}
+
+func (t *Template) Render(embeddings [][]float64, model string) interface{} {
+	return t.render(t.root, embeddings, model, 0)
+}
+
+func (t *Template) render(node interface{}, embeddings [][]float64, model string, index int) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			out[key] = t.render(child, embeddings, model, index)
+		}
+		return out
+	case []interface{}:
+		if isRepeat(v) {
+			out := make([]interface{}, len(embeddings))
+			for i := range embeddings {
+				out[i] = t.render(v[0], embeddings, model, i)
+			}
+			return out
+		}
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = t.render(child, embeddings, model, index)
+		}
+		return out
+	case string:
+		switch v {
+		case embeddingPlaceholder:
+			if index < len(embeddings) {
+				return embeddings[index]
+			}
+			return nil
+		case modelPlaceholder:
+			return model
+		case indexPlaceholder:
+			return index
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isRepeat(v []interface{}) bool {
+	if len(v) != 2 {
+		return false
+	}
+	s, ok := v[1].(string)
+	return ok && s == repeatPlaceholder
+}
@@ -0,0 +1,152 @@
+package tenant
+
+import (
+	"crypto/subtle"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/config"
+)
+
+type Tenant struct {
+	ID          string
+	TokenBudget int64
+	apiKey      string
+}
+
+type usage struct {
+	day         string
+	tokens      int64
+	requests    int64
+	items       int64
+	cachedItems int64
+	rejected    int64
+}
+
+// Registry authenticates tenant API keys and tracks per-tenant usage.
+// Token budgets reset at midnight UTC; usage is kept in memory only.
+type Registry struct {
+	tenants []*Tenant
+	usage   map[string]*usage
+	mutex   sync.Mutex
+}
+
+func New(cfgs []config.TenantConfig, logger *zap.Logger) *Registry {
+	r := &Registry{
+		usage: make(map[string]*usage),
+	}
+
+	for _, cfg := range cfgs {
+		r.tenants = append(r.tenants, &Tenant{
+			ID:          cfg.ID,
+			TokenBudget: cfg.TokenBudget,
+			apiKey:      cfg.APIKey,
+		})
+		r.usage[cfg.ID] = &usage{}
+	}
+
+	logger.Info("Multi-tenancy enabled", zap.Int("tenants", len(r.tenants)))
+	return r
+}
+
+// Authenticate returns the tenant owning the API key, or nil.
+func (r *Registry) Authenticate(apiKey string) *Tenant {
+	var match *Tenant
+	for _, tenant := range r.tenants {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(tenant.apiKey)) == 1 {
+			match = tenant
+		}
+	}
+	return match
+}
+
+func (r *Registry) Exists(id string) bool {
+	_, ok := r.usage[id]
+	return ok
+}
+
+// Exhausted reports whether the tenant has used up today's token budget,
+// counting the request as rejected if so.
+func (r *Registry) Exhausted(tenant *Tenant) bool {
+	if tenant.TokenBudget <= 0 {
+		return false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	u := r.current(tenant.ID)
+	if u.tokens < tenant.TokenBudget {
+		return false
+	}
+
+	u.rejected++
+	return true
+}
+
+func (r *Registry) Record(id string, tokens, items, cachedItems int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	u := r.current(id)
+	u.requests++
+	u.tokens += int64(tokens)
+	u.items += int64(items)
+	u.cachedItems += int64(cachedItems)
+}
+
+func (r *Registry) GetStats(id string) map[string]interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var budget int64
+	for _, tenant := range r.tenants {
+		if tenant.ID == id {
+			budget = tenant.TokenBudget
+		}
+	}
+
+	u := r.current(id)
+	stats := map[string]interface{}{
+		"day":               u.day,
+		"requests":          u.requests,
+		"items":             u.items,
+		"cached_items":      u.cachedItems,
+		"tokens_used":       u.tokens,
+		"token_budget":      budget,
+		"rejected_requests": u.rejected,
+	}
+
+	if budget > 0 {
+		stats["tokens_remaining"] = max(budget-u.tokens, 0)
+	}
+
+	return stats
+}
+
+func (r *Registry) AllStats() map[string]interface{} {
+	stats := make(map[string]interface{}, len(r.tenants))
+	for _, tenant := range r.tenants {
+		stats[tenant.ID] = r.GetStats(tenant.ID)
+	}
+	return stats
+}
+
+// current returns the tenant's usage for today, resetting it when the UTC
+// day has rolled over. The caller must hold the mutex.
+func (r *Registry) current(id string) *usage {
+	u, ok := r.usage[id]
+	if !ok {
+		u = &usage{}
+		r.usage[id] = u
+	}
+
+	day := time.Now().UTC().Format("2006-01-02")
+	if u.day != day {
+		*u = usage{day: day}
+	}
+
+	return u
+}
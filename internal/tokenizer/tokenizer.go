@@ -0,0 +1,95 @@
+package tokenizer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const fallbackEncoding = "cl100k_base"
+
+// Failed loads are retried after retryBackoff, doubling per failure up to
+// maxRetryBackoff.
+const (
+	retryBackoff    = time.Second
+	maxRetryBackoff = 5 * time.Minute
+)
+
+type loader struct {
+	encoding *tiktoken.Tiktoken
+	loading  bool
+	failures int
+	retryAt  time.Time
+}
+
+var (
+	mu      sync.Mutex
+	loaders = make(map[string]*loader)
+)
+
+// CountTokens returns the number of tokens text encodes to for the given
+// model. Encodings are loaded on first use; while one is loading, or if it
+// cannot be loaded, the count falls back to a four-characters-per-token
+// estimate.
+func CountTokens(text, model string) int {
+	encoding := encodingFor(model)
+	if encoding == nil {
+		return Estimate(text)
+	}
+
+	return len(encoding.EncodeOrdinary(text))
+}
+
+// Preload starts loading the model's encoding in the background, so it is
+// usually ready before the first request counts tokens.
+func Preload(model string) {
+	encodingFor(model)
+}
+
+func Estimate(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// encodingFor returns the model's encoding, or nil until it has loaded.
+// Loading may download the BPE ranks with tiktoken-go's http.Get, which
+// has no timeout, so it runs in the background and never on the caller's
+// path; one load per model runs at a time.
+func encodingFor(model string) *tiktoken.Tiktoken {
+	mu.Lock()
+	defer mu.Unlock()
+
+	l, ok := loaders[model]
+	if !ok {
+		l = &loader{}
+		loaders[model] = l
+	}
+	if l.encoding != nil || l.loading || time.Now().Before(l.retryAt) {
+		return l.encoding
+	}
+
+	l.loading = true
+	go load(model, l)
+	return nil
+}
+
+func load(model string, l *loader) {
+	encoding, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		encoding, err = tiktoken.GetEncoding(fallbackEncoding)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	l.loading = false
+	if err != nil {
+		backoff := min(retryBackoff<<min(l.failures, 16), maxRetryBackoff)
+		l.failures++
+		l.retryAt = time.Now().Add(backoff)
+		return
+	}
+
+	l.encoding = encoding
+	l.failures = 0
+}
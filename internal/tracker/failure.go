@@ -0,0 +1,189 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// What to do with a batch whose usage update still fails after retries.
+const (
+	OnFailureDrop    = "drop"    // discard it and count it as failed
+	OnFailureRequeue = "requeue" // put it back in the buffer for the next flush
+	OnFailureSpill   = "spill"   // append it to Options.SpillFile, replayed on start
+)
+
+type Options struct {
+	RetryAttempts int           // extra attempts after the first failure
+	RetryBackoff  time.Duration // grows linearly with each attempt
+	OnFailure     string
+	SpillFile     string
+	SpillMaxBytes int64 // 0 = no limit
+}
+
+// replayBatchSize is how many spilled updates one UPDATE replays.
+const replayBatchSize = 1000
+
+// updateWithRetry runs the usage UPDATE, retrying with backoff while ctx
+// allows.
+func (ut *UsageTracker) updateWithRetry(ctx context.Context, batch []uuid.UUID) error {
+	for attempt := 0; ; attempt++ {
+		err := ut.updateUsageTimestamps(ctx, batch)
+		if err == nil || attempt >= ut.options.RetryAttempts {
+			return err
+		}
+
+		ut.retried.Add(1)
+		backoff := time.Duration(attempt+1) * ut.options.RetryBackoff
+		ut.logger.Warn("Retrying usage timestamp update",
+			zap.Int("batch_size", len(batch)),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// handleFailedBatch applies Options.OnFailure to a batch that could not be
+// written. Whatever is not kept is counted in failed_updates.
+func (ut *UsageTracker) handleFailedBatch(batch []uuid.UUID, stopping bool) {
+	switch ut.options.OnFailure {
+	case OnFailureRequeue:
+		if stopping {
+			break
+		}
+		ut.bufferMutex.Lock()
+		room := max(cap(ut.usageChan), ut.batchSize) - len(ut.buffer)
+		kept := batch[:min(max(room, 0), len(batch))]
+		ut.buffer = append(kept[:len(kept):len(kept)], ut.buffer...)
+		ut.bufferMutex.Unlock()
+
+		ut.requeued.Add(int64(len(kept)))
+		batch = batch[len(kept):]
+	case OnFailureSpill:
+		if err := ut.spill(batch); err != nil {
+			ut.logger.Error("Failed to spill usage updates",
+				zap.String("spill_file", ut.options.SpillFile),
+				zap.Int("batch_size", len(batch)),
+				zap.Error(err))
+			break
+		}
+		ut.spilled.Add(int64(len(batch)))
+		batch = nil
+	}
+
+	if len(batch) > 0 {
+		ut.failed.Add(int64(len(batch)))
+		ut.logger.Error("Discarding usage updates after repeated failures",
+			zap.Int("discarded", len(batch)),
+			zap.String("on_failure", ut.options.OnFailure))
+	}
+}
+
+func (ut *UsageTracker) spill(batch []uuid.UUID) error {
+	file, err := os.OpenFile(ut.options.SpillFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+
+	var lines strings.Builder
+	for _, id := range batch {
+		lines.WriteString(id.String())
+		lines.WriteByte('\n')
+	}
+
+	if ut.options.SpillMaxBytes > 0 {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to stat spill file: %w", err)
+		}
+		if info.Size()+int64(lines.Len()) > ut.options.SpillMaxBytes {
+			file.Close()
+			return fmt.Errorf("spill file would exceed %d bytes", ut.options.SpillMaxBytes)
+		}
+	}
+
+	if _, err := file.WriteString(lines.String()); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+
+	return file.Close()
+}
+
+// replaySpill writes updates spilled by an earlier run. It runs before
+// the workers start, so nothing appends to the file meanwhile, and removes
+// the file only once every update in it is written; otherwise the file is
+// kept for the next start.
+func (ut *UsageTracker) replaySpill(ctx context.Context) {
+	if ut.options.SpillFile == "" {
+		return
+	}
+
+	file, err := os.Open(ut.options.SpillFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		ut.logger.Error("Failed to open usage spill file",
+			zap.String("spill_file", ut.options.SpillFile),
+			zap.Error(err))
+		return
+	}
+
+	var ids []uuid.UUID
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		id, err := uuid.Parse(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	err = scanner.Err()
+	file.Close()
+	if err != nil {
+		ut.logger.Error("Failed to read usage spill file",
+			zap.String("spill_file", ut.options.SpillFile),
+			zap.Error(err))
+		return
+	}
+
+	ut.logger.Info("Replaying spilled usage updates",
+		zap.String("spill_file", ut.options.SpillFile),
+		zap.Int("updates", len(ids)))
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	for start := 0; start < len(ids); start += replayBatchSize {
+		batch := ids[start:min(start+replayBatchSize, len(ids))]
+		if err := ut.updateWithRetry(ctx, batch); err != nil {
+			ut.logger.Error("Failed to replay spilled usage updates, keeping the spill file",
+				zap.String("spill_file", ut.options.SpillFile),
+				zap.Int("replayed", start),
+				zap.Int("updates", len(ids)),
+				zap.Error(err))
+			return
+		}
+	}
+
+	if err := os.Remove(ut.options.SpillFile); err != nil {
+		ut.logger.Error("Failed to remove replayed usage spill file",
+			zap.String("spill_file", ut.options.SpillFile),
+			zap.Error(err))
+	}
+}
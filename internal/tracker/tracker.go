@@ -3,6 +3,7 @@ package tracker
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,13 +22,28 @@ type UsageTracker struct {
 	wg            sync.WaitGroup
 	buffer        []uuid.UUID
 	bufferMutex   sync.Mutex
+	blockTimeout  time.Duration
+	options       Options
+	dropped       atomic.Int64
+	retried       atomic.Int64
+	failed        atomic.Int64
+	requeued      atomic.Int64
+	spilled       atomic.Int64
 }
 
-func New(db *database.Database, logger *zap.Logger, batchSize int, flushInterval time.Duration) *UsageTracker {
+// New creates a tracker whose channel holds channelBuffer pending updates.
+// When it is full, TrackUsage waits up to blockTimeout before dropping.
+func New(db *database.Database, logger *zap.Logger, batchSize int, flushInterval time.Duration, channelBuffer int, blockTimeout time.Duration, opts Options) *UsageTracker {
+	if opts.OnFailure == "" {
+		opts.OnFailure = OnFailureDrop
+	}
+
 	return &UsageTracker{
 		db:            db,
 		logger:        logger,
-		usageChan:     make(chan uuid.UUID, 1000),
+		usageChan:     make(chan uuid.UUID, channelBuffer),
+		blockTimeout:  blockTimeout,
+		options:       opts,
 		batchSize:     batchSize,
 		flushInterval: flushInterval,
 		stopChan:      make(chan struct{}),
@@ -38,7 +54,13 @@ func New(db *database.Database, logger *zap.Logger, batchSize int, flushInterval
 func (ut *UsageTracker) Start(ctx context.Context) {
 	ut.logger.Info("Starting usage tracker",
 		zap.Int("batch_size", ut.batchSize),
-		zap.Duration("flush_interval", ut.flushInterval))
+		zap.Duration("flush_interval", ut.flushInterval),
+		zap.Int("channel_buffer", cap(ut.usageChan)),
+		zap.Duration("block_timeout", ut.blockTimeout),
+		zap.Int("retry_attempts", ut.options.RetryAttempts),
+		zap.String("on_failure", ut.options.OnFailure))
+
+	ut.replaySpill(ctx)
 
 	ut.wg.Add(2)
 
@@ -46,7 +68,9 @@ func (ut *UsageTracker) Start(ctx context.Context) {
 	go ut.flushPeriodically(ctx)
 }
 
-func (ut *UsageTracker) Stop() {
+// Stop ends the background workers and writes out all pending updates.
+// The final flush is bounded by ctx, so it fits the shutdown deadline.
+func (ut *UsageTracker) Stop(ctx context.Context) {
 	ut.logger.Info("Stopping usage tracker")
 
 	close(ut.stopChan)
@@ -54,18 +78,45 @@ func (ut *UsageTracker) Stop() {
 
 	ut.wg.Wait()
 
-	ut.flushBuffer()
+	ut.bufferMutex.Lock()
+	for id := range ut.usageChan {
+		ut.buffer = append(ut.buffer, id)
+	}
+	pending := len(ut.buffer)
+	ut.bufferMutex.Unlock()
 
-	ut.logger.Info("Usage tracker stopped")
+	if err := ut.flushBuffer(ctx, true); err != nil {
+		ut.logger.Error("Usage tracker stopped without flushing pending updates",
+			zap.Int("pending_updates", pending),
+			zap.Error(err))
+		return
+	}
+
+	ut.logger.Info("Usage tracker stopped",
+		zap.Int("flushed_updates", pending))
 }
 
 func (ut *UsageTracker) TrackUsage(id uuid.UUID) {
 	select {
 	case ut.usageChan <- id:
+		return
 	default:
-		ut.logger.Warn("Usage tracking channel full, dropping usage update",
-			zap.String("id", id.String()))
 	}
+
+	if ut.blockTimeout > 0 {
+		timer := time.NewTimer(ut.blockTimeout)
+		defer timer.Stop()
+
+		select {
+		case ut.usageChan <- id:
+			return
+		case <-timer.C:
+		}
+	}
+
+	ut.dropped.Add(1)
+	ut.logger.Warn("Usage tracking channel full, dropping usage update",
+		zap.String("id", id.String()))
 }
 
 func (ut *UsageTracker) processUsageUpdates(ctx context.Context) {
@@ -84,7 +135,7 @@ func (ut *UsageTracker) processUsageUpdates(ctx context.Context) {
 			ut.bufferMutex.Unlock()
 
 			if shouldFlush {
-				ut.flushBuffer()
+				ut.flushWithTimeout()
 			}
 
 		case <-ut.stopChan:
@@ -105,7 +156,7 @@ func (ut *UsageTracker) flushPeriodically(ctx context.Context) {
 	for {
 		select {
 		case <-ticker.C:
-			ut.flushBuffer()
+			ut.flushWithTimeout()
 
 		case <-ut.stopChan:
 			return
@@ -116,11 +167,18 @@ func (ut *UsageTracker) flushPeriodically(ctx context.Context) {
 	}
 }
 
-func (ut *UsageTracker) flushBuffer() {
+func (ut *UsageTracker) flushWithTimeout() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ut.flushBuffer(ctx, false)
+}
+
+func (ut *UsageTracker) flushBuffer(ctx context.Context, stopping bool) error {
 	ut.bufferMutex.Lock()
 	if len(ut.buffer) == 0 {
 		ut.bufferMutex.Unlock()
-		return
+		return nil
 	}
 
 	batch := make([]uuid.UUID, len(ut.buffer))
@@ -128,37 +186,30 @@ func (ut *UsageTracker) flushBuffer() {
 	ut.buffer = ut.buffer[:0]
 	ut.bufferMutex.Unlock()
 
-	if err := ut.updateUsageTimestamps(batch); err != nil {
+	if err := ut.updateWithRetry(ctx, batch); err != nil {
 		ut.logger.Error("Failed to update usage timestamps",
 			zap.Error(err),
 			zap.Int("batch_size", len(batch)))
-	} else {
-		ut.logger.Debug("Updated usage timestamps",
-			zap.Int("batch_size", len(batch)))
+		ut.handleFailedBatch(batch, stopping)
+		return err
 	}
+
+	ut.logger.Debug("Updated usage timestamps",
+		zap.Int("batch_size", len(batch)))
+	return nil
 }
 
-func (ut *UsageTracker) updateUsageTimestamps(ids []uuid.UUID) error {
+func (ut *UsageTracker) updateUsageTimestamps(ctx context.Context, ids []uuid.UUID) error {
 	if len(ids) == 0 {
 		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	query := `
-		UPDATE embedding_cache
-		SET used_at = NOW()
-		WHERE id = ANY($1)
-	`
-
 	idStrings := make([]string, len(ids))
 	for i, id := range ids {
 		idStrings[i] = id.String()
 	}
 
-	_, err := ut.db.Pool().Exec(ctx, query, idStrings)
-	return err
+	return ut.db.TouchEmbeddings(ctx, idStrings)
 }
 
 func (ut *UsageTracker) GetStats() map[string]interface{} {
@@ -169,6 +220,14 @@ func (ut *UsageTracker) GetStats() map[string]interface{} {
 	return map[string]interface{}{
 		"buffer_size":        bufferLen,
 		"channel_capacity":   cap(ut.usageChan),
+		"channel_length":     len(ut.usageChan),
+		"dropped_updates":    ut.dropped.Load(),
+		"retried_flushes":    ut.retried.Load(),
+		"failed_updates":     ut.failed.Load(),
+		"requeued_updates":   ut.requeued.Load(),
+		"spilled_updates":    ut.spilled.Load(),
+		"on_failure":         ut.options.OnFailure,
+		"block_timeout_ms":   ut.blockTimeout.Milliseconds(),
 		"batch_size":         ut.batchSize,
 		"flush_interval_sec": ut.flushInterval.Seconds(),
 	}
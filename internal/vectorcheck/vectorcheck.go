@@ -0,0 +1,48 @@
+package vectorcheck
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidVector = errors.New("invalid embedding vector")
+
+// Validate rejects vectors providers return under error conditions: empty,
+// all zeros, or holding NaN or Inf. Stored or served, they match nothing
+// and silently degrade search.
+func Validate(vector []float64) error {
+	if len(vector) == 0 {
+		return fmt.Errorf("%w: no elements", ErrInvalidVector)
+	}
+
+	zero := true
+	for i, v := range vector {
+		switch {
+		case math.IsNaN(v):
+			return fmt.Errorf("%w: NaN at element %d", ErrInvalidVector, i)
+		case math.IsInf(v, 0):
+			return fmt.Errorf("%w: Inf at element %d", ErrInvalidVector, i)
+		case v != 0:
+			zero = false
+		}
+	}
+
+	if zero {
+		return fmt.Errorf("%w: all %d elements are zero", ErrInvalidVector, len(vector))
+	}
+	return nil
+}
+
+// ValidateMatrix applies Validate to every row of a multi-vector embedding.
+func ValidateMatrix(matrix [][]float64) error {
+	if len(matrix) == 0 {
+		return fmt.Errorf("%w: no rows", ErrInvalidVector)
+	}
+	for i, row := range matrix {
+		if err := Validate(row); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/zanmato/meilisearch-embedder-proxy/internal/version.Version=1.2.0"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// Get returns the build metadata. Commit and build date fall back to the
+// VCS stamp Go embeds when they were not set via ldflags.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+
+	return info
+}
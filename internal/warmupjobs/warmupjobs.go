@@ -0,0 +1,174 @@
+package warmupjobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/zanmato/meilisearch-embedder-proxy/internal/cache"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+type Job struct {
+	ID         string             `json:"id"`
+	Status     string             `json:"status"`
+	Model      string             `json:"model,omitempty"`
+	Progress   cache.WarmupResult `json:"progress"`
+	Error      string             `json:"error,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
+
+type job struct {
+	Job
+	cancel context.CancelFunc
+}
+
+// Registry runs warmups in the background and keeps their progress in
+// memory. Finished jobs are dropped ttl after they end.
+type Registry struct {
+	logger *zap.Logger
+	ttl    time.Duration
+	mu     sync.Mutex
+	jobs   map[string]*job
+	wg     sync.WaitGroup
+}
+
+func New(ttl time.Duration, logger *zap.Logger) *Registry {
+	return &Registry{
+		logger: logger,
+		ttl:    ttl,
+		jobs:   make(map[string]*job),
+	}
+}
+
+func (r *Registry) Start(c *cache.Cache, req *cache.WarmupRequest) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j := &job{
+		Job: Job{
+			ID:        uuid.New().String(),
+			Status:    StatusRunning,
+			Model:     req.Model,
+			Progress:  cache.WarmupResult{Total: len(req.Inputs)},
+			CreatedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	r.mu.Lock()
+	r.sweepLocked()
+	r.jobs[j.ID] = j
+	snapshot := j.snapshot()
+	r.mu.Unlock()
+
+	r.logger.Info("Started warmup job",
+		zap.String("job_id", j.ID),
+		zap.Int("input_count", len(req.Inputs)))
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+
+		result, err := c.WarmupWithProgress(ctx, req, func(progress *cache.WarmupResult) {
+			r.mu.Lock()
+			j.Progress = *progress
+			r.mu.Unlock()
+		})
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		finishedAt := time.Now()
+		j.FinishedAt = &finishedAt
+
+		switch {
+		case err != nil:
+			j.Status = StatusFailed
+			j.Error = err.Error()
+		case result.Interrupted:
+			j.Progress = *result
+			j.Status = StatusCancelled
+		default:
+			j.Progress = *result
+			j.Status = StatusCompleted
+		}
+
+		r.logger.Info("Warmup job finished",
+			zap.String("job_id", j.ID),
+			zap.String("status", j.Status),
+			zap.Int("processed", j.Progress.Processed),
+			zap.Int("failed", j.Progress.Failed))
+	}()
+
+	return snapshot
+}
+
+func (r *Registry) Get(id string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sweepLocked()
+
+	j, ok := r.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return j.snapshot(), true
+}
+
+// Cancel stops a running job. The job stays visible, and reports
+// cancelled once its current batch has finished.
+func (r *Registry) Cancel(id string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j, ok := r.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+
+	if j.Status == StatusRunning {
+		j.cancel()
+		r.logger.Info("Cancelling warmup job", zap.String("job_id", id))
+	}
+
+	return j.snapshot(), true
+}
+
+// Stop cancels all running jobs and waits for them to return.
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	for _, j := range r.jobs {
+		j.cancel()
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+func (r *Registry) sweepLocked() {
+	now := time.Now()
+	for id, j := range r.jobs {
+		if j.FinishedAt != nil && now.Sub(*j.FinishedAt) > r.ttl {
+			delete(r.jobs, id)
+		}
+	}
+}
+
+// snapshot copies the job so callers can read it without the lock.
+func (j *job) snapshot() Job {
+	snapshot := j.Job
+	snapshot.Progress.Errors = append([]string(nil), j.Progress.Errors...)
+	return snapshot
+}
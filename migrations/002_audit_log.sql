@@ -0,0 +1,22 @@
+-- Audit trail of embedding requests
+-- Records who requested which inputs without storing the vectors again
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    client_ip VARCHAR(64) NOT NULL,
+    api_key_id VARCHAR(16),
+    model_name VARCHAR(50) NOT NULL,
+    input_hashes TEXT[] NOT NULL,
+    batch_size INTEGER NOT NULL,
+    cached_items INTEGER NOT NULL,
+    status_code INTEGER NOT NULL,
+    latency_ms INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_api_key_id ON audit_log(api_key_id);
+
+COMMENT ON TABLE audit_log IS 'Audit trail of embedding requests (enabled with server.audit)';
+COMMENT ON COLUMN audit_log.api_key_id IS 'Truncated SHA-256 of the bearer token, if one was sent';
+COMMENT ON COLUMN audit_log.input_hashes IS 'Cache keys of the embedded inputs; raw text is not stored here';
@@ -0,0 +1,5 @@
+-- Record the vector dimension of each cached embedding
+
+ALTER TABLE embedding_cache ADD COLUMN IF NOT EXISTS dimensions INTEGER;
+
+COMMENT ON COLUMN embedding_cache.dimensions IS 'Number of components in embedding_vector; backfilled by the verify command';
@@ -0,0 +1,3 @@
+-- Allow input_text to be omitted when cache.store_input_text is disabled
+
+ALTER TABLE embedding_cache ALTER COLUMN input_text DROP NOT NULL;
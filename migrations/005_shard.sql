@@ -0,0 +1,6 @@
+-- Shard key derived from the input hash (first byte modulo database.shard_count).
+-- Groundwork for declarative partitioning; NULL for rows written with sharding disabled.
+
+ALTER TABLE embedding_cache ADD COLUMN IF NOT EXISTS shard SMALLINT;
+
+CREATE INDEX IF NOT EXISTS idx_embedding_cache_shard ON embedding_cache(shard);
@@ -0,0 +1,7 @@
+-- zstd-compressed copy of the serialized vector, written instead of
+-- embedding_vector when database.compress_vectors is enabled.
+
+ALTER TABLE embedding_cache ADD COLUMN IF NOT EXISTS embedding_compressed BYTEA;
+ALTER TABLE embedding_cache ALTER COLUMN embedding_vector DROP NOT NULL;
+
+COMMENT ON COLUMN embedding_cache.embedding_compressed IS 'zstd-compressed JSON of the embedding vector; NULL when stored in embedding_vector';
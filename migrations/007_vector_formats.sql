@@ -0,0 +1,4 @@
+-- embedding_compressed also holds binary vectors (database.vector_format = "binary").
+-- Each row is read in the format it was written in; see the reformat command.
+
+COMMENT ON COLUMN embedding_cache.embedding_compressed IS 'zstd-compressed JSON, or binary float64 vector prefixed with MEV1; NULL when stored in embedding_vector';
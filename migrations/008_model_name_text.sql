@@ -0,0 +1,6 @@
+-- Model names are not limited to 50 characters (for example long gateway
+-- or deployment names), so store them as TEXT. varchar to text needs no
+-- table rewrite.
+
+ALTER TABLE embedding_cache ALTER COLUMN model_name TYPE TEXT;
+ALTER TABLE audit_log ALTER COLUMN model_name TYPE TEXT;